@@ -0,0 +1,141 @@
+// Package analysis inspects parsed functions to find the inputs and built-ins
+// they depend on before they are evaluated.
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ArtroxGabriel/sigma-parser/ast"
+	"github.com/ArtroxGabriel/sigma-parser/builtin"
+)
+
+// Call describes a function called with a given number of arguments.
+type Call struct {
+	Name  string
+	Arity int
+}
+
+// Info is the result of analysing a function.
+type Info struct {
+	Variables []string // Free identifiers, sorted, excluding constants
+	Calls     []Call   // Called functions, sorted by name then arity
+	Depth     int      // Maximum nesting depth of the expression tree
+	NodeCount int      // Number of expression nodes in the tree
+}
+
+// Analyze walks fn and collects its free variables, called functions and
+// size metrics.
+func Analyze(fn *ast.Function) *Info {
+	a := &analyzer{
+		variables: map[string]bool{},
+		calls:     map[Call]bool{},
+	}
+	info := &Info{}
+
+	if fn != nil && fn.Expression != nil {
+		info.Depth = a.walk(fn.Expression)
+	}
+
+	for name := range a.variables {
+		info.Variables = append(info.Variables, name)
+	}
+	sort.Strings(info.Variables)
+
+	for call := range a.calls {
+		info.Calls = append(info.Calls, call)
+	}
+	sort.Slice(info.Calls, func(i, j int) bool {
+		if info.Calls[i].Name != info.Calls[j].Name {
+			return info.Calls[i].Name < info.Calls[j].Name
+		}
+		return info.Calls[i].Arity < info.Calls[j].Arity
+	})
+
+	info.NodeCount = a.nodes
+	return info
+}
+
+// Check reports an error for every call to a function that is not a known
+// built-in or that is called with the wrong number of arguments.
+func (i *Info) Check() error {
+	var problems []string
+
+	for _, call := range i.Calls {
+		fn, ok := builtin.Lookup(call.Name)
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("unknown function %q", call.Name))
+		case fn.Arity != call.Arity:
+			problems = append(problems, fmt.Sprintf(
+				"function %q expects %d argument(s), got %d",
+				call.Name, fn.Arity, call.Arity,
+			))
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s", strings.Join(problems, "; "))
+}
+
+type analyzer struct {
+	variables map[string]bool
+	calls     map[Call]bool
+	nodes     int
+	bound     map[string]int // variables of the integrals being walked
+}
+
+// walk visits e and returns the depth of the subtree rooted at it.
+func (a *analyzer) walk(e ast.Expression) int {
+	if e == nil {
+		return 0
+	}
+	a.nodes++
+
+	switch e := e.(type) {
+	case *ast.Identifier:
+		if !builtin.IsConstant(e.Value) && a.bound[e.Value] == 0 {
+			a.variables[e.Value] = true
+		}
+		return 1
+	case *ast.PrefixExpression:
+		return 1 + a.walk(e.Right)
+	case *ast.PostfixExpression:
+		return 1 + a.walk(e.Left)
+	case *ast.InfixExpression:
+		return 1 + max(a.walk(e.Left), a.walk(e.Right))
+	case *ast.ConditionalExpression:
+		return 1 + max(a.walk(e.Condition), a.walk(e.Consequence), a.walk(e.Alternative))
+	case *ast.FunctionCall:
+		if integrand, x, lo, hi, ok := e.Integral(); ok {
+			return 1 + a.walkIntegral(integrand, x.Value, lo, hi)
+		}
+		depth := 0
+		if ident, ok := e.Function.(*ast.Identifier); ok {
+			a.calls[Call{Name: ident.Value, Arity: len(e.Arguments)}] = true
+		} else {
+			depth = a.walk(e.Function)
+		}
+		for _, arg := range e.Arguments {
+			depth = max(depth, a.walk(arg))
+		}
+		return 1 + depth
+	}
+
+	return 1
+}
+
+// walkIntegral visits the parts of an integral, in whose integrand the
+// variable of integration is not free.
+func (a *analyzer) walkIntegral(integrand ast.Expression, x string, lo, hi ast.Expression) int {
+	if a.bound == nil {
+		a.bound = map[string]int{}
+	}
+	a.bound[x]++
+	depth := a.walk(integrand)
+	a.bound[x]--
+	return max(depth, a.walk(lo), a.walk(hi))
+}
@@ -0,0 +1,110 @@
+package analysis_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ArtroxGabriel/sigma-parser/analysis"
+	"github.com/ArtroxGabriel/sigma-parser/lexer"
+	"github.com/ArtroxGabriel/sigma-parser/parser"
+)
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		input     string
+		variables []string
+		calls     []analysis.Call
+		depth     int
+		nodes     int
+	}{
+		{
+			input: "5",
+			depth: 1,
+			nodes: 1,
+		},
+		{
+			input:     "x + y * x",
+			variables: []string{"x", "y"},
+			depth:     3,
+			nodes:     5,
+		},
+		{
+			input:     "2 * pi * r + E",
+			variables: []string{"r"},
+			depth:     4,
+			nodes:     7,
+		},
+		{
+			input:     "sin(x) + atan2(y, cos(x))",
+			variables: []string{"x", "y"},
+			calls: []analysis.Call{
+				{Name: "atan2", Arity: 2},
+				{Name: "cos", Arity: 1},
+				{Name: "sin", Arity: 1},
+			},
+			depth: 4,
+			nodes: 7,
+		},
+		{
+			input:     "integrate(x * y, x, 0, b) + x",
+			variables: []string{"b", "x", "y"},
+			depth:     4,
+			nodes:     8,
+		},
+		{
+			input: "integrate(sin(t), t, 0, 1)",
+			calls: []analysis.Call{{Name: "sin", Arity: 1}},
+			depth: 3,
+			nodes: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			p := parser.New(lexer.New(tt.input))
+			function := p.ParseFunction()
+			if len(p.Errors()) != 0 {
+				t.Fatalf("parser errors: %v", p.Errors())
+			}
+
+			info := analysis.Analyze(function)
+			if !reflect.DeepEqual(info.Variables, tt.variables) {
+				t.Errorf("Variables = %v, want %v", info.Variables, tt.variables)
+			}
+			if !reflect.DeepEqual(info.Calls, tt.calls) {
+				t.Errorf("Calls = %v, want %v", info.Calls, tt.calls)
+			}
+			if info.Depth != tt.depth {
+				t.Errorf("Depth = %d, want %d", info.Depth, tt.depth)
+			}
+			if info.NodeCount != tt.nodes {
+				t.Errorf("NodeCount = %d, want %d", info.NodeCount, tt.nodes)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr string
+	}{
+		{input: "sin(x) + sqrt(y)"},
+		{input: "foo(x)", wantErr: `unknown function "foo"`},
+		{input: "sin(x, y)", wantErr: `function "sin" expects 1 argument(s), got 2`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			p := parser.New(lexer.New(tt.input))
+			err := analysis.Analyze(p.ParseFunction()).Check()
+
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Check() = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr):
+				t.Errorf("Check() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package ast
 
 import (
 	"bytes"
+	"strings"
 
 	"github.com/ArtroxGabriel/sigma-parser/token"
 )
@@ -18,7 +19,8 @@ type Expression interface {
 	expressionNode()
 }
 
-// NumberLiteral represents a literal number (integer or decimal)
+// NumberLiteral represents a literal number (integer or decimal), or an
+// imaginary one (4i) when its token is an IMAG token
 type NumberLiteral struct {
 	Token token.Token
 	Value float64 // Using float64 to support decimal numbers
@@ -28,6 +30,9 @@ func (*NumberLiteral) expressionNode()         {}
 func (nl *NumberLiteral) TokenLiteral() string { return nl.Token.Literal }
 func (nl *NumberLiteral) String() string       { return nl.Token.Literal }
 
+// IsImaginary reports whether the literal stands for Value times i.
+func (nl *NumberLiteral) IsImaginary() bool { return nl.Token.Type == token.IMAG }
+
 // PrefixExpression represents unary prefix operations (e.g., -5)
 type PrefixExpression struct {
 	Token    token.Token // The prefix token (-, +)
@@ -70,11 +75,68 @@ func (ie *InfixExpression) String() string {
 	return out.String()
 }
 
-// FunctionCall represents a mathematical function call (e.g., sin(x))
+// PostfixExpression represents unary postfix operations (e.g., 5!, 15%)
+type PostfixExpression struct {
+	Token    token.Token // The postfix token (!, %)
+	Left     Expression  // The expression to the left
+	Operator string      // The operator itself
+}
+
+func (*PostfixExpression) expressionNode()         {}
+func (pe *PostfixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PostfixExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(pe.Left.String())
+	out.WriteString(pe.Operator)
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// ConditionalExpression chooses between two expressions (e.g., x < 0 ? -x : x).
+// Only the chosen branch is evaluated. Alternative is nil for a piecewise
+// definition without a default, which is undefined when no condition holds.
+type ConditionalExpression struct {
+	Token       token.Token // The '?' token, or the if or piecewise identifier
+	Condition   Expression
+	Consequence Expression
+	Alternative Expression
+}
+
+func (*ConditionalExpression) expressionNode()         {}
+func (ce *ConditionalExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *ConditionalExpression) String() string {
+	var out bytes.Buffer
+
+	if ce.Alternative == nil {
+		out.WriteString("piecewise(")
+		out.WriteString(ce.Condition.String())
+		out.WriteString(", ")
+		out.WriteString(ce.Consequence.String())
+		out.WriteString(")")
+		return out.String()
+	}
+
+	out.WriteString("(")
+	out.WriteString(ce.Condition.String())
+	out.WriteString(" ? ")
+	out.WriteString(ce.Consequence.String())
+	out.WriteString(" : ")
+	out.WriteString(ce.Alternative.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// FunctionCall represents a mathematical function call (e.g., sin(x), atan2(y, x)).
+// An absolute value written with bars, |x|, is a call to abs whose token is
+// the opening bar.
 type FunctionCall struct {
-	Token    token.Token // The function token
-	Function Expression  // Function name (sin, cos, etc.)
-	Argument Expression  // The function argument
+	Token     token.Token  // The ( token, or the opening bar
+	Function  Expression   // Function name (sin, cos, etc.)
+	Arguments []Expression // The function arguments
 }
 
 func (*FunctionCall) expressionNode()         {}
@@ -82,14 +144,45 @@ func (fc *FunctionCall) TokenLiteral() string { return fc.Token.Literal }
 func (fc *FunctionCall) String() string {
 	var out bytes.Buffer
 
+	if fc.IsBars() {
+		out.WriteString("|")
+		out.WriteString(fc.Arguments[0].String())
+		out.WriteString("|")
+		return out.String()
+	}
+
+	args := make([]string, 0, len(fc.Arguments))
+	for _, a := range fc.Arguments {
+		args = append(args, a.String())
+	}
+
 	out.WriteString(fc.Function.String())
 	out.WriteString("(")
-	out.WriteString(fc.Argument.String())
+	out.WriteString(strings.Join(args, ", "))
 	out.WriteString(")")
 
 	return out.String()
 }
 
+// IsBars reports whether the call is an absolute value written as |x|.
+func (fc *FunctionCall) IsBars() bool {
+	return fc.Token.Type == token.BAR && len(fc.Arguments) == 1
+}
+
+// Integral returns the parts of a call integrate(integrand, x, a, b): the
+// integrand, the variable of integration, which is bound within the
+// integrand, and the bounds. ok is false for any other call.
+func (fc *FunctionCall) Integral() (integrand Expression, x *Identifier, a, b Expression, ok bool) {
+	name, isIdent := fc.Function.(*Identifier)
+	if !isIdent || name.Value != "integrate" || len(fc.Arguments) != 4 {
+		return nil, nil, nil, nil, false
+	}
+	if x, ok = fc.Arguments[1].(*Identifier); !ok {
+		return nil, nil, nil, nil, false
+	}
+	return fc.Arguments[0], x, fc.Arguments[2], fc.Arguments[3], true
+}
+
 // Identifier represents a variable like x, y, z
 type Identifier struct {
 	Token token.Token // token.IDENT
@@ -111,6 +204,29 @@ func (*Constant) expressionNode()        {}
 func (c *Constant) TokenLiteral() string { return c.Token.Literal }
 func (c *Constant) String() string       { return c.Name }
 
+// TokenOf returns the token that identifies exp in error messages.
+func TokenOf(exp Expression) token.Token {
+	switch exp := exp.(type) {
+	case *NumberLiteral:
+		return exp.Token
+	case *Identifier:
+		return exp.Token
+	case *Constant:
+		return exp.Token
+	case *PrefixExpression:
+		return exp.Token
+	case *PostfixExpression:
+		return exp.Token
+	case *InfixExpression:
+		return exp.Token
+	case *ConditionalExpression:
+		return exp.Token
+	case *FunctionCall:
+		return exp.Token
+	}
+	return token.Token{}
+}
+
 // Function is the root node containing the complete mathematical expression
 type Function struct {
 	Expression Expression // The complete mathematical expression
@@ -129,3 +245,117 @@ func (me *Function) String() string {
 	}
 	return ""
 }
+
+// Equation states that two expressions are equal (e.g., v = u + a * t)
+type Equation struct {
+	Token token.Token // The = token
+	Left  Expression
+	Right Expression
+}
+
+func (eq *Equation) TokenLiteral() string { return eq.Token.Literal }
+func (eq *Equation) String() string {
+	var out bytes.Buffer
+
+	if eq.Left != nil {
+		out.WriteString(eq.Left.String())
+	}
+	out.WriteString(" = ")
+	if eq.Right != nil {
+		out.WriteString(eq.Right.String())
+	}
+
+	return out.String()
+}
+
+// Statement is a single step of a Program
+type Statement interface {
+	Node
+	statementNode()
+}
+
+// Program is the root node of a sequence of statements separated by
+// semicolons or newlines (e.g., f(x) = x^2; f(3))
+type Program struct {
+	Statements []Statement
+}
+
+func (p *Program) TokenLiteral() string {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].TokenLiteral()
+	}
+	return ""
+}
+
+func (p *Program) String() string {
+	stmts := make([]string, 0, len(p.Statements))
+	for _, s := range p.Statements {
+		stmts = append(stmts, s.String())
+	}
+	return strings.Join(stmts, "; ")
+}
+
+// ExpressionStatement is an expression evaluated for its value (e.g., f(3))
+type ExpressionStatement struct {
+	Token      token.Token // The first token of the expression
+	Expression Expression
+}
+
+func (*ExpressionStatement) statementNode()          {}
+func (es *ExpressionStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExpressionStatement) String() string {
+	if es.Expression != nil {
+		return es.Expression.String()
+	}
+	return ""
+}
+
+// AssignStatement binds a variable to a value (e.g., x = 3)
+type AssignStatement struct {
+	Token token.Token // The = token
+	Name  *Identifier
+	Value Expression
+}
+
+func (*AssignStatement) statementNode()          {}
+func (as *AssignStatement) TokenLiteral() string { return as.Token.Literal }
+func (as *AssignStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(as.Name.String())
+	out.WriteString(" = ")
+	if as.Value != nil {
+		out.WriteString(as.Value.String())
+	}
+
+	return out.String()
+}
+
+// FunctionDefinition defines a function of named parameters (e.g., f(x, y) = x * y)
+type FunctionDefinition struct {
+	Token      token.Token // The = token
+	Name       *Identifier
+	Parameters []*Identifier
+	Body       Expression
+}
+
+func (*FunctionDefinition) statementNode()          {}
+func (fd *FunctionDefinition) TokenLiteral() string { return fd.Token.Literal }
+func (fd *FunctionDefinition) String() string {
+	var out bytes.Buffer
+
+	params := make([]string, 0, len(fd.Parameters))
+	for _, p := range fd.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString(fd.Name.String())
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") = ")
+	if fd.Body != nil {
+		out.WriteString(fd.Body.String())
+	}
+
+	return out.String()
+}
@@ -1,6 +1,7 @@
 package ast_test
 
 import (
+	"encoding/json"
 	"math"
 	"testing"
 
@@ -43,9 +44,9 @@ func TestString(t *testing.T) {
 			expectedString: "sin(90)",
 			mathExpression: ast.Function{
 				Expression: &ast.FunctionCall{
-					Token:    token.Token{Type: token.IDENT, Literal: "sin"},
-					Function: &ast.Identifier{Token: token.Token{Type: token.IDENT, Literal: "sin"}, Value: "sin"},
-					Argument: &ast.NumberLiteral{Token: token.Token{Type: token.NUMBER, Literal: "90"}, Value: 90},
+					Token:     token.Token{Type: token.IDENT, Literal: "sin"},
+					Function:  &ast.Identifier{Token: token.Token{Type: token.IDENT, Literal: "sin"}, Value: "sin"},
+					Arguments: []ast.Expression{&ast.NumberLiteral{Token: token.Token{Type: token.NUMBER, Literal: "90"}, Value: 90}},
 				},
 			},
 		},
@@ -77,9 +78,9 @@ func TestString(t *testing.T) {
 					Token:    token.Token{Type: token.TIMES, Literal: "*"},
 					Operator: "*",
 					Left: &ast.FunctionCall{
-						Token:    token.Token{Type: token.IDENT, Literal: "sqrt"},
-						Function: &ast.Identifier{Token: token.Token{Type: token.IDENT, Literal: "sqrt"}, Value: "sqrt"},
-						Argument: &ast.NumberLiteral{Token: token.Token{Type: token.NUMBER, Literal: "16"}, Value: 16},
+						Token:     token.Token{Type: token.IDENT, Literal: "sqrt"},
+						Function:  &ast.Identifier{Token: token.Token{Type: token.IDENT, Literal: "sqrt"}, Value: "sqrt"},
+						Arguments: []ast.Expression{&ast.NumberLiteral{Token: token.Token{Type: token.NUMBER, Literal: "16"}, Value: 16}},
 					},
 					Right: &ast.Constant{Token: token.Token{Type: token.IDENT, Literal: "pi"}, Value: math.Pi, Name: "pi"},
 				},
@@ -93,3 +94,32 @@ func TestString(t *testing.T) {
 		}
 	}
 }
+
+func TestMarshalJSON(t *testing.T) {
+	function := &ast.Function{
+		Expression: &ast.InfixExpression{
+			Token:    token.Token{Type: token.PLUS, Literal: "+", Pos: token.Position{Offset: 2, Line: 1, Column: 3}},
+			Operator: "+",
+			Left:     &ast.Identifier{Token: token.Token{Type: token.IDENT, Literal: "x"}, Value: "x"},
+			Right: &ast.FunctionCall{
+				Token:     token.Token{Type: token.LPAREN, Literal: "("},
+				Function:  &ast.Identifier{Token: token.Token{Type: token.IDENT, Literal: "sin"}, Value: "sin"},
+				Arguments: []ast.Expression{&ast.NumberLiteral{Token: token.Token{Type: token.NUMBER, Literal: "2.0"}, Value: 2}},
+			},
+		},
+	}
+
+	want := `{"type":"Function","expression":{"type":"InfixExpression","operator":"+",` +
+		`"left":{"type":"Identifier","value":"x","pos":{"offset":0,"line":0,"column":0}},` +
+		`"right":{"type":"FunctionCall","function":{"type":"Identifier","value":"sin","pos":{"offset":0,"line":0,"column":0}},` +
+		`"arguments":[{"type":"NumberLiteral","value":2,"literal":"2.0","pos":{"offset":0,"line":0,"column":0}}],` +
+		`"pos":{"offset":0,"line":0,"column":0}},"pos":{"offset":2,"line":1,"column":3}}}`
+
+	got, err := json.Marshal(function)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("json.Marshal() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,77 @@
+package ast
+
+import (
+	"strconv"
+
+	"github.com/ArtroxGabriel/sigma-parser/token"
+)
+
+// The constructors below build nodes that were not produced by the parser,
+// such as the results of differentiation or simplification. Their tokens
+// carry the literal a parser would have seen but no position.
+
+// NewNumber returns a number literal holding v.
+func NewNumber(v float64) *NumberLiteral {
+	literal := strconv.FormatFloat(v, 'g', -1, 64)
+	return &NumberLiteral{
+		Token: token.Token{Type: token.NUMBER, Literal: literal},
+		Value: v,
+	}
+}
+
+// NewIdentifier returns an identifier named name.
+func NewIdentifier(name string) *Identifier {
+	return &Identifier{
+		Token: token.Token{Type: token.IDENT, Literal: name},
+		Value: name,
+	}
+}
+
+// NewPrefix returns the prefix expression operator right.
+func NewPrefix(operator string, right Expression) *PrefixExpression {
+	return &PrefixExpression{
+		Token:    token.Token{Type: token.TokenType(operator), Literal: operator},
+		Operator: operator,
+		Right:    right,
+	}
+}
+
+// NewPostfix returns the postfix expression left operator.
+func NewPostfix(left Expression, operator string) *PostfixExpression {
+	return &PostfixExpression{
+		Token:    token.Token{Type: token.TokenType(operator), Literal: operator},
+		Left:     left,
+		Operator: operator,
+	}
+}
+
+// NewInfix returns the infix expression left operator right.
+func NewInfix(left Expression, operator string, right Expression) *InfixExpression {
+	return &InfixExpression{
+		Token:    token.Token{Type: token.TokenType(operator), Literal: operator},
+		Left:     left,
+		Operator: operator,
+		Right:    right,
+	}
+}
+
+// NewCall returns a call to the function name with the given arguments.
+func NewCall(name string, args ...Expression) *FunctionCall {
+	return &FunctionCall{
+		Token:     token.Token{Type: token.LPAREN, Literal: "("},
+		Function:  NewIdentifier(name),
+		Arguments: args,
+	}
+}
+
+// NewConditional returns the conditional expression condition ? consequence
+// : alternative. A nil alternative leaves the expression undefined when the
+// condition does not hold.
+func NewConditional(condition, consequence, alternative Expression) *ConditionalExpression {
+	return &ConditionalExpression{
+		Token:       token.Token{Type: token.QUESTION, Literal: "?"},
+		Condition:   condition,
+		Consequence: consequence,
+		Alternative: alternative,
+	}
+}
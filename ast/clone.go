@@ -0,0 +1,113 @@
+package ast
+
+// Clone returns a deep copy of n that can be mutated without affecting the
+// original tree.
+func Clone[T Node](n T) T {
+	if isNil(n) {
+		return n
+	}
+	return clone(n).(T)
+}
+
+func clone(n Node) Node {
+	if isNil(n) {
+		return nil
+	}
+
+	switch n := n.(type) {
+	case *Function:
+		return &Function{Expression: cloneExpression(n.Expression)}
+	case *Equation:
+		return &Equation{
+			Token: n.Token,
+			Left:  cloneExpression(n.Left),
+			Right: cloneExpression(n.Right),
+		}
+	case *NumberLiteral:
+		c := *n
+		return &c
+	case *Identifier:
+		c := *n
+		return &c
+	case *Constant:
+		c := *n
+		return &c
+	case *PrefixExpression:
+		return &PrefixExpression{
+			Token:    n.Token,
+			Operator: n.Operator,
+			Right:    cloneExpression(n.Right),
+		}
+	case *PostfixExpression:
+		return &PostfixExpression{
+			Token:    n.Token,
+			Left:     cloneExpression(n.Left),
+			Operator: n.Operator,
+		}
+	case *InfixExpression:
+		return &InfixExpression{
+			Token:    n.Token,
+			Left:     cloneExpression(n.Left),
+			Operator: n.Operator,
+			Right:    cloneExpression(n.Right),
+		}
+	case *ConditionalExpression:
+		return &ConditionalExpression{
+			Token:       n.Token,
+			Condition:   cloneExpression(n.Condition),
+			Consequence: cloneExpression(n.Consequence),
+			Alternative: cloneExpression(n.Alternative),
+		}
+	case *FunctionCall:
+		return &FunctionCall{
+			Token:     n.Token,
+			Function:  cloneExpression(n.Function),
+			Arguments: cloneList(n.Arguments),
+		}
+	case *Program:
+		stmts := make([]Statement, len(n.Statements))
+		for i, s := range n.Statements {
+			stmts[i] = Clone(s)
+		}
+		return &Program{Statements: stmts}
+	case *ExpressionStatement:
+		return &ExpressionStatement{Token: n.Token, Expression: cloneExpression(n.Expression)}
+	case *AssignStatement:
+		return &AssignStatement{
+			Token: n.Token,
+			Name:  Clone(n.Name),
+			Value: cloneExpression(n.Value),
+		}
+	case *FunctionDefinition:
+		params := make([]*Identifier, len(n.Parameters))
+		for i, p := range n.Parameters {
+			params[i] = Clone(p)
+		}
+		return &FunctionDefinition{
+			Token:      n.Token,
+			Name:       Clone(n.Name),
+			Parameters: params,
+			Body:       cloneExpression(n.Body),
+		}
+	}
+
+	return n
+}
+
+func cloneExpression(e Expression) Expression {
+	if isNil(e) {
+		return nil
+	}
+	return clone(e).(Expression)
+}
+
+func cloneList(list []Expression) []Expression {
+	if list == nil {
+		return nil
+	}
+	out := make([]Expression, len(list))
+	for i, e := range list {
+		out[i] = cloneExpression(e)
+	}
+	return out
+}
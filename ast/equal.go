@@ -0,0 +1,124 @@
+package ast
+
+// Equal reports whether a and b are structurally equal. Token positions and
+// literal spellings are ignored, so "2.0" and "2" compare equal.
+func Equal(a, b Node) bool {
+	if isNil(a) || isNil(b) {
+		return isNil(a) && isNil(b)
+	}
+
+	switch a := a.(type) {
+	case *Function:
+		b, ok := b.(*Function)
+		return ok && Equal(a.Expression, b.Expression)
+	case *Equation:
+		b, ok := b.(*Equation)
+		return ok && Equal(a.Left, b.Left) && Equal(a.Right, b.Right)
+	case *NumberLiteral:
+		b, ok := b.(*NumberLiteral)
+		return ok && a.Value == b.Value && a.IsImaginary() == b.IsImaginary()
+	case *Identifier:
+		b, ok := b.(*Identifier)
+		return ok && a.Value == b.Value
+	case *Constant:
+		b, ok := b.(*Constant)
+		return ok && a.Name == b.Name && a.Value == b.Value
+	case *PrefixExpression:
+		b, ok := b.(*PrefixExpression)
+		return ok && a.Operator == b.Operator && Equal(a.Right, b.Right)
+	case *PostfixExpression:
+		b, ok := b.(*PostfixExpression)
+		return ok && a.Operator == b.Operator && Equal(a.Left, b.Left)
+	case *InfixExpression:
+		b, ok := b.(*InfixExpression)
+		return ok && a.Operator == b.Operator &&
+			Equal(a.Left, b.Left) && Equal(a.Right, b.Right)
+	case *ConditionalExpression:
+		b, ok := b.(*ConditionalExpression)
+		return ok && Equal(a.Condition, b.Condition) &&
+			Equal(a.Consequence, b.Consequence) && Equal(a.Alternative, b.Alternative)
+	case *FunctionCall:
+		b, ok := b.(*FunctionCall)
+		return ok && Equal(a.Function, b.Function) && equalList(a.Arguments, b.Arguments)
+	case *Program:
+		b, ok := b.(*Program)
+		if !ok || len(a.Statements) != len(b.Statements) {
+			return false
+		}
+		for i := range a.Statements {
+			if !Equal(a.Statements[i], b.Statements[i]) {
+				return false
+			}
+		}
+		return true
+	case *ExpressionStatement:
+		b, ok := b.(*ExpressionStatement)
+		return ok && Equal(a.Expression, b.Expression)
+	case *AssignStatement:
+		b, ok := b.(*AssignStatement)
+		return ok && Equal(a.Name, b.Name) && Equal(a.Value, b.Value)
+	case *FunctionDefinition:
+		b, ok := b.(*FunctionDefinition)
+		if !ok || len(a.Parameters) != len(b.Parameters) {
+			return false
+		}
+		for i := range a.Parameters {
+			if !Equal(a.Parameters[i], b.Parameters[i]) {
+				return false
+			}
+		}
+		return Equal(a.Name, b.Name) && Equal(a.Body, b.Body)
+	}
+
+	return false
+}
+
+func equalList(a, b []Expression) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isNil reports whether n is nil or a typed nil pointer wrapped in the
+// interface, which the parser produces for sub-expressions that failed.
+func isNil(n Node) bool {
+	switch n := n.(type) {
+	case nil:
+		return true
+	case *Function:
+		return n == nil
+	case *Equation:
+		return n == nil
+	case *NumberLiteral:
+		return n == nil
+	case *Identifier:
+		return n == nil
+	case *Constant:
+		return n == nil
+	case *PrefixExpression:
+		return n == nil
+	case *PostfixExpression:
+		return n == nil
+	case *InfixExpression:
+		return n == nil
+	case *ConditionalExpression:
+		return n == nil
+	case *FunctionCall:
+		return n == nil
+	case *Program:
+		return n == nil
+	case *ExpressionStatement:
+		return n == nil
+	case *AssignStatement:
+		return n == nil
+	case *FunctionDefinition:
+		return n == nil
+	}
+	return false
+}
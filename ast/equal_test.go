@@ -0,0 +1,93 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/ArtroxGabriel/sigma-parser/ast"
+	"github.com/ArtroxGabriel/sigma-parser/lexer"
+	"github.com/ArtroxGabriel/sigma-parser/parser"
+)
+
+func parse(t *testing.T, input string) *ast.Function {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	function := p.ParseFunction()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return function
+}
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"2", "2.0", true},
+		{"x + 2", "x+2.000", true},
+		{"(x + 1) * y", "(x+1)*y", true},
+		{"sin(x ^ 2)", "sin((x^2))", true},
+		{"x + 1", "1 + x", false},
+		{"x - 1", "x + 1", false},
+		{"-x", "x", false},
+		{"sin(x)", "cos(x)", false},
+		{"x", "y", false},
+		{"2", "2.5", false},
+		{"2i", "2.0j", true},
+		{"2i", "2", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" == "+tt.b, func(t *testing.T) {
+			a, b := parse(t, tt.a), parse(t, tt.b)
+
+			if got := ast.Equal(a, b); got != tt.want {
+				t.Fatalf("Equal() = %t, want %t", got, tt.want)
+			}
+			if tt.want && ast.Hash(a) != ast.Hash(b) {
+				t.Errorf("Hash() differs for equal nodes: %d != %d", ast.Hash(a), ast.Hash(b))
+			}
+			if !tt.want && ast.Hash(a) == ast.Hash(b) {
+				t.Errorf("Hash() collides for different nodes: %d", ast.Hash(a))
+			}
+		})
+	}
+}
+
+func TestEqualNil(t *testing.T) {
+	var nilFunction *ast.Function
+
+	if !ast.Equal(nil, nil) {
+		t.Errorf("Equal(nil, nil) = false, want true")
+	}
+	if !ast.Equal(nilFunction, nil) {
+		t.Errorf("Equal(typed nil, nil) = false, want true")
+	}
+	if ast.Equal(parse(t, "x"), nil) {
+		t.Errorf("Equal(x, nil) = true, want false")
+	}
+}
+
+func TestClone(t *testing.T) {
+	original := parse(t, "a + sin(b * -c)")
+	clone := ast.Clone(original)
+
+	if clone == original {
+		t.Fatalf("Clone() returned the same pointer")
+	}
+	if !ast.Equal(original, clone) {
+		t.Fatalf("Clone() = %s, want %s", clone, original)
+	}
+
+	infix := clone.Expression.(*ast.InfixExpression)
+	infix.Operator = "-"
+	infix.Left.(*ast.Identifier).Value = "z"
+
+	if original.String() != "(a + sin((b * (-c))))" {
+		t.Errorf("mutating clone changed original: %s", original)
+	}
+	if clone.String() != "(z - sin((b * (-c))))" {
+		t.Errorf("clone = %s", clone)
+	}
+}
@@ -0,0 +1,134 @@
+package ast
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+)
+
+// node kinds written into the hash so that different node types with equal
+// payloads do not collide
+const (
+	hashNil byte = iota
+	hashFunction
+	hashNumber
+	hashIdentifier
+	hashConstant
+	hashPrefix
+	hashInfix
+	hashCall
+	hashPostfix
+	hashConditional
+	hashProgram
+	hashExpressionStatement
+	hashAssign
+	hashDefinition
+	hashImaginary
+	hashEquation
+)
+
+// Hash returns a stable structural hash of n. Nodes that are Equal always
+// produce the same hash.
+func Hash(n Node) uint64 {
+	h := &hasher{buf: make([]byte, 0, 64)}
+	h.node(n)
+
+	f := fnv.New64a()
+	_, _ = f.Write(h.buf)
+	return f.Sum64()
+}
+
+type hasher struct {
+	buf []byte
+}
+
+func (h *hasher) node(n Node) {
+	if isNil(n) {
+		h.kind(hashNil)
+		return
+	}
+
+	switch n := n.(type) {
+	case *Function:
+		h.kind(hashFunction)
+		h.node(n.Expression)
+	case *Equation:
+		h.kind(hashEquation)
+		h.node(n.Left)
+		h.node(n.Right)
+	case *NumberLiteral:
+		if n.IsImaginary() {
+			h.kind(hashImaginary)
+		} else {
+			h.kind(hashNumber)
+		}
+		h.float(n.Value)
+	case *Identifier:
+		h.kind(hashIdentifier)
+		h.string(n.Value)
+	case *Constant:
+		h.kind(hashConstant)
+		h.string(n.Name)
+		h.float(n.Value)
+	case *PrefixExpression:
+		h.kind(hashPrefix)
+		h.string(n.Operator)
+		h.node(n.Right)
+	case *PostfixExpression:
+		h.kind(hashPostfix)
+		h.string(n.Operator)
+		h.node(n.Left)
+	case *InfixExpression:
+		h.kind(hashInfix)
+		h.string(n.Operator)
+		h.node(n.Left)
+		h.node(n.Right)
+	case *ConditionalExpression:
+		h.kind(hashConditional)
+		h.node(n.Condition)
+		h.node(n.Consequence)
+		h.node(n.Alternative)
+	case *FunctionCall:
+		h.kind(hashCall)
+		h.node(n.Function)
+		h.buf = binary.AppendUvarint(h.buf, uint64(len(n.Arguments)))
+		for _, a := range n.Arguments {
+			h.node(a)
+		}
+	case *Program:
+		h.kind(hashProgram)
+		h.buf = binary.AppendUvarint(h.buf, uint64(len(n.Statements)))
+		for _, s := range n.Statements {
+			h.node(s)
+		}
+	case *ExpressionStatement:
+		h.kind(hashExpressionStatement)
+		h.node(n.Expression)
+	case *AssignStatement:
+		h.kind(hashAssign)
+		h.node(n.Name)
+		h.node(n.Value)
+	case *FunctionDefinition:
+		h.kind(hashDefinition)
+		h.node(n.Name)
+		h.buf = binary.AppendUvarint(h.buf, uint64(len(n.Parameters)))
+		for _, p := range n.Parameters {
+			h.node(p)
+		}
+		h.node(n.Body)
+	}
+}
+
+func (h *hasher) kind(k byte) { h.buf = append(h.buf, k) }
+
+func (h *hasher) string(s string) {
+	h.buf = binary.AppendUvarint(h.buf, uint64(len(s)))
+	h.buf = append(h.buf, s...)
+}
+
+func (h *hasher) float(f float64) {
+	if f == 0 {
+		f = 0 // -0 and +0 are Equal, so they must hash alike
+	}
+	h.buf = binary.LittleEndian.AppendUint64(h.buf, math.Float64bits(f))
+}
@@ -0,0 +1,135 @@
+package ast
+
+import (
+	"encoding/json"
+
+	"github.com/ArtroxGabriel/sigma-parser/token"
+)
+
+// The MarshalJSON methods encode every node as an object whose "type" field
+// names the node, so that the tree can be consumed outside of Go.
+
+func (f *Function) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type       string     `json:"type"`
+		Expression Expression `json:"expression"`
+	}{"Function", f.Expression})
+}
+
+func (eq *Equation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type  string         `json:"type"`
+		Left  Expression     `json:"left"`
+		Right Expression     `json:"right"`
+		Pos   token.Position `json:"pos"`
+	}{"Equation", eq.Left, eq.Right, eq.Token.Pos})
+}
+
+func (nl *NumberLiteral) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type      string         `json:"type"`
+		Value     float64        `json:"value"`
+		Imaginary bool           `json:"imaginary,omitempty"`
+		Literal   string         `json:"literal"`
+		Pos       token.Position `json:"pos"`
+	}{"NumberLiteral", nl.Value, nl.IsImaginary(), nl.Token.Literal, nl.Token.Pos})
+}
+
+func (i *Identifier) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type  string         `json:"type"`
+		Value string         `json:"value"`
+		Pos   token.Position `json:"pos"`
+	}{"Identifier", i.Value, i.Token.Pos})
+}
+
+func (c *Constant) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type  string         `json:"type"`
+		Name  string         `json:"name"`
+		Value float64        `json:"value"`
+		Pos   token.Position `json:"pos"`
+	}{"Constant", c.Name, c.Value, c.Token.Pos})
+}
+
+func (pe *PrefixExpression) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type     string         `json:"type"`
+		Operator string         `json:"operator"`
+		Right    Expression     `json:"right"`
+		Pos      token.Position `json:"pos"`
+	}{"PrefixExpression", pe.Operator, pe.Right, pe.Token.Pos})
+}
+
+func (pe *PostfixExpression) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type     string         `json:"type"`
+		Operator string         `json:"operator"`
+		Left     Expression     `json:"left"`
+		Pos      token.Position `json:"pos"`
+	}{"PostfixExpression", pe.Operator, pe.Left, pe.Token.Pos})
+}
+
+func (ce *ConditionalExpression) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type        string         `json:"type"`
+		Condition   Expression     `json:"condition"`
+		Consequence Expression     `json:"consequence"`
+		Alternative Expression     `json:"alternative"`
+		Pos         token.Position `json:"pos"`
+	}{"ConditionalExpression", ce.Condition, ce.Consequence, ce.Alternative, ce.Token.Pos})
+}
+
+func (ie *InfixExpression) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type     string         `json:"type"`
+		Operator string         `json:"operator"`
+		Left     Expression     `json:"left"`
+		Right    Expression     `json:"right"`
+		Pos      token.Position `json:"pos"`
+	}{"InfixExpression", ie.Operator, ie.Left, ie.Right, ie.Token.Pos})
+}
+
+func (fc *FunctionCall) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type      string         `json:"type"`
+		Function  Expression     `json:"function"`
+		Arguments []Expression   `json:"arguments"`
+		Bars      bool           `json:"bars,omitempty"`
+		Pos       token.Position `json:"pos"`
+	}{"FunctionCall", fc.Function, fc.Arguments, fc.IsBars(), fc.Token.Pos})
+}
+
+func (p *Program) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type       string      `json:"type"`
+		Statements []Statement `json:"statements"`
+	}{"Program", p.Statements})
+}
+
+func (es *ExpressionStatement) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type       string         `json:"type"`
+		Expression Expression     `json:"expression"`
+		Pos        token.Position `json:"pos"`
+	}{"ExpressionStatement", es.Expression, es.Token.Pos})
+}
+
+func (as *AssignStatement) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type  string         `json:"type"`
+		Name  *Identifier    `json:"name"`
+		Value Expression     `json:"value"`
+		Pos   token.Position `json:"pos"`
+	}{"AssignStatement", as.Name, as.Value, as.Token.Pos})
+}
+
+func (fd *FunctionDefinition) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type       string         `json:"type"`
+		Name       *Identifier    `json:"name"`
+		Parameters []*Identifier  `json:"parameters"`
+		Body       Expression     `json:"body"`
+		Pos        token.Position `json:"pos"`
+	}{"FunctionDefinition", fd.Name, fd.Parameters, fd.Body, fd.Token.Pos})
+}
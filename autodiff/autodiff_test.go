@@ -0,0 +1,381 @@
+package autodiff_test
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/ArtroxGabriel/sigma-parser/autodiff"
+	"github.com/ArtroxGabriel/sigma-parser/builtin"
+	"github.com/ArtroxGabriel/sigma-parser/calculus"
+	"github.com/ArtroxGabriel/sigma-parser/eval"
+	"github.com/ArtroxGabriel/sigma-parser/object"
+	"github.com/ArtroxGabriel/sigma-parser/parser"
+)
+
+func near(got, want float64) bool {
+	if math.IsNaN(want) {
+		return math.IsNaN(got)
+	}
+	return math.Abs(got-want) <= 1e-12*math.Max(1, math.Abs(want))
+}
+
+// symbolic returns the derivative of input with respect to x at vars,
+// computed by differentiating the tree and evaluating the result.
+func symbolic(t *testing.T, input, x string, vars map[string]float64) float64 {
+	t.Helper()
+	function, err := parser.Parse(input)
+	if err != nil {
+		t.Fatalf("Parse(%q) error = %v", input, err)
+	}
+	d, err := calculus.Derive(function.Expression, x)
+	if err != nil {
+		t.Fatalf("Derive(%q, %s) error = %v", input, x, err)
+	}
+	env := object.NewEnvironment()
+	for name, v := range vars {
+		env.Set(name, &object.Number{Value: v})
+	}
+	v, err := eval.Float(d, env)
+	if err != nil {
+		t.Fatalf("eval of d/d%s %q error = %v", x, input, err)
+	}
+	return v
+}
+
+var gradientTests = []string{
+	"x^2 * y + 3*x",
+	"x / y - y / x",
+	"sin(x) * cos(y) + tan(x*y)",
+	"exp(-x^2 - y^2) / sqrt(x^2 + y^2)",
+	"ln(x) + log(y) + log2(x*y)",
+	"x^y",
+	"2^x * y^3",
+	"asin(x/3) + acos(y/4) + atan(x - y)",
+	"sinh(x) + cosh(y) * tanh(x + y)",
+	"abs(x - y) + re(x) + conj(y) + im(x)",
+	"x < y ? x^3 : y^3",
+	"x > 0 ? x : 0",
+	"x * y > 1 ? y : x",
+	"50% * x * y",
+	"-x - (-y)",
+	"pi * x + e * y",
+}
+
+func TestGradient(t *testing.T) {
+	vars := map[string]float64{"x": 1.3, "y": 2.1}
+	for _, input := range gradientTests {
+		t.Run(input, func(t *testing.T) {
+			function, err := parser.Parse(input)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", input, err)
+			}
+
+			value, grad, err := autodiff.Gradient(function, vars)
+			if err != nil {
+				t.Fatalf("Gradient(%q) error = %v", input, err)
+			}
+			if want := evalAt(t, input, vars); !near(value, want) {
+				t.Errorf("value = %v, want %v", value, want)
+			}
+			for _, x := range []string{"x", "y"} {
+				if want := symbolic(t, input, x, vars); !near(grad[x], want) {
+					t.Errorf("d/d%s = %v, want %v", x, grad[x], want)
+				}
+			}
+		})
+	}
+}
+
+func evalAt(t *testing.T, input string, vars map[string]float64) float64 {
+	t.Helper()
+	function, err := parser.Parse(input)
+	if err != nil {
+		t.Fatalf("Parse(%q) error = %v", input, err)
+	}
+	env := object.NewEnvironment()
+	for name, v := range vars {
+		env.Set(name, &object.Number{Value: v})
+	}
+	v, err := eval.Float(function, env)
+	if err != nil {
+		t.Fatalf("Float(%q) error = %v", input, err)
+	}
+	return v
+}
+
+func TestGradientWrt(t *testing.T) {
+	function, err := parser.Parse("a*x^2 + b*x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]float64{"a": 2, "b": -1, "x": 3}
+
+	_, grad, err := autodiff.Gradient(function, vars, "x")
+	if err != nil {
+		t.Fatalf("Gradient error = %v", err)
+	}
+	if len(grad) != 1 || grad["x"] != 11 {
+		t.Errorf("grad = %v, want map[x:11]", grad)
+	}
+
+	_, grad, err = autodiff.Gradient(function, vars, "a", "b")
+	if err != nil {
+		t.Fatalf("Gradient error = %v", err)
+	}
+	if grad["a"] != 9 || grad["b"] != 3 {
+		t.Errorf("grad = %v, want map[a:9 b:3]", grad)
+	}
+}
+
+// TestPowNegativeBase checks that a constant exponent does not bring in
+// ln of a negative base.
+func TestPowNegativeBase(t *testing.T) {
+	function, err := parser.Parse("x^3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, grad, err := autodiff.Gradient(function, map[string]float64{"x": -2})
+	if err != nil {
+		t.Fatalf("Gradient error = %v", err)
+	}
+	if grad["x"] != 12 {
+		t.Errorf("d/dx x^3 at -2 = %v, want 12", grad["x"])
+	}
+}
+
+// TestRegistryDerivatives checks each Deriv in the registry against a
+// central difference.
+func TestRegistryDerivatives(t *testing.T) {
+	for _, fn := range builtin.Functions() {
+		if fn.Deriv == nil {
+			t.Errorf("%s has no Deriv", fn.Name)
+			continue
+		}
+		for _, x := range []float64{0.3, 0.7} {
+			h := 1e-6
+			want := (fn.Fn(x+h) - fn.Fn(x-h)) / (2 * h)
+			if got := fn.Deriv(x)[0]; math.Abs(got-want) > 1e-6*math.Max(1, math.Abs(want)) {
+				t.Errorf("%s'(%v) = %v, want %v", fn.Name, x, got, want)
+			}
+		}
+	}
+}
+
+func TestGradientErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		wrt   []string
+		want  string
+	}{
+		{"x + z", nil, "1:5: identifier not found: z"},
+		{"x!", nil, "1:2: no derivative rule for !"},
+		{"x < 1", nil, "1:3: expected a number, got a condition"},
+		{"foo(x)", nil, "1:1: unknown function: foo"},
+		{"sin(x, x)", nil, "1:1: wrong number of arguments to sin: want 1, got 2"},
+		{"x", []string{"w"}, "0:0: no value for w"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			function, err := parser.Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.input, err)
+			}
+			_, _, err = autodiff.Gradient(function, map[string]float64{"x": 1}, tt.wrt...)
+			var evalErr *eval.Error
+			if !errors.As(err, &evalErr) {
+				t.Fatalf("Gradient(%q) error = %v, want *eval.Error", tt.input, err)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("Gradient(%q) error = %q, want %q", tt.input, err, tt.want)
+			}
+		})
+	}
+}
+
+// TestTapeGradient checks the backward pass against forward mode.
+func TestTapeGradient(t *testing.T) {
+	vars := map[string]float64{"x": 1.3, "y": 2.1}
+	for _, input := range gradientTests {
+		t.Run(input, func(t *testing.T) {
+			function, err := parser.Parse(input)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", input, err)
+			}
+			value, want, err := autodiff.Gradient(function, vars)
+			if err != nil {
+				t.Fatalf("Gradient(%q) error = %v", input, err)
+			}
+
+			tape, err := autodiff.Record(function, vars)
+			if err != nil {
+				t.Fatalf("Record(%q) error = %v", input, err)
+			}
+			if tape.Value() != value {
+				t.Errorf("Value() = %v, want %v", tape.Value(), value)
+			}
+			got := tape.Gradient()
+			for _, x := range []string{"x", "y"} {
+				if !near(got[x], want[x]) {
+					t.Errorf("d/d%s = %v, want %v", x, got[x], want[x])
+				}
+			}
+		})
+	}
+}
+
+// TestNonFinitePartials checks that a partial that is infinite or NaN at
+// the point leaves the other components alone, in both modes.
+func TestNonFinitePartials(t *testing.T) {
+	vars := map[string]float64{"x": 0, "y": 1}
+	for _, input := range []string{"sqrt(x) + y", "abs(x) + y", "0^y + x", "sqrt(x) * 2 + 3 * y"} {
+		t.Run(input, func(t *testing.T) {
+			function, err := parser.Parse(input)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", input, err)
+			}
+			_, forward, err := autodiff.Gradient(function, vars)
+			if err != nil {
+				t.Fatalf("Gradient(%q) error = %v", input, err)
+			}
+			tape, err := autodiff.Record(function, vars)
+			if err != nil {
+				t.Fatalf("Record(%q) error = %v", input, err)
+			}
+			reverse := tape.Gradient()
+
+			for _, x := range []string{"x", "y"} {
+				f, r := forward[x], reverse[x]
+				if math.IsNaN(f) != math.IsNaN(r) || (!math.IsNaN(f) && f != r && !near(f, r)) {
+					t.Errorf("d/d%s = %v in forward mode, %v in reverse mode", x, f, r)
+				}
+			}
+			finite := "y"
+			if input == "0^y + x" {
+				finite = "x"
+			}
+			if f := forward[finite]; math.IsNaN(f) || math.IsInf(f, 0) {
+				t.Errorf("d/d%s = %v, want a finite value", finite, f)
+			}
+		})
+	}
+}
+
+// TestTapeManyParameters differentiates a polynomial with 60 coefficients
+// in one backward pass.
+func TestTapeManyParameters(t *testing.T) {
+	const n = 60
+	input := "p0"
+	vars := map[string]float64{"x": 0.9, "p0": 1}
+	for i := 1; i < n; i++ {
+		name := "p" + strconv.Itoa(i)
+		input += " + " + name + " * x^" + strconv.Itoa(i)
+		vars[name] = float64(i)
+	}
+
+	function, err := parser.Parse(input)
+	if err != nil {
+		t.Fatalf("Parse error = %v", err)
+	}
+	tape, err := autodiff.Record(function, vars)
+	if err != nil {
+		t.Fatalf("Record error = %v", err)
+	}
+	grad := tape.Gradient()
+	if len(grad) != n+1 {
+		t.Fatalf("len(grad) = %d, want %d", len(grad), n+1)
+	}
+
+	var dx float64
+	for i := 0; i < n; i++ {
+		if want := math.Pow(0.9, float64(i)); !near(grad["p"+strconv.Itoa(i)], want) {
+			t.Errorf("d/dp%d = %v, want %v", i, grad["p"+strconv.Itoa(i)], want)
+		}
+		if i > 0 {
+			dx += float64(i) * float64(i) * math.Pow(0.9, float64(i-1))
+		}
+	}
+	if !near(grad["x"], dx) {
+		t.Errorf("d/dx = %v, want %v", grad["x"], dx)
+	}
+}
+
+func TestHessianVector(t *testing.T) {
+	vars := map[string]float64{"x": 1.3, "y": 2.1}
+	v := map[string]float64{"x": 0.7, "y": -1.1}
+
+	for _, input := range gradientTests {
+		t.Run(input, func(t *testing.T) {
+			function, err := parser.Parse(input)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", input, err)
+			}
+			tape, err := autodiff.Record(function, vars)
+			if err != nil {
+				t.Fatalf("Record(%q) error = %v", input, err)
+			}
+			got := tape.HessianVector(v)
+
+			// row x of the Hessian times v, from symbolic second derivatives
+			for _, x := range []string{"x", "y"} {
+				d, err := calculus.Derive(function.Expression, x)
+				if err != nil {
+					t.Fatalf("Derive error = %v", err)
+				}
+				var want float64
+				for _, y := range []string{"x", "y"} {
+					want += symbolic(t, d.String(), y, vars) * v[y]
+				}
+				if math.Abs(got[x]-want) > 1e-9*math.Max(1, math.Abs(want)) {
+					t.Errorf("(Hv)_%s = %v, want %v", x, got[x], want)
+				}
+			}
+		})
+	}
+}
+
+// TestRegistrySecondDerivatives checks each Deriv2 in the registry against
+// a central difference of Deriv.
+func TestRegistrySecondDerivatives(t *testing.T) {
+	for _, fn := range builtin.Functions() {
+		if fn.Deriv2 == nil {
+			t.Errorf("%s has no Deriv2", fn.Name)
+			continue
+		}
+		for _, x := range []float64{0.3, 0.7} {
+			h := 1e-6
+			want := (fn.Deriv(x + h)[0] - fn.Deriv(x - h)[0]) / (2 * h)
+			if got := fn.Deriv2(x)[0]; math.Abs(got-want) > 1e-5*math.Max(1, math.Abs(want)) {
+				t.Errorf("%s''(%v) = %v, want %v", fn.Name, x, got, want)
+			}
+		}
+	}
+}
+
+func TestRecordErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"x + z", "1:5: identifier not found: z"},
+		{"(x + 1)!", "1:8: no derivative rule for !"},
+		{"x < 0 ? 1 : 2 < 3", "1:15: expected a number, got a condition"},
+		{"x ? 1 : 2", "1:1: expected a condition, got a number"},
+		{"piecewise(x > 5, 1)", "1:1: no condition of piecewise((x > 5), 1) holds"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			function, err := parser.Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.input, err)
+			}
+			_, err = autodiff.Record(function, map[string]float64{"x": 1})
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("Record(%q) error = %v, want %q", tt.input, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,359 @@
+// Package autodiff computes derivatives of expressions by automatic
+// differentiation: exact to rounding, like symbolic derivatives, but at a
+// cost proportional to evaluating the expression itself.
+package autodiff
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/ArtroxGabriel/sigma-parser/ast"
+	"github.com/ArtroxGabriel/sigma-parser/builtin"
+	"github.com/ArtroxGabriel/sigma-parser/eval"
+	"github.com/ArtroxGabriel/sigma-parser/token"
+)
+
+// dual is a value together with its partial derivatives with respect to
+// each variable being differentiated. A nil grad stands for all zeros.
+type dual struct {
+	value float64
+	grad  []float64
+}
+
+// Gradient evaluates node at vars and returns its value and its partial
+// derivative with respect to each variable in wrt, or to every variable in
+// vars if wrt is empty. It differentiates every variable at once in a
+// single pass over the tree, carrying a dual number per node.
+func Gradient(node ast.Node, vars map[string]float64, wrt ...string) (float64, map[string]float64, error) {
+	exp, err := expression(node)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(wrt) == 0 {
+		wrt = names(vars)
+	}
+
+	f := &forward{vars: vars, index: map[string]int{}, n: len(wrt)}
+	for i, name := range wrt {
+		if _, ok := vars[name]; !ok {
+			return 0, nil, &eval.Error{Msg: fmt.Sprintf("no value for %s", name)}
+		}
+		f.index[name] = i
+	}
+
+	d, err := f.eval(exp)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	grad := make(map[string]float64, len(wrt))
+	for i, name := range wrt {
+		if d.grad != nil {
+			grad[name] = d.grad[i]
+		} else {
+			grad[name] = 0
+		}
+	}
+	return d.value, grad, nil
+}
+
+// expression returns the expression to differentiate in node.
+func expression(node ast.Node) (ast.Expression, error) {
+	if fn, ok := node.(*ast.Function); ok {
+		if fn.Expression == nil {
+			return nil, &eval.Error{Msg: "empty expression"}
+		}
+		return fn.Expression, nil
+	}
+	if exp, ok := node.(ast.Expression); ok {
+		return exp, nil
+	}
+	return nil, &eval.Error{Msg: fmt.Sprintf("cannot differentiate %T", node)}
+}
+
+// names returns the keys of vars in sorted order.
+func names(vars map[string]float64) []string {
+	list := make([]string, 0, len(vars))
+	for name := range vars {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func newError(tok token.Token, format string, args ...any) *eval.Error {
+	return &eval.Error{Pos: tok.Pos, End: tok.End(), Msg: fmt.Sprintf(format, args...)}
+}
+
+type forward struct {
+	vars  map[string]float64
+	index map[string]int // position of each differentiated variable in grad
+	n     int
+}
+
+func (f *forward) eval(node ast.Expression) (dual, error) {
+	switch node := node.(type) {
+	case *ast.NumberLiteral:
+		if node.IsImaginary() {
+			return dual{}, newError(node.Token, "cannot differentiate imaginary number %s", node)
+		}
+		return dual{value: node.Value}, nil
+
+	case *ast.Constant:
+		return dual{value: node.Value}, nil
+
+	case *ast.Identifier:
+		return f.identifier(node)
+
+	case *ast.PrefixExpression:
+		if node.Operator == "!" {
+			return dual{}, newError(node.Token, "expected a number, got a condition")
+		}
+		right, err := f.eval(node.Right)
+		if err != nil || node.Operator != "-" {
+			return right, err
+		}
+		return dual{-right.value, scale(-1, right.grad)}, nil
+
+	case *ast.PostfixExpression:
+		if node.Operator != "%" {
+			return dual{}, newError(node.Token, "no derivative rule for %s", node.Operator)
+		}
+		left, err := f.eval(node.Left)
+		if err != nil {
+			return dual{}, err
+		}
+		return dual{left.value / 100, scale(0.01, left.grad)}, nil
+
+	case *ast.InfixExpression:
+		return f.infix(node)
+
+	case *ast.ConditionalExpression:
+		branch, err := pick(node, func(n ast.Expression) (float64, error) {
+			d, err := f.eval(n)
+			return d.value, err
+		})
+		if err != nil {
+			return dual{}, err
+		}
+		return f.eval(branch)
+
+	case *ast.FunctionCall:
+		return f.call(node)
+	}
+
+	return dual{}, &eval.Error{Msg: fmt.Sprintf("cannot differentiate %T", node)}
+}
+
+func (f *forward) identifier(node *ast.Identifier) (dual, error) {
+	v, ok := f.vars[node.Value]
+	if !ok {
+		if c, ok := builtin.LookupConstant(node.Value); ok {
+			return dual{value: c}, nil
+		}
+		return dual{}, newError(node.Token, "identifier not found: %s", node.Value)
+	}
+
+	i, ok := f.index[node.Value]
+	if !ok {
+		return dual{value: v}, nil
+	}
+	grad := make([]float64, f.n)
+	grad[i] = 1
+	return dual{v, grad}, nil
+}
+
+func (f *forward) infix(node *ast.InfixExpression) (dual, error) {
+	if !arithmetic(node.Operator) {
+		return dual{}, newError(node.Token, "expected a number, got a condition")
+	}
+
+	u, err := f.eval(node.Left)
+	if err != nil {
+		return dual{}, err
+	}
+	v, err := f.eval(node.Right)
+	if err != nil {
+		return dual{}, err
+	}
+
+	value, du, dv := partials(node.Operator, u.value, v.value, v.grad == nil)
+	return dual{value, combine(du, u.grad, dv, v.grad)}, nil
+}
+
+func (f *forward) call(node *ast.FunctionCall) (dual, error) {
+	fn, err := lookup(node)
+	if err != nil {
+		return dual{}, err
+	}
+
+	args := make([]dual, len(node.Arguments))
+	values := make([]float64, len(node.Arguments))
+	for i, arg := range node.Arguments {
+		if args[i], err = f.eval(arg); err != nil {
+			return dual{}, err
+		}
+		values[i] = args[i].value
+	}
+
+	// chain rule: the gradient of f(u, v, ...) sums f's partials times
+	// the gradients of its arguments
+	var grad []float64
+	for i, p := range fn.Deriv(values...) {
+		grad = combine(1, grad, p, args[i].grad)
+	}
+	return dual{fn.Fn(values...), grad}, nil
+}
+
+// scale returns a*x, keeping nil for zero.
+func scale(a float64, x []float64) []float64 {
+	return combine(a, x, 0, nil)
+}
+
+// combine returns a*x + b*y, where nil slices are zero and stay nil when
+// both are. Zero components contribute nothing even when a or b is
+// infinite or NaN, as in reverse mode: the infinite slope of sqrt(x) at 0
+// says nothing about the derivative with respect to y.
+func combine(a float64, x []float64, b float64, y []float64) []float64 {
+	if x == nil && y == nil {
+		return nil
+	}
+	n := max(len(x), len(y))
+	out := make([]float64, n)
+	if x != nil {
+		for i := range out {
+			if x[i] != 0 {
+				out[i] = a * x[i]
+			}
+		}
+	}
+	if y != nil {
+		for i := range out {
+			if y[i] != 0 {
+				out[i] += b * y[i]
+			}
+		}
+	}
+	return out
+}
+
+func arithmetic(op string) bool {
+	switch op {
+	case "+", "-", "*", "/", "^":
+		return true
+	}
+	return false
+}
+
+// partials returns u op v and its partial derivatives with respect to u and
+// v. For a power with a constant exponent the derivative with respect to v
+// is left out, so that negative bases do not turn it into NaN.
+func partials(op string, u, v float64, constExponent bool) (value, du, dv float64) {
+	switch op {
+	case "+":
+		return u + v, 1, 1
+	case "-":
+		return u - v, 1, -1
+	case "*":
+		return u * v, v, u
+	case "/":
+		return u / v, 1 / v, -u / (v * v)
+	}
+
+	value = math.Pow(u, v)
+	if v == 0 {
+		du = 0
+	} else {
+		du = v * math.Pow(u, v-1)
+	}
+	if !constExponent {
+		dv = value * math.Log(u)
+	}
+	return value, du, dv
+}
+
+// pick evaluates the condition of node with value and returns the branch it
+// selects. The derivative is that of the branch, which holds everywhere the
+// condition does not switch.
+func pick(node *ast.ConditionalExpression, value func(ast.Expression) (float64, error)) (ast.Expression, error) {
+	cond, err := condition(node.Condition, value)
+	if err != nil {
+		return nil, err
+	}
+	if cond {
+		return node.Consequence, nil
+	}
+	if node.Alternative == nil {
+		return nil, newError(node.Token, "no condition of %s holds", node)
+	}
+	return node.Alternative, nil
+}
+
+func condition(node ast.Expression, value func(ast.Expression) (float64, error)) (bool, error) {
+	switch node := node.(type) {
+	case *ast.PrefixExpression:
+		if node.Operator == "!" {
+			b, err := condition(node.Right, value)
+			return !b, err
+		}
+	case *ast.InfixExpression:
+		switch node.Operator {
+		case "&&", "||":
+			left, err := condition(node.Left, value)
+			if err != nil || left == (node.Operator == "||") {
+				return left, err
+			}
+			return condition(node.Right, value)
+		case "<", "<=", ">", ">=", "==", "!=":
+			l, err := value(node.Left)
+			if err != nil {
+				return false, err
+			}
+			r, err := value(node.Right)
+			if err != nil {
+				return false, err
+			}
+			return compare(node.Operator, l, r), nil
+		}
+	}
+	return false, newError(ast.TokenOf(node), "expected a condition, got a number")
+}
+
+func compare(op string, l, r float64) bool {
+	switch op {
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	case ">":
+		return l > r
+	case ">=":
+		return l >= r
+	case "==":
+		return l == r
+	}
+	return l != r
+}
+
+// lookup returns the built-in function called by node.
+func lookup(node *ast.FunctionCall) (*builtin.Function, error) {
+	ident, ok := node.Function.(*ast.Identifier)
+	if !ok {
+		return nil, newError(node.Token, "not a function: %s", node.Function)
+	}
+	fn, ok := builtin.Lookup(ident.Value)
+	if !ok {
+		return nil, newError(ident.Token, "unknown function: %s", ident.Value)
+	}
+	if fn.Deriv == nil {
+		return nil, newError(ident.Token, "no derivative rule for %s", ident.Value)
+	}
+	if len(node.Arguments) != fn.Arity {
+		return nil, newError(ident.Token,
+			"wrong number of arguments to %s: want %d, got %d",
+			fn.Name, fn.Arity, len(node.Arguments),
+		)
+	}
+	return fn, nil
+}
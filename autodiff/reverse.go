@@ -0,0 +1,296 @@
+package autodiff
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ArtroxGabriel/sigma-parser/ast"
+	"github.com/ArtroxGabriel/sigma-parser/builtin"
+	"github.com/ArtroxGabriel/sigma-parser/eval"
+)
+
+// Tape is the record of one evaluation of an expression: every value it
+// computed, in order, with the partial derivatives of each with respect to
+// the values it was computed from. A backward pass over the tape yields
+// the whole gradient at the cost of about one evaluation, however many
+// variables there are.
+type Tape struct {
+	entries []entry
+	vars    map[string]float64
+	index   map[string]int // entry of each variable, once it is read
+	root    int            // entry of the expression's value
+}
+
+// entry is one value on the tape. partials[i] is its derivative with
+// respect to entries[args[i]]; second holds the second derivatives with
+// respect to args i and j at i*len(args)+j.
+type entry struct {
+	value    float64
+	args     []int
+	partials []float64
+	second   []float64
+	variable bool // computed from a variable, rather than constant
+}
+
+// Record evaluates node at vars and returns the tape of the evaluation.
+// The tape holds for vars only: a conditional records the branch taken.
+func Record(node ast.Node, vars map[string]float64) (*Tape, error) {
+	exp, err := expression(node)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Tape{vars: vars, index: map[string]int{}}
+	root, err := t.eval(exp)
+	if err != nil {
+		return nil, err
+	}
+	// a conditional's value may be an entry recorded before its condition
+	t.root = root
+	return t, nil
+}
+
+// Value returns the value of the recorded expression.
+func (t *Tape) Value() float64 { return t.entries[t.root].value }
+
+// Len returns the number of values on the tape.
+func (t *Tape) Len() int { return len(t.entries) }
+
+// Gradient returns the partial derivative of the expression with respect to
+// every variable it was recorded with, from a single backward pass.
+func (t *Tape) Gradient() map[string]float64 {
+	adjoint := make([]float64, len(t.entries))
+	adjoint[t.root] = 1
+	for k := t.root; k >= 0; k-- {
+		e := &t.entries[k]
+		if adjoint[k] == 0 {
+			continue
+		}
+		for i, arg := range e.args {
+			adjoint[arg] += adjoint[k] * e.partials[i]
+		}
+	}
+	return t.collect(adjoint)
+}
+
+// HessianVector returns the product of the Hessian of the expression with
+// the vector v, whose missing components are zero. It differentiates the
+// backward pass along v in forward mode, so it costs a few evaluations
+// rather than the one gradient per variable a full Hessian takes.
+func (t *Tape) HessianVector(v map[string]float64) map[string]float64 {
+	// tangent of every value along v
+	tangent := make([]float64, len(t.entries))
+	for name, k := range t.index {
+		tangent[k] = v[name]
+	}
+	for k := range t.entries {
+		e := &t.entries[k]
+		for i, arg := range e.args {
+			tangent[k] += e.partials[i] * tangent[arg]
+		}
+	}
+
+	// backward pass over adjoints and their tangents
+	adjoint := make([]float64, len(t.entries))
+	adjointTangent := make([]float64, len(t.entries))
+	adjoint[t.root] = 1
+	for k := t.root; k >= 0; k-- {
+		e := &t.entries[k]
+		n := len(e.args)
+		for i, arg := range e.args {
+			// tangent of the partial, through its dependence on each arg
+			var dp float64
+			for j, other := range e.args {
+				if s := e.second[i*n+j]; s != 0 {
+					dp += s * tangent[other]
+				}
+			}
+			adjoint[arg] += adjoint[k] * e.partials[i]
+			adjointTangent[arg] += adjointTangent[k]*e.partials[i] + adjoint[k]*dp
+		}
+	}
+	return t.collect(adjointTangent)
+}
+
+// collect picks the components of the variables out of values.
+func (t *Tape) collect(values []float64) map[string]float64 {
+	out := make(map[string]float64, len(t.vars))
+	for name := range t.vars {
+		if k, ok := t.index[name]; ok {
+			out[name] = values[k]
+		} else {
+			out[name] = 0
+		}
+	}
+	return out
+}
+
+// push appends an entry to the tape and returns its index.
+func (t *Tape) push(e entry) int {
+	for _, arg := range e.args {
+		e.variable = e.variable || t.entries[arg].variable
+	}
+	t.entries = append(t.entries, e)
+	return len(t.entries) - 1
+}
+
+func (t *Tape) constant(v float64) int { return t.push(entry{value: v}) }
+
+func (t *Tape) value(node ast.Expression) (float64, error) {
+	k, err := t.eval(node)
+	if err != nil {
+		return 0, err
+	}
+	return t.entries[k].value, nil
+}
+
+// eval records node and returns the index of its value.
+func (t *Tape) eval(node ast.Expression) (int, error) {
+	switch node := node.(type) {
+	case *ast.NumberLiteral:
+		if node.IsImaginary() {
+			return 0, newError(node.Token, "cannot differentiate imaginary number %s", node)
+		}
+		return t.constant(node.Value), nil
+
+	case *ast.Constant:
+		return t.constant(node.Value), nil
+
+	case *ast.Identifier:
+		return t.identifier(node)
+
+	case *ast.PrefixExpression:
+		if node.Operator == "!" {
+			return 0, newError(node.Token, "expected a number, got a condition")
+		}
+		right, err := t.eval(node.Right)
+		if err != nil || node.Operator != "-" {
+			return right, err
+		}
+		return t.push(entry{
+			value:    -t.entries[right].value,
+			args:     []int{right},
+			partials: []float64{-1},
+			second:   []float64{0},
+		}), nil
+
+	case *ast.PostfixExpression:
+		if node.Operator != "%" {
+			return 0, newError(node.Token, "no derivative rule for %s", node.Operator)
+		}
+		left, err := t.eval(node.Left)
+		if err != nil {
+			return 0, err
+		}
+		return t.push(entry{
+			value:    t.entries[left].value / 100,
+			args:     []int{left},
+			partials: []float64{0.01},
+			second:   []float64{0},
+		}), nil
+
+	case *ast.InfixExpression:
+		return t.infix(node)
+
+	case *ast.ConditionalExpression:
+		branch, err := pick(node, t.value)
+		if err != nil {
+			return 0, err
+		}
+		return t.eval(branch)
+
+	case *ast.FunctionCall:
+		return t.call(node)
+	}
+
+	return 0, &eval.Error{Msg: fmt.Sprintf("cannot differentiate %T", node)}
+}
+
+// identifier records a variable once and reuses its entry after that, so
+// that the adjoints of all its uses add up in one place.
+func (t *Tape) identifier(node *ast.Identifier) (int, error) {
+	if k, ok := t.index[node.Value]; ok {
+		return k, nil
+	}
+	v, ok := t.vars[node.Value]
+	if !ok {
+		if c, ok := builtin.LookupConstant(node.Value); ok {
+			return t.constant(c), nil
+		}
+		return 0, newError(node.Token, "identifier not found: %s", node.Value)
+	}
+	k := t.push(entry{value: v, variable: true})
+	t.index[node.Value] = k
+	return k, nil
+}
+
+func (t *Tape) infix(node *ast.InfixExpression) (int, error) {
+	if !arithmetic(node.Operator) {
+		return 0, newError(node.Token, "expected a number, got a condition")
+	}
+
+	left, err := t.eval(node.Left)
+	if err != nil {
+		return 0, err
+	}
+	right, err := t.eval(node.Right)
+	if err != nil {
+		return 0, err
+	}
+
+	u, v := t.entries[left].value, t.entries[right].value
+	constExponent := !t.entries[right].variable
+	value, du, dv := partials(node.Operator, u, v, constExponent)
+	uu, uv, vv := secondPartials(node.Operator, u, v, constExponent)
+	return t.push(entry{
+		value:    value,
+		args:     []int{left, right},
+		partials: []float64{du, dv},
+		second:   []float64{uu, uv, uv, vv},
+	}), nil
+}
+
+func (t *Tape) call(node *ast.FunctionCall) (int, error) {
+	fn, err := lookup(node)
+	if err != nil {
+		return 0, err
+	}
+
+	args := make([]int, len(node.Arguments))
+	values := make([]float64, len(node.Arguments))
+	for i, arg := range node.Arguments {
+		if args[i], err = t.eval(arg); err != nil {
+			return 0, err
+		}
+		values[i] = t.entries[args[i]].value
+	}
+
+	e := entry{value: fn.Fn(values...), args: args, partials: fn.Deriv(values...)}
+	if fn.Deriv2 != nil {
+		e.second = fn.Deriv2(values...)
+	} else {
+		e.second = make([]float64, len(args)*len(args))
+	}
+	return t.push(e), nil
+}
+
+// secondPartials returns the second derivatives of u op v with respect to
+// u twice, u and v, and v twice.
+func secondPartials(op string, u, v float64, constExponent bool) (uu, uv, vv float64) {
+	switch op {
+	case "*":
+		return 0, 1, 0
+	case "/":
+		return 0, -1 / (v * v), 2 * u / (v * v * v)
+	case "^":
+		if v != 0 && v != 1 {
+			uu = v * (v - 1) * math.Pow(u, v-2)
+		}
+		if !constExponent {
+			uv = math.Pow(u, v-1) * (1 + v*math.Log(u))
+			vv = math.Pow(u, v) * math.Log(u) * math.Log(u)
+		}
+		return uu, uv, vv
+	}
+	return 0, 0, 0
+}
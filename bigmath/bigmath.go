@@ -0,0 +1,496 @@
+// Package bigmath implements the elementary functions on big.Float values,
+// correct to a requested precision in bits.
+//
+// Like big.Float.Sqrt, the functions panic with a big.ErrNaN when the result
+// is not a number, such as the logarithm of a negative value.
+package bigmath
+
+import (
+	"math"
+	"math/big"
+)
+
+// guard is the number of extra bits carried by intermediate results.
+const guard = 64
+
+// newFloat returns a zero with the given precision.
+func newFloat(prec uint) *big.Float { return new(big.Float).SetPrec(prec) }
+
+// round returns x rounded to prec bits.
+func round(x *big.Float, prec uint) *big.Float { return newFloat(prec).Set(x) }
+
+// exponent returns the binary exponent of x, so that 2^(exp-1) <= |x| < 2^exp.
+func exponent(x *big.Float) int { return x.MantExp(nil) }
+
+// negligible reports whether term no longer changes sum at wp bits.
+func negligible(term, sum *big.Float, wp uint) bool {
+	if term.Sign() == 0 {
+		return true
+	}
+	if sum.Sign() == 0 {
+		return exponent(term) < -int(wp)
+	}
+	return exponent(term) < exponent(sum)-int(wp)
+}
+
+// Pi returns π, computed with the Gauss–Legendre algorithm.
+func Pi(prec uint) *big.Float {
+	wp := prec + guard
+	a := newFloat(wp).SetInt64(1)
+	b := newFloat(wp).Sqrt(newFloat(wp).SetFloat64(0.5))
+	t := newFloat(wp).SetFloat64(0.25)
+	p := newFloat(wp).SetInt64(1)
+
+	for range 64 {
+		next := newFloat(wp).Add(a, b)
+		next.Quo(next, newFloat(wp).SetInt64(2))
+		b.Sqrt(newFloat(wp).Mul(a, b))
+
+		d := newFloat(wp).Sub(a, next)
+		d.Mul(d, d)
+		t.Sub(t, d.Mul(d, p))
+		a = next
+		p.Add(p, p)
+
+		if diff := newFloat(wp).Sub(a, b); diff.Sign() == 0 || exponent(diff) < -int(wp)/2 {
+			break
+		}
+	}
+
+	pi := newFloat(wp).Add(a, b)
+	pi.Mul(pi, pi)
+	return round(pi.Quo(pi, t.Mul(t, newFloat(wp).SetInt64(4))), prec)
+}
+
+// Ln2 returns the natural logarithm of 2, as 2·atanh(1/3).
+func Ln2(prec uint) *big.Float {
+	wp := prec + guard
+	third := newFloat(wp).Quo(newFloat(wp).SetInt64(1), newFloat(wp).SetInt64(3))
+	ninth := newFloat(wp).Mul(third, third)
+
+	sum := newFloat(wp)
+	power := newFloat(wp).Set(third)
+	for n := int64(1); ; n += 2 {
+		term := newFloat(wp).Quo(power, newFloat(wp).SetInt64(n))
+		sum.Add(sum, term)
+		if negligible(term, sum, wp) {
+			break
+		}
+		power.Mul(power, ninth)
+	}
+	return round(sum.Add(sum, sum), prec)
+}
+
+// Exp returns e^x.
+func Exp(x *big.Float, prec uint) *big.Float {
+	switch {
+	case x.IsInf():
+		if x.Sign() > 0 {
+			return newFloat(prec).SetInf(false)
+		}
+		return newFloat(prec)
+	case x.Sign() == 0:
+		return newFloat(prec).SetInt64(1)
+	case exponent(x) > 32:
+		// beyond the exponent range of big.Float
+		if x.Sign() > 0 {
+			return newFloat(prec).SetInf(false)
+		}
+		return newFloat(prec)
+	}
+
+	// x = k·ln2 + r with |r| < ln2, so e^x = 2^k · e^r
+	wp := prec + guard + uint(max(exponent(x), 0))
+	ln2 := Ln2(wp)
+	k, _ := newFloat(wp).Quo(x, ln2).Int64()
+	r := newFloat(wp).Sub(x, newFloat(wp).Mul(ln2, newFloat(wp).SetInt64(k)))
+
+	// e^r = (e^(r/2^s))^(2^s), where the series converges quickly
+	const s = 8
+	r.SetMantExp(r, -s)
+
+	sum := newFloat(wp).SetInt64(1)
+	term := newFloat(wp).SetInt64(1)
+	for n := int64(1); ; n++ {
+		term.Mul(term, r)
+		term.Quo(term, newFloat(wp).SetInt64(n))
+		sum.Add(sum, term)
+		if negligible(term, sum, wp) {
+			break
+		}
+	}
+	for range s {
+		sum.Mul(sum, sum)
+	}
+
+	// SetMantExp saturates to Inf or zero outside the exponent range
+	return round(sum.SetMantExp(sum, int(k)), prec)
+}
+
+// Log returns the natural logarithm of x.
+func Log(x *big.Float, prec uint) *big.Float {
+	switch {
+	case x.Sign() < 0:
+		panic(big.ErrNaN{})
+	case x.Sign() == 0:
+		return newFloat(prec).SetInf(true)
+	case x.IsInf():
+		return newFloat(prec).SetInf(false)
+	}
+
+	wp := prec + guard
+	one := newFloat(wp).SetInt64(1)
+
+	// near 1 the result is small, so it needs as many more bits as x - 1
+	// has leading zeros
+	if d := newFloat(wp).Sub(x, one); d.Sign() != 0 && exponent(d) < 0 {
+		wp += uint(-exponent(d))
+		return round(logHalley(x, wp), prec)
+	}
+
+	// x = m·2^k with 0.5 <= m < 1, so ln x = ln m + k·ln2
+	m := newFloat(wp)
+	k := x.MantExp(m)
+	result := logHalley(m, wp)
+	result.Add(result, newFloat(wp).Mul(Ln2(wp), newFloat(wp).SetInt64(int64(k))))
+	return round(result, prec)
+}
+
+// logHalley solves e^y = x for y with Halley's method, starting from the
+// float64 logarithm. Each step triples the number of correct bits.
+func logHalley(x *big.Float, wp uint) *big.Float {
+	f, _ := x.Float64()
+	y := newFloat(wp).SetFloat64(math.Log(f))
+
+	for range 64 {
+		ey := Exp(y, wp)
+		num := newFloat(wp).Sub(x, ey)
+		den := newFloat(wp).Add(x, ey)
+		step := num.Quo(num, den)
+		step.Add(step, step)
+		y.Add(y, step)
+		if negligible(step, y, wp) {
+			break
+		}
+	}
+	return y
+}
+
+// Log10 returns the decimal logarithm of x.
+func Log10(x *big.Float, prec uint) *big.Float {
+	wp := prec + guard
+	l := Log(x, wp)
+	if l.IsInf() {
+		return round(l, prec)
+	}
+	return round(l.Quo(l, Log(newFloat(wp).SetInt64(10), wp)), prec)
+}
+
+// Log2 returns the binary logarithm of x, exactly when x is a power of two.
+func Log2(x *big.Float, prec uint) *big.Float {
+	if x.Sign() > 0 && !x.IsInf() {
+		m := new(big.Float)
+		k := x.MantExp(m)
+		if m.Cmp(big.NewFloat(0.5)) == 0 {
+			return newFloat(prec).SetInt64(int64(k - 1))
+		}
+	}
+
+	wp := prec + guard
+	l := Log(x, wp)
+	if l.IsInf() {
+		return round(l, prec)
+	}
+	return round(l.Quo(l, Ln2(wp)), prec)
+}
+
+// Sqrt returns the square root of x.
+func Sqrt(x *big.Float, prec uint) *big.Float {
+	return newFloat(prec).Sqrt(x)
+}
+
+// Pow returns x^y. Integer powers of any x are computed by repeated
+// squaring, other powers of positive x as e^(y·ln x).
+func Pow(x, y *big.Float, prec uint) *big.Float {
+	wp := prec + guard
+
+	if y.IsInt() && !y.IsInf() {
+		if n, acc := y.Int64(); acc == big.Exact && n >= -1<<20 && n <= 1<<20 {
+			return round(powInt(x, n, wp), prec)
+		}
+	}
+
+	switch {
+	case x.Sign() < 0:
+		if !y.IsInt() {
+			panic(big.ErrNaN{})
+		}
+		// (-x)^n for a huge integer n: the sign follows the parity of n
+		abs := Pow(newFloat(wp).Neg(x), y, wp)
+		half := newFloat(0).SetMantExp(y, -1)
+		if !half.IsInt() {
+			abs.Neg(abs)
+		}
+		return round(abs, prec)
+	case x.Sign() == 0:
+		if y.Sign() < 0 {
+			return newFloat(prec).SetInf(false)
+		}
+		return newFloat(prec)
+	}
+
+	l := Log(x, wp)
+	return Exp(l.Mul(l, y), prec)
+}
+
+func powInt(x *big.Float, n int64, wp uint) *big.Float {
+	result := newFloat(wp).SetInt64(1)
+	base := newFloat(wp).Set(x)
+	for k := n; k != 0; k /= 2 {
+		if k%2 != 0 {
+			result.Mul(result, base)
+		}
+		base.Mul(base, base)
+	}
+	if n < 0 {
+		return result.Quo(newFloat(wp).SetInt64(1), result)
+	}
+	return result
+}
+
+// sincos returns the sine and cosine of x.
+func sincos(x *big.Float, prec uint) (sin, cos *big.Float) {
+	if x.IsInf() {
+		panic(big.ErrNaN{})
+	}
+	if x.Sign() == 0 {
+		return newFloat(prec), newFloat(prec).SetInt64(1)
+	}
+
+	// x = k·π/2 + r with |r| <= π/4; the reduction needs as many more bits
+	// as x has integer bits
+	wp := prec + guard + uint(max(exponent(x), 0))
+	halfPi := Pi(wp)
+	halfPi.SetMantExp(halfPi, -1)
+
+	q := newFloat(wp).Quo(x, halfPi)
+	q.Add(q, newFloat(wp).SetFloat64(0.5*float64(q.Sign())))
+	k, _ := q.Int(nil)
+	r := newFloat(wp).Sub(x, newFloat(wp).Mul(halfPi, newFloat(wp).SetInt(k)))
+
+	r2 := newFloat(wp).Mul(r, r)
+	s := newFloat(wp).Set(r)
+	term := newFloat(wp).Set(r)
+	for n := int64(1); ; n++ {
+		term.Mul(term, r2)
+		term.Quo(term, newFloat(wp).SetInt64(-(2*n)*(2*n+1)))
+		s.Add(s, term)
+		if negligible(term, s, wp) {
+			break
+		}
+	}
+	c := newFloat(wp).SetInt64(1)
+	term.SetInt64(1)
+	for n := int64(1); ; n++ {
+		term.Mul(term, r2)
+		term.Quo(term, newFloat(wp).SetInt64(-(2*n-1)*(2*n)))
+		c.Add(c, term)
+		if negligible(term, c, wp) {
+			break
+		}
+	}
+
+	switch new(big.Int).And(k, big.NewInt(3)).Int64() {
+	case 1:
+		s, c = c, s.Neg(s)
+	case 2:
+		s, c = s.Neg(s), c.Neg(c)
+	case 3:
+		s, c = c.Neg(c), s
+	}
+	return round(s, prec), round(c, prec)
+}
+
+// Sin returns the sine of x.
+func Sin(x *big.Float, prec uint) *big.Float {
+	s, _ := sincos(x, prec)
+	return s
+}
+
+// Cos returns the cosine of x.
+func Cos(x *big.Float, prec uint) *big.Float {
+	_, c := sincos(x, prec)
+	return c
+}
+
+// Tan returns the tangent of x.
+func Tan(x *big.Float, prec uint) *big.Float {
+	s, c := sincos(x, prec+guard)
+	return round(s.Quo(s, c), prec)
+}
+
+// Atan returns the arctangent of x.
+func Atan(x *big.Float, prec uint) *big.Float {
+	wp := prec + guard
+	switch {
+	case x.Sign() == 0:
+		return newFloat(prec)
+	case x.IsInf():
+		halfPi := Pi(prec)
+		halfPi.SetMantExp(halfPi, -1)
+		if x.Sign() < 0 {
+			halfPi.Neg(halfPi)
+		}
+		return halfPi
+	}
+
+	a := newFloat(wp).Abs(x)
+	one := newFloat(wp).SetInt64(1)
+
+	// atan(a) = π/2 - atan(1/a) brings a into [0, 1]
+	inverted := a.Cmp(one) > 0
+	if inverted {
+		a.Quo(one, a)
+	}
+
+	// atan(a) = 2·atan(a / (1 + sqrt(1 + a²))) shrinks a further
+	const halvings = 8
+	for range halvings {
+		d := newFloat(wp).Mul(a, a)
+		d.Add(d, one)
+		d.Sqrt(d)
+		a.Quo(a, d.Add(d, one))
+	}
+
+	a2 := newFloat(wp).Mul(a, a)
+	sum := newFloat(wp).Set(a)
+	power := newFloat(wp).Set(a)
+	for n := int64(1); ; n++ {
+		power.Mul(power, a2)
+		power.Neg(power)
+		term := newFloat(wp).Quo(power, newFloat(wp).SetInt64(2*n+1))
+		sum.Add(sum, term)
+		if negligible(term, sum, wp) {
+			break
+		}
+	}
+	sum.SetMantExp(sum, halvings)
+
+	if inverted {
+		halfPi := Pi(wp)
+		halfPi.SetMantExp(halfPi, -1)
+		sum.Sub(halfPi, sum)
+	}
+	if x.Sign() < 0 {
+		sum.Neg(sum)
+	}
+	return round(sum, prec)
+}
+
+// Asin returns the arcsine of x, which must lie in [-1, 1].
+func Asin(x *big.Float, prec uint) *big.Float {
+	wp := prec + guard
+	one := newFloat(wp).SetInt64(1)
+	a := newFloat(wp).Abs(x)
+
+	switch a.Cmp(one) {
+	case 1:
+		panic(big.ErrNaN{})
+	case 0:
+		halfPi := Pi(prec)
+		halfPi.SetMantExp(halfPi, -1)
+		if x.Sign() < 0 {
+			halfPi.Neg(halfPi)
+		}
+		return halfPi
+	}
+
+	// asin(x) = atan(x / sqrt((1 - x)(1 + x)))
+	d := newFloat(wp).Sub(one, x)
+	d.Mul(d, newFloat(wp).Add(one, x))
+	d.Sqrt(d)
+	return Atan(d.Quo(x, d), prec)
+}
+
+// Acos returns the arccosine of x, which must lie in [-1, 1].
+func Acos(x *big.Float, prec uint) *big.Float {
+	wp := prec + guard
+	one := newFloat(wp).SetInt64(1)
+
+	if newFloat(wp).Abs(x).Cmp(one) > 0 {
+		panic(big.ErrNaN{})
+	}
+	if x.Cmp(newFloat(wp).Neg(one)) == 0 {
+		return Pi(prec)
+	}
+
+	// acos(x) = 2·atan(sqrt((1 - x) / (1 + x))), accurate near x = 1
+	d := newFloat(wp).Sub(one, x)
+	d.Quo(d, newFloat(wp).Add(one, x))
+	d.Sqrt(d)
+	a := Atan(d, wp)
+	return round(a.Add(a, a), prec)
+}
+
+// Sinh returns the hyperbolic sine of x.
+func Sinh(x *big.Float, prec uint) *big.Float {
+	wp := prec + guard
+	if x.IsInf() {
+		return round(x, prec)
+	}
+
+	// the series avoids the cancellation of e^x - e^-x for small x
+	if exponent(x) <= 0 {
+		x2 := newFloat(wp).Mul(x, x)
+		sum := newFloat(wp).Set(x)
+		term := newFloat(wp).Set(x)
+		for n := int64(1); ; n++ {
+			term.Mul(term, x2)
+			term.Quo(term, newFloat(wp).SetInt64((2*n)*(2*n+1)))
+			sum.Add(sum, term)
+			if negligible(term, sum, wp) {
+				break
+			}
+		}
+		return round(sum, prec)
+	}
+
+	ex := Exp(x, wp)
+	if ex.IsInf() {
+		return round(ex, prec)
+	}
+	if ex.Sign() == 0 {
+		return newFloat(prec).SetInf(true)
+	}
+	s := newFloat(wp).Quo(newFloat(wp).SetInt64(1), ex)
+	s.Sub(ex, s)
+	return round(s.SetMantExp(s, -1), prec)
+}
+
+// Cosh returns the hyperbolic cosine of x.
+func Cosh(x *big.Float, prec uint) *big.Float {
+	wp := prec + guard
+	if x.IsInf() {
+		return newFloat(prec).SetInf(false)
+	}
+
+	ex := Exp(newFloat(wp).Abs(x), wp)
+	if ex.IsInf() {
+		return round(ex, prec)
+	}
+	c := newFloat(wp).Quo(newFloat(wp).SetInt64(1), ex)
+	c.Add(ex, c)
+	return round(c.SetMantExp(c, -1), prec)
+}
+
+// Tanh returns the hyperbolic tangent of x.
+func Tanh(x *big.Float, prec uint) *big.Float {
+	wp := prec + guard
+
+	// beyond wp/2 the result rounds to ±1
+	if x.IsInf() || newFloat(wp).Abs(x).Cmp(newFloat(wp).SetInt64(int64(wp/2+1))) > 0 {
+		return newFloat(prec).SetInt64(int64(x.Sign()))
+	}
+	s := Sinh(x, wp)
+	return round(s.Quo(s, Cosh(x, wp)), prec)
+}
@@ -0,0 +1,153 @@
+package bigmath_test
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/ArtroxGabriel/sigma-parser/bigmath"
+)
+
+const prec = 256
+
+func parse(t *testing.T, s string) *big.Float {
+	t.Helper()
+	f, _, err := big.ParseFloat(s, 10, prec, big.ToNearestEven)
+	if err != nil {
+		t.Fatalf("ParseFloat(%q) error = %v", s, err)
+	}
+	return f
+}
+
+// close reports whether got and want agree to within 2^-(prec-8) relative.
+func close(got, want *big.Float) bool {
+	diff := new(big.Float).SetPrec(prec).Sub(got, want)
+	if diff.Sign() == 0 {
+		return true
+	}
+	scale := want.MantExp(nil)
+	if want.Sign() == 0 {
+		scale = 0
+	}
+	return diff.MantExp(nil) < scale-(prec-8)
+}
+
+func TestConstants(t *testing.T) {
+	tests := []struct {
+		name string
+		got  *big.Float
+		want string
+	}{
+		{"pi", bigmath.Pi(prec), "3.14159265358979323846264338327950288419716939937510582097494459230781640628620899"},
+		{"ln2", bigmath.Ln2(prec), "0.693147180559945309417232121458176568075500134360255254120680009493393621969694715"},
+		{"e", bigmath.Exp(big.NewFloat(1), prec), "2.71828182845904523536028747135266249775724709369995957496696762772407663035354759"},
+		{"sqrt2", bigmath.Sqrt(big.NewFloat(2), prec), "1.41421356237309504880168872420969807856967187537694807317667973799073247846210704"},
+		{"sin1", bigmath.Sin(big.NewFloat(1), prec), "0.841470984807896506652502321630298999622563060798371065672751709991910404391239669"},
+		{"atan1", bigmath.Atan(big.NewFloat(1), prec), "0.785398163397448309615660845819875721049292349843776455243736148076954101571552250"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if want := parse(t, tt.want); !close(tt.got, want) {
+				t.Errorf("%s = %s, want %s", tt.name, tt.got.Text('g', 80), tt.want)
+			}
+		})
+	}
+}
+
+// TestAgainstFloat64 checks every function against package math.
+func TestAgainstFloat64(t *testing.T) {
+	tests := []struct {
+		name string
+		big  func(*big.Float, uint) *big.Float
+		f    func(float64) float64
+		args []float64
+	}{
+		{"exp", bigmath.Exp, math.Exp, []float64{-30, -1, -1e-9, 0.5, 3, 100}},
+		{"log", bigmath.Log, math.Log, []float64{1e-300, 0.25, 0.999, 1.001, 2, 10, 1e300}},
+		{"log10", bigmath.Log10, math.Log10, []float64{0.001, 3, 1e12}},
+		{"log2", bigmath.Log2, math.Log2, []float64{0.125, 3, 1024}},
+		{"sin", bigmath.Sin, math.Sin, []float64{-7, -0.1, 1e-8, 2, 3.14159, 100}},
+		{"cos", bigmath.Cos, math.Cos, []float64{-7, 0, 1.5, 1.5707963, 4, 100}},
+		{"tan", bigmath.Tan, math.Tan, []float64{-1, 0.3, 1.5, 10}},
+		{"atan", bigmath.Atan, math.Atan, []float64{-50, -1, 0.001, 0.7, 3}},
+		{"asin", bigmath.Asin, math.Asin, []float64{-1, -0.5, 0.1, 0.999, 1}},
+		{"acos", bigmath.Acos, math.Acos, []float64{-1, -0.5, 0.1, 0.999, 1}},
+		{"sinh", bigmath.Sinh, math.Sinh, []float64{-5, -0.5, 1e-10, 0.9, 20}},
+		{"cosh", bigmath.Cosh, math.Cosh, []float64{-5, 0, 0.9, 20}},
+		{"tanh", bigmath.Tanh, math.Tanh, []float64{-400, -2, 1e-5, 0.5, 30}},
+	}
+
+	for _, tt := range tests {
+		for _, x := range tt.args {
+			got, _ := tt.big(big.NewFloat(x), prec).Float64()
+			want := tt.f(x)
+			if math.Abs(got-want) > 1e-15*math.Max(1, math.Abs(want)) {
+				t.Errorf("%s(%v) = %v, want %v", tt.name, x, got, want)
+			}
+		}
+	}
+}
+
+func TestIdentities(t *testing.T) {
+	x := parse(t, "0.7")
+	one := big.NewFloat(1)
+
+	if got := bigmath.Exp(bigmath.Log(x, prec+16), prec); !close(got, x) {
+		t.Errorf("exp(ln(0.7)) = %s", got.Text('g', 80))
+	}
+	s, c := bigmath.Sin(x, prec+16), bigmath.Cos(x, prec+16)
+	sum := new(big.Float).SetPrec(prec).Mul(s, s)
+	sum.Add(sum, new(big.Float).Mul(c, c))
+	if !close(sum, one) {
+		t.Errorf("sin²+cos² = %s", sum.Text('g', 80))
+	}
+	if got := bigmath.Tan(bigmath.Atan(x, prec+16), prec); !close(got, x) {
+		t.Errorf("tan(atan(0.7)) = %s", got.Text('g', 80))
+	}
+	if got := bigmath.Log2(big.NewFloat(1<<40), prec); got.Cmp(big.NewFloat(40)) != 0 {
+		t.Errorf("log2(2^40) = %s, want exactly 40", got.Text('g', 20))
+	}
+}
+
+func TestPow(t *testing.T) {
+	tests := []struct {
+		x, y float64
+		want string
+	}{
+		{2, 10, "1024"},
+		{-2, 3, "-8"},
+		{2, -2, "0.25"},
+		{2, 0.5, "1.41421356237309504880168872420969807856967187537694807317667973799073247846210704"},
+		{10, 1.5, "31.6227766016837933199889354443271853371955513932521682685750485279259443863923823"},
+		{0, 2, "0"},
+	}
+
+	for _, tt := range tests {
+		got := bigmath.Pow(big.NewFloat(tt.x), big.NewFloat(tt.y), prec)
+		if want := parse(t, tt.want); !close(got, want) {
+			t.Errorf("Pow(%v, %v) = %s, want %s", tt.x, tt.y, got.Text('g', 80), tt.want)
+		}
+	}
+}
+
+func TestNaN(t *testing.T) {
+	tests := map[string]func(){
+		"log(-1)":    func() { bigmath.Log(big.NewFloat(-1), prec) },
+		"asin(2)":    func() { bigmath.Asin(big.NewFloat(2), prec) },
+		"sqrt(-1)":   func() { bigmath.Sqrt(big.NewFloat(-1), prec) },
+		"(-8)^(1/3)": func() { bigmath.Pow(big.NewFloat(-8), big.NewFloat(1.0/3), prec) },
+		"sin(+Inf)":  func() { bigmath.Sin(new(big.Float).SetInf(false), prec) },
+	}
+
+	for name, f := range tests {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if _, ok := recover().(big.ErrNaN); !ok {
+					t.Errorf("%s did not panic with big.ErrNaN", name)
+				}
+			}()
+			f()
+		})
+	}
+}
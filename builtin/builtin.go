@@ -0,0 +1,151 @@
+// Package builtin holds the registry of functions and constants that every
+// evaluator and analysis in sigma-parser understands.
+package builtin
+
+import (
+	"math"
+	"math/big"
+	"math/cmplx"
+	"sort"
+
+	"github.com/ArtroxGabriel/sigma-parser/bigmath"
+)
+
+// Function describes a built-in mathematical function.
+type Function struct {
+	Name    string                              // Name used in expressions (sin, sqrt, etc.)
+	Arity   int                                 // Number of arguments the function takes
+	Fn      func(args ...float64) float64       // Float64 implementation
+	Complex func(args ...complex128) complex128 // Complex128 implementation
+
+	// Big computes the result to prec bits and panics with big.ErrNaN
+	// outside the function's domain
+	Big func(prec uint, args ...*big.Float) *big.Float
+
+	// Deriv returns the partial derivative of Fn with respect to each
+	// argument, evaluated at args
+	Deriv func(args ...float64) []float64
+
+	// Deriv2 returns the second partial derivatives of Fn at args, row by
+	// row: with respect to arguments i and j at i*Arity+j
+	Deriv2 func(args ...float64) []float64
+}
+
+var functions = map[string]*Function{}
+
+var constants = map[string]float64{
+	"pi": math.Pi,
+	"PI": math.Pi,
+	"e":  math.E,
+	"E":  math.E,
+}
+
+// unary holds the implementations of a one-argument function
+type unary struct {
+	real    func(float64) float64
+	complex func(complex128) complex128
+	big     func(*big.Float, uint) *big.Float
+	deriv   func(float64) float64
+	deriv2  func(float64) float64
+}
+
+func init() {
+	unaries := map[string]unary{
+		"sin":  {math.Sin, cmplx.Sin, bigmath.Sin, math.Cos, func(x float64) float64 { return -math.Sin(x) }},
+		"cos":  {math.Cos, cmplx.Cos, bigmath.Cos, func(x float64) float64 { return -math.Sin(x) }, func(x float64) float64 { return -math.Cos(x) }},
+		"tan":  {math.Tan, cmplx.Tan, bigmath.Tan, func(x float64) float64 { return 1 / (math.Cos(x) * math.Cos(x)) }, func(x float64) float64 { return 2 * math.Tan(x) / (math.Cos(x) * math.Cos(x)) }},
+		"asin": {math.Asin, cmplx.Asin, bigmath.Asin, func(x float64) float64 { return 1 / math.Sqrt(1-x*x) }, func(x float64) float64 { return x / math.Pow(1-x*x, 1.5) }},
+		"acos": {math.Acos, cmplx.Acos, bigmath.Acos, func(x float64) float64 { return -1 / math.Sqrt(1-x*x) }, func(x float64) float64 { return -x / math.Pow(1-x*x, 1.5) }},
+		"atan": {math.Atan, cmplx.Atan, bigmath.Atan, func(x float64) float64 { return 1 / (1 + x*x) }, func(x float64) float64 { return -2 * x / ((1 + x*x) * (1 + x*x)) }},
+		"sinh": {math.Sinh, cmplx.Sinh, bigmath.Sinh, math.Cosh, math.Sinh},
+		"cosh": {math.Cosh, cmplx.Cosh, bigmath.Cosh, math.Sinh, math.Cosh},
+		"tanh": {math.Tanh, cmplx.Tanh, bigmath.Tanh, func(x float64) float64 { return 1 / (math.Cosh(x) * math.Cosh(x)) }, func(x float64) float64 { return -2 * math.Tanh(x) / (math.Cosh(x) * math.Cosh(x)) }},
+		"sqrt": {math.Sqrt, cmplx.Sqrt, bigmath.Sqrt, func(x float64) float64 { return 1 / (2 * math.Sqrt(x)) }, func(x float64) float64 { return -1 / (4 * x * math.Sqrt(x)) }},
+		"exp":  {math.Exp, cmplx.Exp, bigmath.Exp, math.Exp, math.Exp},
+		"ln":   {math.Log, cmplx.Log, bigmath.Log, func(x float64) float64 { return 1 / x }, func(x float64) float64 { return -1 / (x * x) }},
+		"log":  {math.Log10, cmplx.Log10, bigmath.Log10, func(x float64) float64 { return 1 / (x * math.Ln10) }, func(x float64) float64 { return -1 / (x * x * math.Ln10) }},
+		"log2": {math.Log2, func(z complex128) complex128 { return cmplx.Log(z) / math.Ln2 }, bigmath.Log2, func(x float64) float64 { return 1 / (x * math.Ln2) }, func(x float64) float64 { return -1 / (x * x * math.Ln2) }},
+		"abs":  {math.Abs, func(z complex128) complex128 { return complex(cmplx.Abs(z), 0) }, bigAbs, func(x float64) float64 { return x / math.Abs(x) }, zero},
+		"re":   {func(x float64) float64 { return x }, func(z complex128) complex128 { return complex(real(z), 0) }, bigIdentity, one, zero},
+		"im":   {func(float64) float64 { return 0 }, func(z complex128) complex128 { return complex(imag(z), 0) }, bigZero, zero, zero},
+		"arg":  {func(x float64) float64 { return cmplx.Phase(complex(x, 0)) }, func(z complex128) complex128 { return complex(cmplx.Phase(z), 0) }, bigArg, zero, zero},
+		"conj": {func(x float64) float64 { return x }, cmplx.Conj, bigIdentity, one, zero},
+	}
+	for name, fn := range unaries {
+		register(&Function{
+			Name:    name,
+			Arity:   1,
+			Fn:      func(args ...float64) float64 { return fn.real(args[0]) },
+			Complex: func(args ...complex128) complex128 { return fn.complex(args[0]) },
+			Big:     func(prec uint, args ...*big.Float) *big.Float { return fn.big(args[0], prec) },
+			Deriv:   func(args ...float64) []float64 { return []float64{fn.deriv(args[0])} },
+			Deriv2:  func(args ...float64) []float64 { return []float64{fn.deriv2(args[0])} },
+		})
+	}
+}
+
+func register(fn *Function) { functions[fn.Name] = fn }
+
+// one and zero are the derivatives of the identity and of constants; re,
+// im, arg and conj act on real numbers as one of those
+func one(float64) float64  { return 1 }
+func zero(float64) float64 { return 0 }
+
+func bigAbs(x *big.Float, prec uint) *big.Float {
+	return new(big.Float).SetPrec(prec).Abs(x)
+}
+
+func bigIdentity(x *big.Float, prec uint) *big.Float {
+	return new(big.Float).SetPrec(prec).Set(x)
+}
+
+func bigZero(_ *big.Float, prec uint) *big.Float {
+	return new(big.Float).SetPrec(prec)
+}
+
+// bigArg is the phase of a real number: π for negative numbers, else 0.
+func bigArg(x *big.Float, prec uint) *big.Float {
+	if x.Sign() < 0 {
+		return bigmath.Pi(prec)
+	}
+	return new(big.Float).SetPrec(prec)
+}
+
+// Lookup returns the built-in function with the given name.
+func Lookup(name string) (*Function, bool) {
+	fn, ok := functions[name]
+	return fn, ok
+}
+
+// Functions returns every registered function sorted by name.
+func Functions() []*Function {
+	list := make([]*Function, 0, len(functions))
+	for _, fn := range functions {
+		list = append(list, fn)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// LookupConstant returns the value of the named constant (pi, e).
+func LookupConstant(name string) (float64, bool) {
+	v, ok := constants[name]
+	return v, ok
+}
+
+// LookupBigConstant returns the named constant computed to prec bits.
+func LookupBigConstant(name string, prec uint) (*big.Float, bool) {
+	switch name {
+	case "pi", "PI":
+		return bigmath.Pi(prec), true
+	case "e", "E":
+		return bigmath.Exp(big.NewFloat(1), prec), true
+	}
+	return nil, false
+}
+
+// IsConstant reports whether name refers to a built-in constant.
+func IsConstant(name string) bool {
+	_, ok := constants[name]
+	return ok
+}
@@ -0,0 +1,291 @@
+// Package calculus implements symbolic calculus over parsed expressions.
+package calculus
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ArtroxGabriel/sigma-parser/ast"
+	"github.com/ArtroxGabriel/sigma-parser/builtin"
+	"github.com/ArtroxGabriel/sigma-parser/simplify"
+)
+
+// Derive returns the derivative of e with respect to variable, simplified.
+// Identifiers other than variable are treated as constants.
+func Derive(e ast.Expression, variable string) (ast.Expression, error) {
+	d, err := derive(e, variable)
+	if err != nil {
+		return nil, err
+	}
+	return simplify.Simplify(d), nil
+}
+
+// DeriveContext is like Derive, but gives up with the context's error once
+// ctx is done. The derivative before simplification shares subtrees, and
+// can take far longer to simplify than e took to differentiate.
+func DeriveContext(ctx context.Context, e ast.Expression, variable string) (ast.Expression, error) {
+	d, err := derive(e, variable)
+	if err != nil {
+		return nil, err
+	}
+	return simplify.SimplifyContext(ctx, d)
+}
+
+// DependsOn reports whether e references variable.
+func DependsOn(e ast.Expression, variable string) bool {
+	switch e := e.(type) {
+	case *ast.Identifier:
+		return e.Value == variable
+	case *ast.PrefixExpression:
+		return DependsOn(e.Right, variable)
+	case *ast.PostfixExpression:
+		return DependsOn(e.Left, variable)
+	case *ast.InfixExpression:
+		return DependsOn(e.Left, variable) || DependsOn(e.Right, variable)
+	case *ast.ConditionalExpression:
+		return DependsOn(e.Condition, variable) ||
+			DependsOn(e.Consequence, variable) || DependsOn(e.Alternative, variable)
+	case *ast.FunctionCall:
+		if integrand, x, lo, hi, ok := e.Integral(); ok {
+			return (x.Value != variable && DependsOn(integrand, variable)) ||
+				DependsOn(lo, variable) || DependsOn(hi, variable)
+		}
+		for _, arg := range e.Arguments {
+			if DependsOn(arg, variable) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Substitute returns e with every free occurrence of variable replaced by
+// value. The parts of e that do not change are shared, not copied.
+func Substitute(e ast.Expression, variable string, value ast.Expression) ast.Expression {
+	switch e := e.(type) {
+	case *ast.Identifier:
+		if e.Value == variable {
+			return value
+		}
+	case *ast.PrefixExpression:
+		return ast.NewPrefix(e.Operator, Substitute(e.Right, variable, value))
+	case *ast.PostfixExpression:
+		return ast.NewPostfix(Substitute(e.Left, variable, value), e.Operator)
+	case *ast.InfixExpression:
+		return ast.NewInfix(Substitute(e.Left, variable, value), e.Operator,
+			Substitute(e.Right, variable, value))
+	case *ast.ConditionalExpression:
+		var alternative ast.Expression
+		if e.Alternative != nil {
+			alternative = Substitute(e.Alternative, variable, value)
+		}
+		return ast.NewConditional(Substitute(e.Condition, variable, value),
+			Substitute(e.Consequence, variable, value), alternative)
+	case *ast.FunctionCall:
+		// an integral over the variable binds it in the integrand
+		_, v, _, _, ok := e.Integral()
+		bound := ok && v.Value == variable
+
+		args := make([]ast.Expression, len(e.Arguments))
+		for i, arg := range e.Arguments {
+			if bound && i < 2 {
+				args[i] = arg
+				continue
+			}
+			args[i] = Substitute(arg, variable, value)
+		}
+		return &ast.FunctionCall{Token: e.Token, Function: e.Function, Arguments: args}
+	}
+	return e
+}
+
+func derive(e ast.Expression, x string) (ast.Expression, error) {
+	if !DependsOn(e, x) {
+		return num(0), nil
+	}
+
+	switch e := e.(type) {
+	case *ast.Identifier:
+		return num(1), nil
+
+	case *ast.PrefixExpression:
+		du, err := derive(e.Right, x)
+		if err != nil {
+			return nil, err
+		}
+		switch e.Operator {
+		case "-":
+			return ast.NewPrefix("-", du), nil
+		case "!":
+			return nil, fmt.Errorf("cannot differentiate %s: it is not a number", e)
+		}
+		return du, nil
+
+	case *ast.PostfixExpression:
+		if e.Operator != "%" {
+			return nil, fmt.Errorf("cannot differentiate %s: no derivative rule for %s", e, e.Operator)
+		}
+		du, err := derive(e.Left, x)
+		if err != nil {
+			return nil, err
+		}
+		return infix(du, "/", num(100)), nil
+
+	case *ast.InfixExpression:
+		return deriveInfix(e, x)
+
+	case *ast.ConditionalExpression:
+		return deriveConditional(e, x)
+
+	case *ast.FunctionCall:
+		return deriveCall(e, x)
+	}
+
+	return nil, fmt.Errorf("cannot differentiate %s", e)
+}
+
+func deriveInfix(e *ast.InfixExpression, x string) (ast.Expression, error) {
+	u, v := e.Left, e.Right
+
+	du, err := derive(u, x)
+	if err != nil {
+		return nil, err
+	}
+	dv, err := derive(v, x)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.Operator {
+	case "+", "-":
+		return infix(du, e.Operator, dv), nil
+
+	case "*":
+		// (uv)' = u'v + uv'
+		return infix(infix(du, "*", v), "+", infix(u, "*", dv)), nil
+
+	case "/":
+		// (u/v)' = (u'v - uv') / v^2
+		return infix(
+			infix(infix(du, "*", v), "-", infix(u, "*", dv)),
+			"/",
+			infix(v, "^", num(2)),
+		), nil
+
+	case "^":
+		switch {
+		case !DependsOn(v, x):
+			// (u^n)' = n u^(n-1) u'
+			return infix(infix(v, "*", infix(u, "^", infix(v, "-", num(1)))), "*", du), nil
+		case !DependsOn(u, x):
+			// (a^v)' = a^v ln(a) v'
+			return infix(infix(e, "*", call("ln", u)), "*", dv), nil
+		default:
+			// (u^v)' = u^v (v' ln(u) + v u' / u)
+			return infix(e, "*", infix(
+				infix(dv, "*", call("ln", u)),
+				"+",
+				infix(infix(v, "*", du), "/", u),
+			)), nil
+		}
+	}
+
+	return nil, fmt.Errorf("cannot differentiate operator %s", e.Operator)
+}
+
+// deriveConditional differentiates each branch separately. The condition
+// only picks a branch, so the result holds everywhere except where the
+// condition switches.
+func deriveConditional(e *ast.ConditionalExpression, x string) (ast.Expression, error) {
+	da, err := derive(e.Consequence, x)
+	if err != nil {
+		return nil, err
+	}
+
+	var db ast.Expression
+	if e.Alternative != nil {
+		if db, err = derive(e.Alternative, x); err != nil {
+			return nil, err
+		}
+	}
+	return ast.NewConditional(e.Condition, da, db), nil
+}
+
+// derivatives maps each built-in function to its derivative with respect to
+// its argument u.
+var derivatives = map[string]func(u ast.Expression) ast.Expression{
+	"sin": func(u ast.Expression) ast.Expression { return call("cos", u) },
+	"cos": func(u ast.Expression) ast.Expression { return ast.NewPrefix("-", call("sin", u)) },
+	// 1 + tan(u)^2 rather than 1 / cos(u)^2, so that higher derivatives
+	// stay polynomials in tan(u)
+	"tan": func(u ast.Expression) ast.Expression {
+		return infix(num(1), "+", infix(call("tan", u), "^", num(2)))
+	},
+	"asin": func(u ast.Expression) ast.Expression {
+		return infix(num(1), "/", call("sqrt", infix(num(1), "-", infix(u, "^", num(2)))))
+	},
+	"acos": func(u ast.Expression) ast.Expression {
+		return ast.NewPrefix("-", infix(num(1), "/", call("sqrt", infix(num(1), "-", infix(u, "^", num(2))))))
+	},
+	"atan": func(u ast.Expression) ast.Expression {
+		return infix(num(1), "/", infix(num(1), "+", infix(u, "^", num(2))))
+	},
+	"sinh": func(u ast.Expression) ast.Expression { return call("cosh", u) },
+	"cosh": func(u ast.Expression) ast.Expression { return call("sinh", u) },
+	"tanh": func(u ast.Expression) ast.Expression {
+		return infix(num(1), "-", infix(call("tanh", u), "^", num(2)))
+	},
+	"sqrt": func(u ast.Expression) ast.Expression {
+		return infix(num(1), "/", infix(num(2), "*", call("sqrt", u)))
+	},
+	"exp": func(u ast.Expression) ast.Expression { return call("exp", u) },
+	"ln":  func(u ast.Expression) ast.Expression { return infix(num(1), "/", u) },
+	"log": func(u ast.Expression) ast.Expression {
+		return infix(num(1), "/", infix(u, "*", call("ln", num(10))))
+	},
+	"log2": func(u ast.Expression) ast.Expression {
+		return infix(num(1), "/", infix(u, "*", call("ln", num(2))))
+	},
+	"abs": func(u ast.Expression) ast.Expression { return infix(u, "/", call("abs", u)) },
+	// re, im and conj act on real inputs as x, 0 and x
+	"re":   func(ast.Expression) ast.Expression { return num(1) },
+	"im":   func(ast.Expression) ast.Expression { return num(0) },
+	"conj": func(ast.Expression) ast.Expression { return num(1) },
+}
+
+func deriveCall(e *ast.FunctionCall, x string) (ast.Expression, error) {
+	ident, ok := e.Function.(*ast.Identifier)
+	if !ok {
+		return nil, fmt.Errorf("cannot differentiate call to %s", e.Function)
+	}
+
+	rule, ok := derivatives[ident.Value]
+	if !ok {
+		if _, known := builtin.Lookup(ident.Value); known || ident.Value == "integrate" {
+			return nil, fmt.Errorf("no derivative rule for %s", ident.Value)
+		}
+		return nil, fmt.Errorf("unknown function: %s", ident.Value)
+	}
+	if len(e.Arguments) != 1 {
+		return nil, fmt.Errorf("%s expects 1 argument, got %d", ident.Value, len(e.Arguments))
+	}
+
+	u := e.Arguments[0]
+	du, err := derive(u, x)
+	if err != nil {
+		return nil, err
+	}
+
+	// chain rule: f(u)' = f'(u) u'
+	return infix(rule(u), "*", du), nil
+}
+
+func num(v float64) ast.Expression { return ast.NewNumber(v) }
+
+func infix(l ast.Expression, op string, r ast.Expression) ast.Expression {
+	return ast.NewInfix(l, op, r)
+}
+
+func call(name string, args ...ast.Expression) ast.Expression {
+	return ast.NewCall(name, args...)
+}
@@ -0,0 +1,126 @@
+package calculus_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ArtroxGabriel/sigma-parser/ast"
+	"github.com/ArtroxGabriel/sigma-parser/calculus"
+	"github.com/ArtroxGabriel/sigma-parser/eval"
+	"github.com/ArtroxGabriel/sigma-parser/lexer"
+	"github.com/ArtroxGabriel/sigma-parser/object"
+	"github.com/ArtroxGabriel/sigma-parser/parser"
+	"github.com/ArtroxGabriel/sigma-parser/printer"
+)
+
+func parse(t *testing.T, input string) ast.Expression {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	function := p.ParseFunction()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return function.Expression
+}
+
+func TestDerive(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"5", "0"},
+		{"y", "0"},
+		{"x", "1"},
+		{"3 * x + 2", "3"},
+		{"x ^ 3", "3 * x^2"},
+		{"-x ^ 2", "2 * x"},
+		{"a * x ^ 2 + b * x + c", "a * (2 * x) + b"},
+		{"sin(x)", "cos(x)"},
+		{"cos(2 * x)", "-(2 * sin(2 * x))"},
+		{"exp(x ^ 2)", "exp(x^2) * (2 * x)"},
+		{"ln(x)", "1 / x"},
+		{"2 ^ x", "2^x * ln(2)"},
+		{"x / y", "y / y^2"},
+		{"sqrt(x)", "1 / (2 * sqrt(x))"},
+		{"x%", "1 / 100"},
+		{"y!", "0"},
+		{"x < 0 ? -x : x ^ 2", "x < 0 ? -1 : 2 * x"},
+		{"piecewise(x < 1, 3 * x, x < 2, 5)", "piecewise(x < 1, 3, x < 2, 0)"},
+		{"x > 0 ? 1 : 2", "0"},
+		{"integrate(x * t, x, 0, 1)", "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			d, err := calculus.Derive(parse(t, tt.input), "x")
+			if err != nil {
+				t.Fatalf("Derive() error = %v", err)
+			}
+			if got := printer.Format(d); got != tt.want {
+				t.Errorf("Derive() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDeriveNumerically compares symbolic derivatives against central
+// differences for expressions whose printed form is not worth pinning down.
+func TestDeriveNumerically(t *testing.T) {
+	inputs := []string{
+		"x ^ x",
+		"tan(x) * atan(x)",
+		"asin(x / 2) + acos(x / 3)",
+		"sinh(x) / cosh(x) - tanh(x)",
+		"log(x) + log2(x ^ 2)",
+		"abs(x - 3) * sqrt(x)",
+		"(x + 1) / (x - 1)",
+	}
+
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			e := parse(t, input)
+			d, err := calculus.Derive(e, "x")
+			if err != nil {
+				t.Fatalf("Derive() error = %v", err)
+			}
+
+			for _, x := range []float64{0.3, 0.7, 1.4} {
+				got := evalAt(t, d, x)
+
+				const h = 1e-6
+				want := (evalAt(t, e, x+h) - evalAt(t, e, x-h)) / (2 * h)
+				if math.Abs(got-want) > 1e-5*math.Max(1, math.Abs(want)) {
+					t.Errorf("at x=%v: d/dx = %v, numeric = %v (%s)", x, got, want, printer.Format(d))
+				}
+			}
+		})
+	}
+}
+
+func TestDeriveErrors(t *testing.T) {
+	if _, err := calculus.Derive(parse(t, "foo(x)"), "x"); err == nil {
+		t.Errorf("Derive(foo(x)) error = nil, want unknown function")
+	}
+	if _, err := calculus.Derive(parse(t, "x!"), "x"); err == nil {
+		t.Errorf("Derive(x!) error = nil, want no derivative rule")
+	}
+	if _, err := calculus.Derive(parse(t, "integrate(x * t, t, 0, 1)"), "x"); err == nil {
+		t.Errorf("Derive(integrate(x * t, t, 0, 1)) error = nil, want no derivative rule")
+	}
+	if _, err := calculus.Derive(parse(t, "x < 1"), "x"); err == nil {
+		t.Errorf("Derive(x < 1) error = nil, want an error for a boolean expression")
+	}
+}
+
+func evalAt(t *testing.T, e ast.Expression, x float64) float64 {
+	t.Helper()
+
+	env := object.NewEnvironment()
+	env.Set("x", &object.Number{Value: x})
+	v, err := eval.Float(e, env)
+	if err != nil {
+		t.Fatalf("Eval(%s) error = %v", e, err)
+	}
+	return v
+}
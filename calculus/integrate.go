@@ -0,0 +1,585 @@
+package calculus
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/ArtroxGabriel/sigma-parser/ast"
+	"github.com/ArtroxGabriel/sigma-parser/builtin"
+	"github.com/ArtroxGabriel/sigma-parser/eval"
+	"github.com/ArtroxGabriel/sigma-parser/simplify"
+)
+
+// ErrNoClosedForm is returned by Integrate for an expression that is not
+// one of the forms it knows how to integrate. The integral may still exist.
+var ErrNoClosedForm = errors.New("no closed form found")
+
+// Integrate returns an antiderivative of e with respect to variable,
+// simplified, without a constant of integration. It knows sums and
+// constant multiples of powers, exponentials, logarithms, trigonometric and
+// hyperbolic functions of a linear argument, and quotients of polynomials
+// with numeric coefficients whose denominator is at most quadratic.
+func Integrate(e ast.Expression, variable string) (ast.Expression, error) {
+	F, err := integrate(e, variable)
+	if err != nil {
+		return nil, err
+	}
+	return simplify.Simplify(F), nil
+}
+
+func noClosedForm(e ast.Expression) error {
+	return fmt.Errorf("%w for %s", ErrNoClosedForm, e)
+}
+
+func integrate(e ast.Expression, x string) (ast.Expression, error) {
+	if !DependsOn(e, x) {
+		return infix(e, "*", ast.NewIdentifier(x)), nil
+	}
+
+	switch e := e.(type) {
+	case *ast.Identifier:
+		return infix(infix(e, "^", num(2)), "/", num(2)), nil
+
+	case *ast.PrefixExpression:
+		if e.Operator != "-" {
+			break
+		}
+		F, err := integrate(e.Right, x)
+		if err != nil {
+			return nil, err
+		}
+		return ast.NewPrefix("-", F), nil
+
+	case *ast.PostfixExpression:
+		if e.Operator != "%" {
+			break
+		}
+		F, err := integrate(e.Left, x)
+		if err != nil {
+			return nil, err
+		}
+		return infix(F, "/", num(100)), nil
+
+	case *ast.InfixExpression:
+		if F, ok, err := integrateInfix(e, x); ok || err != nil {
+			return F, err
+		}
+
+	case *ast.FunctionCall:
+		if F, ok := integrateCall(e, x); ok {
+			return F, nil
+		}
+	}
+
+	if F, ok := integrateRational(e, x); ok {
+		return F, nil
+	}
+	return nil, noClosedForm(e)
+}
+
+// integrateInfix applies linearity, and the rules for powers and
+// reciprocals of a linear argument. ok is false when none applies.
+func integrateInfix(e *ast.InfixExpression, x string) (F ast.Expression, ok bool, err error) {
+	u, v := e.Left, e.Right
+
+	// a numeric multiple of a power of x, whose coefficient integratePower
+	// folds: 3 x^2 integrates to x^3 rather than 3 (x^3 / 3)
+	if e.Operator == "*" || e.Operator == "/" {
+		if c, k, ok := monomial(e, x); ok {
+			if _, err := eval.Float(c, nil); err == nil {
+				return integratePower(c, ast.NewIdentifier(x), num(k), num(1)), true, nil
+			}
+		}
+	}
+
+	switch e.Operator {
+	case "+", "-":
+		Fu, err := integrate(u, x)
+		if err != nil {
+			return nil, true, err
+		}
+		Fv, err := integrate(v, x)
+		if err != nil {
+			return nil, true, err
+		}
+		return infix(Fu, e.Operator, Fv), true, nil
+
+	case "*":
+		// c f = c ∫f
+		if !DependsOn(u, x) {
+			Fv, err := integrate(v, x)
+			return infix(u, "*", Fv), true, err
+		}
+		if !DependsOn(v, x) {
+			Fu, err := integrate(u, x)
+			return infix(Fu, "*", v), true, err
+		}
+		if c, k, ok := monomial(e, x); ok {
+			return integratePower(c, ast.NewIdentifier(x), num(k), num(1)), true, nil
+		}
+
+	case "/":
+		if !DependsOn(v, x) {
+			Fu, err := integrate(u, x)
+			return infix(Fu, "/", v), true, err
+		}
+		if !DependsOn(u, x) {
+			// c / w^n = c w^-n, for a linear w
+			w, n := power(v, x)
+			if a, ok := linear(w, x); ok {
+				return integratePower(u, w, ast.NewPrefix("-", n), a), true, nil
+			}
+		}
+		if c, k, ok := monomial(e, x); ok {
+			return integratePower(c, ast.NewIdentifier(x), num(k), num(1)), true, nil
+		}
+
+	case "^":
+		switch {
+		case !DependsOn(v, x):
+			// u^n for a linear u
+			if a, ok := linear(u, x); ok {
+				return integratePower(num(1), u, v, a), true, nil
+			}
+		case !DependsOn(u, x):
+			// b^w = b^w / (ln(b) w') for a linear w
+			if a, ok := linear(v, x); ok {
+				return infix(e, "/", infix(call("ln", u), "*", a)), true, nil
+			}
+		}
+	}
+	return nil, false, nil
+}
+
+// power writes e as w^n for an exponent n that does not depend on x,
+// reading sqrt(w) as w^0.5.
+func power(e ast.Expression, x string) (w, n ast.Expression) {
+	switch e := e.(type) {
+	case *ast.InfixExpression:
+		if e.Operator == "^" && !DependsOn(e.Right, x) {
+			return e.Left, e.Right
+		}
+	case *ast.FunctionCall:
+		if ident, ok := e.Function.(*ast.Identifier); ok && ident.Value == "sqrt" && len(e.Arguments) == 1 {
+			return e.Arguments[0], num(0.5)
+		}
+	}
+	return e, num(1)
+}
+
+// integratePower returns ∫ c u^n dx for u with constant derivative a: the
+// logarithm where n is -1 and the power rule elsewhere.
+func integratePower(c, u, n, a ast.Expression) ast.Expression {
+	k, numeric := constant(n)
+	if numeric && k == -1 {
+		return infix(infix(c, "*", call("ln", call("abs", u))), "/", a)
+	}
+
+	m := infix(n, "+", num(1))
+	if numeric {
+		m = num(k + 1)
+	}
+	F := infix(u, "^", m)
+
+	d := infix(m, "*", a)
+	dv, ok := constant(d)
+	if !ok {
+		return infix(infix(c, "*", F), "/", d)
+	}
+
+	// fold numeric factors into a single coefficient: 3 x^2 integrates to
+	// x^3, not 3 (x^3 / 3), and x^2 / 3 to x^3 / 9
+	p, q, rest := split(c)
+	q *= dv
+	if p == math.Trunc(p) && q == math.Trunc(q) && p != 0 {
+		g := gcd(p, q)
+		p, q = p/g, q/g
+	}
+	if rest != nil {
+		F = infix(rest, "*", F)
+	}
+	switch {
+	case p/q == math.Trunc(p/q):
+		return infix(num(p/q), "*", F)
+	case q/p == math.Trunc(q/p):
+		return infix(F, "/", num(q/p))
+	}
+	return infix(infix(num(p), "*", F), "/", num(q))
+}
+
+// split writes c as p / q times rest, for numbers p and q and a rest that
+// is nil when c is a number.
+func split(c ast.Expression) (p, q float64, rest ast.Expression) {
+	if v, ok := constant(c); ok {
+		return v, 1, nil
+	}
+	if e, ok := c.(*ast.InfixExpression); ok {
+		switch e.Operator {
+		case "*":
+			lp, lq, lrest := split(e.Left)
+			rp, rq, rrest := split(e.Right)
+			switch {
+			case lrest == nil:
+				rest = rrest
+			case rrest == nil:
+				rest = lrest
+			default:
+				rest = infix(lrest, "*", rrest)
+			}
+			return lp * rp, lq * rq, rest
+		case "/":
+			if v, ok := constant(e.Right); ok && v != 0 {
+				p, q, rest := split(e.Left)
+				return p, q * v, rest
+			}
+		}
+	}
+	return 1, 1, c
+}
+
+// gcd returns the greatest common divisor of the integers a and b.
+func gcd(a, b float64) float64 {
+	for b != 0 {
+		a, b = b, math.Mod(a, b)
+	}
+	return math.Abs(a)
+}
+
+// antiderivatives maps built-in functions to an antiderivative in terms of
+// their argument u.
+var antiderivatives = map[string]func(u ast.Expression) ast.Expression{
+	"sin": func(u ast.Expression) ast.Expression { return ast.NewPrefix("-", call("cos", u)) },
+	"cos": func(u ast.Expression) ast.Expression { return call("sin", u) },
+	"tan": func(u ast.Expression) ast.Expression {
+		return ast.NewPrefix("-", call("ln", call("abs", call("cos", u))))
+	},
+	"sinh": func(u ast.Expression) ast.Expression { return call("cosh", u) },
+	"cosh": func(u ast.Expression) ast.Expression { return call("sinh", u) },
+	"tanh": func(u ast.Expression) ast.Expression { return call("ln", call("cosh", u)) },
+	"exp":  func(u ast.Expression) ast.Expression { return call("exp", u) },
+	"sqrt": func(u ast.Expression) ast.Expression {
+		return infix(infix(num(2), "*", infix(u, "^", num(1.5))), "/", num(3))
+	},
+	"ln": func(u ast.Expression) ast.Expression { return xLnX(u) },
+	"log": func(u ast.Expression) ast.Expression {
+		return infix(xLnX(u), "/", call("ln", num(10)))
+	},
+	"log2": func(u ast.Expression) ast.Expression {
+		return infix(xLnX(u), "/", call("ln", num(2)))
+	},
+	"abs": func(u ast.Expression) ast.Expression {
+		return infix(infix(u, "*", call("abs", u)), "/", num(2))
+	},
+	"asin": func(u ast.Expression) ast.Expression {
+		return infix(infix(u, "*", call("asin", u)), "+", call("sqrt", oneMinusSquare(u)))
+	},
+	"acos": func(u ast.Expression) ast.Expression {
+		return infix(infix(u, "*", call("acos", u)), "-", call("sqrt", oneMinusSquare(u)))
+	},
+	"atan": func(u ast.Expression) ast.Expression {
+		return infix(
+			infix(u, "*", call("atan", u)),
+			"-",
+			infix(call("ln", infix(num(1), "+", infix(u, "^", num(2)))), "/", num(2)),
+		)
+	},
+	// re, im and conj act on real inputs as u, 0 and u
+	"re":   func(u ast.Expression) ast.Expression { return infix(infix(u, "^", num(2)), "/", num(2)) },
+	"conj": func(u ast.Expression) ast.Expression { return infix(infix(u, "^", num(2)), "/", num(2)) },
+	"im":   func(ast.Expression) ast.Expression { return num(0) },
+}
+
+// xLnX returns u ln(u) - u, the antiderivative of ln(u).
+func xLnX(u ast.Expression) ast.Expression {
+	return infix(infix(u, "*", call("ln", u)), "-", u)
+}
+
+func oneMinusSquare(u ast.Expression) ast.Expression {
+	return infix(num(1), "-", infix(u, "^", num(2)))
+}
+
+// integrateCall integrates f(u) for a known f and a linear u by
+// substitution: F(u) / u'.
+func integrateCall(e *ast.FunctionCall, x string) (ast.Expression, bool) {
+	ident, ok := e.Function.(*ast.Identifier)
+	if !ok || len(e.Arguments) != 1 {
+		return nil, false
+	}
+	rule, ok := antiderivatives[ident.Value]
+	if !ok {
+		return nil, false
+	}
+	u := e.Arguments[0]
+	a, ok := linear(u, x)
+	if !ok {
+		return nil, false
+	}
+	return infix(rule(u), "/", a), true
+}
+
+// linear returns the derivative of u if it is a nonzero constant, as it is
+// for u = a x + b.
+func linear(u ast.Expression, x string) (ast.Expression, bool) {
+	du, err := Derive(u, x)
+	if err != nil || DependsOn(du, x) {
+		return nil, false
+	}
+	if v, ok := constant(du); ok && v == 0 {
+		return nil, false
+	}
+	return du, true
+}
+
+// monomial writes a product or quotient of constants and powers of x with
+// numeric exponents as c x^k.
+func monomial(e ast.Expression, x string) (c ast.Expression, k float64, ok bool) {
+	if !DependsOn(e, x) {
+		return e, 0, true
+	}
+
+	switch e := e.(type) {
+	case *ast.Identifier:
+		return num(1), 1, true
+	case *ast.InfixExpression:
+		switch e.Operator {
+		case "*", "/":
+			lc, lk, lok := monomial(e.Left, x)
+			rc, rk, rok := monomial(e.Right, x)
+			if !lok || !rok {
+				break
+			}
+			if e.Operator == "/" {
+				return infix(lc, "/", rc), lk - rk, true
+			}
+			return infix(lc, "*", rc), lk + rk, true
+		case "^":
+			n, isNum := constant(e.Right)
+			if ident, isVar := e.Left.(*ast.Identifier); isNum && isVar && ident.Value == x {
+				return num(1), n, true
+			}
+		}
+	}
+	return nil, 0, false
+}
+
+// constant returns the value of e if it is a number, possibly negated.
+func constant(e ast.Expression) (float64, bool) {
+	switch e := simplify.Simplify(e).(type) {
+	case *ast.NumberLiteral:
+		if !e.IsImaginary() {
+			return e.Value, true
+		}
+	case *ast.PrefixExpression:
+		if v, ok := constant(e.Right); ok && e.Operator == "-" {
+			return -v, true
+		}
+	}
+	return 0, false
+}
+
+// maxPolyDegree bounds the powers expanded when reading a polynomial.
+const maxPolyDegree = 64
+
+// coefficients reads e as a polynomial in x with numeric coefficients,
+// lowest power first.
+func coefficients(e ast.Expression, x string) ([]float64, bool) {
+	switch e := e.(type) {
+	case *ast.NumberLiteral:
+		if e.IsImaginary() {
+			return nil, false
+		}
+		return []float64{e.Value}, true
+	case *ast.Constant:
+		return []float64{e.Value}, true
+	case *ast.Identifier:
+		if e.Value == x {
+			return []float64{0, 1}, true
+		}
+		v, ok := builtin.LookupConstant(e.Value)
+		return []float64{v}, ok
+	case *ast.PrefixExpression:
+		p, ok := coefficients(e.Right, x)
+		if !ok || e.Operator != "-" {
+			return p, ok && e.Operator == "+"
+		}
+		return scalePoly(p, -1), true
+	case *ast.InfixExpression:
+		p, ok := coefficients(e.Left, x)
+		if !ok {
+			return nil, false
+		}
+		if e.Operator == "^" {
+			n, ok := constant(e.Right)
+			if !ok || n < 0 || n != math.Trunc(n) || n*float64(len(p)-1) > maxPolyDegree {
+				return nil, false
+			}
+			result := []float64{1}
+			for range int(n) {
+				result = mulPoly(result, p)
+			}
+			return result, true
+		}
+
+		q, ok := coefficients(e.Right, x)
+		if !ok {
+			return nil, false
+		}
+		switch e.Operator {
+		case "+":
+			return addPoly(p, q), true
+		case "-":
+			return addPoly(p, scalePoly(q, -1)), true
+		case "*":
+			return mulPoly(p, q), true
+		case "/":
+			if len(trim(q)) == 1 && q[0] != 0 {
+				return scalePoly(p, 1/q[0]), true
+			}
+		}
+	}
+	return nil, false
+}
+
+func trim(p []float64) []float64 {
+	for len(p) > 1 && p[len(p)-1] == 0 {
+		p = p[:len(p)-1]
+	}
+	return p
+}
+
+func scalePoly(p []float64, c float64) []float64 {
+	out := make([]float64, len(p))
+	for i, v := range p {
+		out[i] = c * v
+	}
+	return out
+}
+
+func addPoly(p, q []float64) []float64 {
+	out := make([]float64, max(len(p), len(q)))
+	copy(out, p)
+	for i, v := range q {
+		out[i] += v
+	}
+	return trim(out)
+}
+
+func mulPoly(p, q []float64) []float64 {
+	out := make([]float64, len(p)+len(q)-1)
+	for i, a := range p {
+		for j, b := range q {
+			out[i+j] += a * b
+		}
+	}
+	return trim(out)
+}
+
+// divPoly divides p by q, returning the quotient and remainder.
+func divPoly(p, q []float64) (quo, rem []float64) {
+	q = trim(q)
+	rem = append([]float64(nil), trim(p)...)
+	if len(rem) < len(q) {
+		return []float64{0}, rem
+	}
+	quo = make([]float64, len(rem)-len(q)+1)
+	lead := q[len(q)-1]
+	for i := len(quo) - 1; i >= 0; i-- {
+		c := rem[i+len(q)-1] / lead
+		quo[i] = c
+		for j, b := range q {
+			rem[i+j] -= c * b
+		}
+	}
+	return quo, trim(rem[:len(q)-1])
+}
+
+// polynomial builds the expression of p in x, highest power first.
+func polynomial(p []float64, x string) ast.Expression {
+	var e ast.Expression = num(0)
+	for k := len(p) - 1; k >= 0; k-- {
+		c, op := p[k], "+"
+		if c == 0 {
+			continue
+		}
+		if c < 0 {
+			c, op = -c, "-"
+		}
+		e = infix(e, op, infix(num(c), "*", infix(ast.NewIdentifier(x), "^", num(float64(k)))))
+	}
+	return e
+}
+
+// integrateRational integrates p/q for polynomials with numeric
+// coefficients whose denominator has degree one or two: the quotient of
+// their division by the power rule, and the remainder over q by logarithms
+// and arctangents.
+func integrateRational(e ast.Expression, x string) (ast.Expression, bool) {
+	p, q := e, ast.Expression(num(1))
+	if ie, ok := e.(*ast.InfixExpression); ok && ie.Operator == "/" {
+		p, q = ie.Left, ie.Right
+	}
+	top, ok := coefficients(p, x)
+	if !ok {
+		return nil, false
+	}
+	den, ok := coefficients(q, x)
+	if !ok {
+		return nil, false
+	}
+	den = trim(den)
+	if len(den) > 3 || (len(den) == 1 && den[0] == 0) {
+		return nil, false
+	}
+
+	quo, rem := divPoly(top, den)
+	integral := make([]float64, len(quo)+1)
+	for k, c := range quo {
+		integral[k+1] = c / float64(k+1)
+	}
+	F := polynomial(integral, x)
+
+	if len(den) == 1 || (len(rem) == 1 && rem[0] == 0) {
+		return F, true
+	}
+	return infix(F, "+", integrateProper(rem, den, x)), true
+}
+
+// integrateProper integrates r/q for q of degree one or two and r of lower
+// degree.
+func integrateProper(r, q []float64, x string) ast.Expression {
+	r = append(r, 0)
+	if len(q) == 2 {
+		// r0 / (q1 x + q0) = (r0 / q1) ln|q1 x + q0|
+		return infix(num(r[0]/q[1]), "*", call("ln", call("abs", polynomial(q, x))))
+	}
+
+	// write r1 x + r0 as r1/(2a) (2a x + b) + k, with q = a x^2 + b x + c:
+	// the first part integrates to a logarithm of q
+	a, b, c := q[2], q[1], q[0]
+	var F ast.Expression = num(0)
+	if r[1] != 0 {
+		F = infix(num(r[1]/(2*a)), "*", call("ln", call("abs", polynomial(q, x))))
+	}
+	k := r[0] - r[1]*b/(2*a)
+	if k == 0 {
+		return F
+	}
+
+	// k / q by the sign of the discriminant
+	twoAXB := polynomial([]float64{b, 2 * a}, x)
+	disc := b*b - 4*a*c
+	var G ast.Expression
+	switch {
+	case disc < 0:
+		s := math.Sqrt(-disc)
+		G = infix(num(2*k/s), "*", call("atan", polynomial([]float64{b / s, 2 * a / s}, x)))
+	case disc > 0:
+		s := math.Sqrt(disc)
+		G = infix(num(k/s), "*", call("ln", call("abs",
+			infix(infix(twoAXB, "-", num(s)), "/", infix(twoAXB, "+", num(s))))))
+	default:
+		G = infix(num(-2*k), "/", twoAXB)
+	}
+	return infix(F, "+", G)
+}
@@ -0,0 +1,111 @@
+package calculus_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/ArtroxGabriel/sigma-parser/calculus"
+	"github.com/ArtroxGabriel/sigma-parser/printer"
+)
+
+func TestIntegrate(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"5", "5 * x"},
+		{"x", "x^2 / 2"},
+		{"x ^ 3", "x^4 / 4"},
+		{"3 * x ^ 2 + 2 * x", "x^3 + x^2"},
+		{"x ^ 2 / 3", "x^3 / 9"},
+		{"6 * x / 4", "3 * x^2 / 4"},
+		{"2 * pi * x", "pi * x^2"},
+		{"1 / x", "ln(abs(x))"},
+		{"exp(x)", "exp(x)"},
+		{"sin(x)", "-cos(x)"},
+		{"cos(2 * x)", "sin(2 * x) / 2"},
+		{"y", "y * x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			F, err := calculus.Integrate(parse(t, tt.input), "x")
+			if err != nil {
+				t.Fatalf("Integrate() error = %v", err)
+			}
+			if got := printer.Format(F); got != tt.want {
+				t.Errorf("Integrate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestIntegrateDerivesBack checks every antiderivative by differentiating
+// it and comparing with the integrand at sample points.
+func TestIntegrateDerivesBack(t *testing.T) {
+	tests := []string{
+		"x ^ 5 - 4 * x ^ 2 + 7",
+		"(2 * x + 1) ^ 3",
+		"sqrt(x)",
+		"1 / sqrt(3 * x + 1)",
+		"x ^ -2",
+		"x * x * x / 4",
+		"2 / (5 - x)",
+		"4 / (2 * x + 1) ^ 2",
+		"exp(3 * x - 1) + 2 ^ x",
+		"ln(x) + log(2 * x) + log2(x)",
+		"sin(3 * x) - cos(x / 2) + tan(x)",
+		"sinh(x) + cosh(2 * x) + tanh(x)",
+		"asin(x / 2) + acos(x / 3) + atan(x)",
+		"abs(x - 1)",
+		"-(x + 1)%",
+		"pi * x ^ pi",
+		"(x + 1) * (x - 1) * x",
+		"1 / (x ^ 2 + 1)",
+		"x / (x ^ 2 + 4)",
+		"(x ^ 3 + 2) / (x - 3)",
+		"(3 * x + 5) / (x ^ 2 - 3 * x + 2)",
+		"1 / (x ^ 2 + 2 * x + 1)",
+		"(2 * x ^ 2 - x) / (x ^ 2 + x + 1)",
+	}
+
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			f := parse(t, input)
+			F, err := calculus.Integrate(f, "x")
+			if err != nil {
+				t.Fatalf("Integrate() error = %v", err)
+			}
+			dF, err := calculus.Derive(F, "x")
+			if err != nil {
+				t.Fatalf("Derive(%s) error = %v", printer.Format(F), err)
+			}
+			for _, x := range []float64{0.3, 0.55, 0.9} {
+				want, got := evalAt(t, f, x), evalAt(t, dF, x)
+				if math.Abs(got-want) > 1e-9*math.Max(1, math.Abs(want)) {
+					t.Errorf("d/dx %s at %v = %v, want %v", printer.Format(F), x, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestIntegrateNoClosedForm(t *testing.T) {
+	for _, input := range []string{
+		"exp(x ^ 2)",
+		"x * exp(x)",
+		"sin(x) / x",
+		"1 / (x ^ 3 + 1)",
+		"sin(sin(x))",
+		"x < 1 ? x : 1",
+		"x ^ x",
+	} {
+		t.Run(input, func(t *testing.T) {
+			_, err := calculus.Integrate(parse(t, input), "x")
+			if !errors.Is(err, calculus.ErrNoClosedForm) {
+				t.Errorf("Integrate() error = %v, want ErrNoClosedForm", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,865 @@
+package calculus
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/ArtroxGabriel/sigma-parser/ast"
+	"github.com/ArtroxGabriel/sigma-parser/builtin"
+	"github.com/ArtroxGabriel/sigma-parser/eval"
+	"github.com/ArtroxGabriel/sigma-parser/object"
+	poly "github.com/ArtroxGabriel/sigma-parser/polynomial"
+)
+
+// Direction says from which side a limit approaches its point.
+type Direction int
+
+const (
+	Both  Direction = iota // both one-sided limits, which must agree
+	Left                   // from below, x → a⁻
+	Right                  // from above, x → a⁺
+)
+
+var (
+	// ErrIndeterminate is returned when a limit takes an indeterminate
+	// form, such as 0/0 or ∞ - ∞, that neither series nor L'Hôpital's
+	// rule resolves.
+	ErrIndeterminate = errors.New("limit is indeterminate")
+
+	// ErrNoLimit is returned when a limit does not exist: the one-sided
+	// limits differ, or the expression oscillates or is undefined near the
+	// point.
+	ErrNoLimit = errors.New("limit does not exist")
+)
+
+const (
+	// maxRewrites bounds the rewrites of indeterminate forms in one limit,
+	// each of which may double the size of the expression.
+	maxRewrites = 12
+
+	// limitSeriesOrder is the highest power whose coefficient is looked at
+	// when comparing series.
+	limitSeriesOrder = 16
+
+	// zeroPrec is the precision in bits at which vanishes evaluates an
+	// expression at the point, and again at twice as many bits.
+	zeroPrec = 256
+)
+
+// Limit returns the limit of e as variable approaches point, which may be
+// infinite, as in 1/0. Every other identifier in e must be a constant.
+// Limits at an infinity are taken from its finite side, whatever dir says.
+// The result may be infinite.
+//
+// Continuous operations are applied to the limits of their operands, while
+// abs and conditionals are resolved by the sign of the argument, or the
+// truth of the condition, on the side of approach. Indeterminate forms are
+// rewritten into quotients, which are settled by comparing the leading
+// terms of the Taylor series of numerator and denominator, or at an
+// infinity their leading powers, or by L'Hôpital's rule where neither
+// exists. A bounded factor with no limit of its own times one that tends
+// to 0 tends to 0.
+func Limit(e ast.Expression, variable string, point ast.Expression, dir Direction) (float64, error) {
+	if DependsOn(point, variable) {
+		return 0, fmt.Errorf("limit point %s depends on %s", point, variable)
+	}
+	a, err := eval.Float(point, nil)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(a) {
+		return 0, fmt.Errorf("limit point %s is not a number", point)
+	}
+
+	switch {
+	case math.IsInf(a, 1) || dir == Left:
+		return limitFrom(e, variable, point, a, -1)
+	case math.IsInf(a, -1) || dir == Right:
+		return limitFrom(e, variable, point, a, 1)
+	}
+
+	left, err := limitFrom(e, variable, point, a, -1)
+	if err != nil {
+		return 0, err
+	}
+	right, err := limitFrom(e, variable, point, a, 1)
+	if err != nil {
+		return 0, err
+	}
+	if left == right || (!math.IsInf(left, 0) && !math.IsInf(right, 0) &&
+		math.Abs(left-right) <= 1e-9*math.Max(1, math.Abs(right))) {
+		return (left + right) / 2, nil
+	}
+	return 0, fmt.Errorf("%w: the limit from the left is %v and from the right %v", ErrNoLimit, left, right)
+}
+
+// limitFrom returns the one-sided limit of e at point, whose value is a,
+// from the given side.
+func limitFrom(e ast.Expression, x string, point ast.Expression, a, side float64) (float64, error) {
+	l := &limiter{x: x, point: point, a: a, side: side, rewrites: maxRewrites}
+	if math.IsNaN(l.probe(e)) {
+		return 0, fmt.Errorf("%w: %s is undefined on the %s of %v", ErrNoLimit, brief(e), l.sideName(), a)
+	}
+	return l.limit(l.unabs(e))
+}
+
+// limiter computes one-sided limits at a point.
+type limiter struct {
+	x        string
+	point    ast.Expression // the point, to evaluate at it exactly
+	a        float64
+	side     float64 // -1 from the left, 1 from the right
+	rewrites int     // rewrites of indeterminate forms left
+}
+
+func (l *limiter) sideName() string {
+	if l.side < 0 {
+		return "left"
+	}
+	return "right"
+}
+
+func (l *limiter) limit(e ast.Expression) (float64, error) {
+	if !DependsOn(e, l.x) {
+		return eval.Float(e, nil)
+	}
+
+	switch e := e.(type) {
+	case *ast.Identifier:
+		return l.a, nil
+
+	case *ast.PrefixExpression:
+		if e.Operator == "-" {
+			v, err := l.limit(e.Right)
+			return -v, err
+		}
+
+	case *ast.PostfixExpression:
+		v, err := l.limit(e.Left)
+		if err != nil {
+			return 0, err
+		}
+		switch e.Operator {
+		case "%":
+			return v / 100, nil
+		case "!":
+			return l.factorial(e, v)
+		}
+
+	case *ast.InfixExpression:
+		return l.limitInfix(e)
+
+	case *ast.ConditionalExpression:
+		return l.limitConditional(e)
+
+	case *ast.FunctionCall:
+		if _, x, _, _, ok := e.Integral(); ok && x.Value != l.x {
+			return l.limitIntegral(e)
+		}
+		if v, ok, err := l.limitCall(e); ok {
+			return v, err
+		}
+	}
+
+	return 0, fmt.Errorf("%w: no limit rule for %s", ErrIndeterminate, brief(e))
+}
+
+// operand returns the limit of e, an operand of a larger expression. That
+// e has no limit does not settle whether the larger expression has one.
+func (l *limiter) operand(e ast.Expression) (float64, error) {
+	v, err := l.limit(e)
+	if errors.Is(err, ErrNoLimit) {
+		return 0, fmt.Errorf("%w: %s has no limit at %v", ErrIndeterminate, brief(e), l.a)
+	}
+	return v, err
+}
+
+// limitConditional follows the branch whose condition holds on the side of
+// approach.
+func (l *limiter) limitConditional(e *ast.ConditionalExpression) (float64, error) {
+	for {
+		holds, err := l.holds(e.Condition)
+		if err != nil {
+			return 0, err
+		}
+		if holds {
+			return l.limit(e.Consequence)
+		}
+
+		switch alt := e.Alternative.(type) {
+		case nil:
+			return 0, fmt.Errorf("%w: no condition of %s holds on the %s of %v", ErrNoLimit, brief(e), l.sideName(), l.a)
+		case *ast.ConditionalExpression:
+			e = alt
+		default:
+			return l.limit(alt)
+		}
+	}
+}
+
+// holds reports whether cond is true near the point, on the limiter's side.
+// It must have the same value at every point probe tries.
+func (l *limiter) holds(cond ast.Expression) (bool, error) {
+	ev := eval.New()
+	var holds bool
+	for i, x := range l.probePoints() {
+		env := object.NewEnvironment()
+		env.Set(l.x, &object.Number{Value: x})
+		obj, err := ev.Eval(&ast.Function{Expression: cond}, env)
+		if err != nil {
+			return false, err
+		}
+		b, ok := obj.(*object.Boolean)
+		if !ok {
+			return false, fmt.Errorf("condition %s is not a boolean", cond)
+		}
+		if i > 0 && b.Value != holds {
+			return false, fmt.Errorf("%w: %s changes near %v", ErrIndeterminate, brief(cond), l.a)
+		}
+		holds = b.Value
+	}
+	return holds, nil
+}
+
+// limitIntegral evaluates an integral whose bounds depend on the variable
+// at the limits of its bounds, as integrals are continuous in them.
+func (l *limiter) limitIntegral(e *ast.FunctionCall) (float64, error) {
+	integrand, x, lo, hi, _ := e.Integral()
+	a, err := l.operand(lo)
+	if err != nil {
+		return 0, err
+	}
+	b, err := l.operand(hi)
+	if err != nil {
+		return 0, err
+	}
+	if DependsOn(integrand, l.x) || math.IsInf(a, 0) || math.IsInf(b, 0) {
+		return 0, fmt.Errorf("%w: no limit rule for %s", ErrIndeterminate, brief(e))
+	}
+	return eval.Float(call("integrate", integrand, x, num(a), num(b)), nil)
+}
+
+// limitCall applies a built-in function, assumed continuous, to the limits
+// of its arguments. It reports false for any other call.
+func (l *limiter) limitCall(e *ast.FunctionCall) (float64, bool, error) {
+	name, ok := e.Function.(*ast.Identifier)
+	if !ok {
+		return 0, false, nil
+	}
+	fn, ok := builtin.Lookup(name.Value)
+	if !ok || fn.Arity != len(e.Arguments) {
+		return 0, false, nil
+	}
+
+	args := make([]float64, len(e.Arguments))
+	for i, arg := range e.Arguments {
+		v, err := l.operand(arg)
+		if err != nil {
+			return 0, true, err
+		}
+		args[i] = v
+	}
+	v, err := l.defined(e, fn.Fn(args...))
+	return v, true, err
+}
+
+// factorial returns the limit of e = u!, where u tends to v. At a pole of
+// Γ, where v is a negative integer, it diverges with the sign u! takes near
+// the point, which flips from one side to the other as for 1/x.
+func (l *limiter) factorial(e *ast.PostfixExpression, v float64) (float64, error) {
+	n := math.Round(v)
+	if n >= 0 || !l.vanishes(infix(e.Left, "-", num(n)), v-n) {
+		return l.defined(e, eval.Factorial(v))
+	}
+	s := l.probe(e)
+	if s == 0 || math.IsNaN(s) {
+		return 0, fmt.Errorf("%w: the sign of %s near %v is unknown", ErrIndeterminate, brief(e), l.a)
+	}
+	return math.Copysign(math.Inf(1), s), nil
+}
+
+// defined checks the value of a continuous function at the limit of its
+// operands.
+func (l *limiter) defined(e ast.Expression, v float64) (float64, error) {
+	if math.IsNaN(v) {
+		return 0, fmt.Errorf("%w: %s has no limit at %v", ErrNoLimit, brief(e), l.a)
+	}
+	return v, nil
+}
+
+func (l *limiter) limitInfix(e *ast.InfixExpression) (float64, error) {
+	if e.Operator == "/" {
+		return l.quotient(e.Left, e.Right)
+	}
+
+	u, uerr := l.operand(e.Left)
+	v, verr := l.operand(e.Right)
+	uzero := uerr == nil && l.vanishes(e.Left, u)
+	vzero := verr == nil && l.vanishes(e.Right, v)
+	if e.Operator == "*" {
+		// squeeze: a bounded factor times one tending to 0 tends to 0
+		if (uzero && l.bounded(e.Right, verr)) || (vzero && l.bounded(e.Left, uerr)) {
+			return 0, nil
+		}
+	}
+	if uerr != nil {
+		return 0, uerr
+	}
+	if verr != nil {
+		return 0, verr
+	}
+
+	switch e.Operator {
+	case "+", "-":
+		w := u + v
+		if e.Operator == "-" {
+			w = u - v
+		}
+		if !math.IsNaN(w) {
+			return w, nil
+		}
+		if err := l.rewrite(e); err != nil {
+			return 0, err
+		}
+		if f, g, ok := l.conjugate(e); ok {
+			return l.quotient(f, g)
+		}
+		// ∞ - ∞: f ± g = (1/g ± 1/f) / (1/f 1/g)
+		rf, rg := reciprocal(e.Left), reciprocal(e.Right)
+		return l.quotient(infix(rg, e.Operator, rf), infix(rf, "*", rg))
+
+	case "*":
+		// 0 ∞: f g = g / (1/f), keeping the infinite factor on top
+		switch {
+		case uzero && math.IsInf(v, 0):
+			return l.quotient(e.Right, reciprocal(e.Left))
+		case math.IsInf(u, 0) && vzero:
+			return l.quotient(e.Left, reciprocal(e.Right))
+		}
+		return u * v, nil
+
+	case "^":
+		if (u == 1 && math.IsInf(v, 0)) || (uzero && vzero) || (math.IsInf(u, 0) && vzero) {
+			// 1^∞, 0^0 and ∞^0: f^g = exp(g ln(f))
+			w, err := l.limit(infix(e.Right, "*", call("ln", e.Left)))
+			return math.Exp(w), err
+		}
+		return l.defined(e, math.Pow(u, v))
+	}
+
+	return 0, fmt.Errorf("%w: no limit rule for %s", ErrIndeterminate, brief(e))
+}
+
+// bounded reports whether e, whose limit failed with err, is bounded near
+// the point, because it is made of bounded functions and constants.
+func (l *limiter) bounded(e ast.Expression, err error) bool {
+	if !errors.Is(err, ErrIndeterminate) {
+		return false
+	}
+	var isBounded func(e ast.Expression) bool
+	isBounded = func(e ast.Expression) bool {
+		if !DependsOn(e, l.x) {
+			v, err := eval.Float(e, nil)
+			return err == nil && !math.IsInf(v, 0) && !math.IsNaN(v)
+		}
+		switch e := e.(type) {
+		case *ast.PrefixExpression:
+			return e.Operator == "-" && isBounded(e.Right)
+		case *ast.InfixExpression:
+			return (e.Operator == "+" || e.Operator == "-" || e.Operator == "*") &&
+				isBounded(e.Left) && isBounded(e.Right)
+		case *ast.FunctionCall:
+			name, ok := e.Function.(*ast.Identifier)
+			return ok && len(e.Arguments) == 1 && boundedFuncs[name.Value]
+		}
+		return false
+	}
+	return isBounded(e)
+}
+
+// boundedFuncs are the built-ins whose values are bounded over the reals.
+var boundedFuncs = map[string]bool{"sin": true, "cos": true, "atan": true, "tanh": true}
+
+// conjugate rewrites f ± sqrt(h), or sqrt(h) ± g, as a quotient by the
+// conjugate, as in x - sqrt(x^2 + x) = (x^2 - (x^2 + x)) / (x + sqrt(x^2 +
+// x)), expanding the numerator so that its leading terms cancel.
+func (l *limiter) conjugate(e *ast.InfixExpression) (ast.Expression, ast.Expression, bool) {
+	other := "+"
+	if e.Operator == "+" {
+		other = "-"
+	}
+	_, left := sqrtArg(e.Left)
+	_, right := sqrtArg(e.Right)
+	if !left && !right {
+		return nil, nil, false
+	}
+	numerator := infix(square(e.Left), "-", square(e.Right))
+	if expanded, err := poly.Expand(numerator, l.x); err == nil {
+		numerator = expanded
+	}
+	return numerator, infix(e.Left, other, e.Right), true
+}
+
+// square returns e^2, or u when e is sqrt(u).
+func square(e ast.Expression) ast.Expression {
+	if u, ok := sqrtArg(e); ok {
+		return u
+	}
+	return infix(e, "^", num(2))
+}
+
+// sqrtArg returns u for an expression sqrt(u).
+func sqrtArg(e ast.Expression) (ast.Expression, bool) {
+	c, ok := e.(*ast.FunctionCall)
+	if !ok || len(c.Arguments) != 1 {
+		return nil, false
+	}
+	name, ok := c.Function.(*ast.Identifier)
+	return c.Arguments[0], ok && name.Value == "sqrt"
+}
+
+// quotient returns the limit of f / g.
+func (l *limiter) quotient(f, g ast.Expression) (float64, error) {
+	u, uerr := l.operand(f)
+	v, verr := l.operand(g)
+	if verr == nil && math.IsInf(v, 0) && l.bounded(f, uerr) {
+		// squeeze: bounded over unbounded
+		return 0, nil
+	}
+	if uerr != nil {
+		return 0, uerr
+	}
+	if verr != nil {
+		return 0, verr
+	}
+
+	uzero, vzero := l.vanishes(f, u), l.vanishes(g, v)
+	if (uzero && vzero) || (math.IsInf(u, 0) && math.IsInf(v, 0)) {
+		if w, ok := l.asymptotic(f, g); ok {
+			return w, nil
+		}
+	}
+
+	switch {
+	case uzero && vzero:
+		if w, ok := l.series(f, g); ok {
+			return w, nil
+		}
+		return l.lHopital(f, g)
+	case math.IsInf(u, 0) && math.IsInf(v, 0):
+		// f/g = (1/g) / (1/f), a 0/0 form whose series may exist
+		if w, ok := l.series(reciprocal(g), reciprocal(f)); ok {
+			return w, nil
+		}
+		return l.lHopital(f, g)
+	case vzero:
+		// c / 0 diverges, with the sign g takes near the point
+		s := l.probe(g)
+		if s == 0 || math.IsNaN(s) {
+			return 0, fmt.Errorf("%w: the sign of %s near %v is unknown", ErrIndeterminate, brief(g), l.a)
+		}
+		return math.Copysign(math.Inf(1), u*s), nil
+	}
+	return u / v, nil
+}
+
+// asymptotic resolves f/g at an infinity by comparing the leading powers
+// of f and g. It reports false at a finite point, and when either is not
+// an algebraic expression.
+func (l *limiter) asymptotic(f, g ast.Expression) (float64, bool) {
+	if !math.IsInf(l.a, 0) {
+		return 0, false
+	}
+	cf, kf, ok := l.asymptote(f)
+	if !ok {
+		return 0, false
+	}
+	cg, kg, ok := l.asymptote(g)
+	if !ok {
+		return 0, false
+	}
+
+	switch k := kf - kg; {
+	case sameOrder(kf, kg):
+		return cf / cg, true
+	case k < 0:
+		return 0, true
+	}
+	return math.Copysign(math.Inf(1), cf/cg), true
+}
+
+// asymptote returns c and k such that e behaves as c |x|^k at an infinite
+// point. It reports false for other than algebraic expressions, and for
+// sums whose leading terms cancel.
+func (l *limiter) asymptote(e ast.Expression) (c, k float64, ok bool) {
+	if !DependsOn(e, l.x) {
+		v, err := eval.Float(e, nil)
+		return v, 0, err == nil && !l.vanishes(e, v) && !math.IsInf(v, 0) && !math.IsNaN(v)
+	}
+
+	switch e := e.(type) {
+	case *ast.Identifier:
+		return math.Copysign(1, l.a), 1, true
+
+	case *ast.PrefixExpression:
+		if e.Operator == "-" {
+			c, k, ok := l.asymptote(e.Right)
+			return -c, k, ok
+		}
+
+	case *ast.InfixExpression:
+		cu, ku, ok := l.asymptote(e.Left)
+		if !ok {
+			return 0, 0, false
+		}
+		if e.Operator == "^" {
+			if DependsOn(e.Right, l.x) {
+				return 0, 0, false
+			}
+			n, err := eval.Float(e.Right, nil)
+			if err != nil {
+				return 0, 0, false
+			}
+			return leadingPower(cu, ku, n)
+		}
+
+		cv, kv, ok := l.asymptote(e.Right)
+		if !ok {
+			return 0, 0, false
+		}
+		switch e.Operator {
+		case "*":
+			return cu * cv, ku + kv, true
+		case "/":
+			return cu / cv, ku - kv, true
+		case "+", "-":
+			if e.Operator == "-" {
+				cv = -cv
+			}
+			switch {
+			case sameOrder(ku, kv):
+			case ku > kv:
+				return cu, ku, true
+			default:
+				return cv, kv, true
+			}
+			c := cu + cv
+			if math.Abs(c) <= 1e-9*math.Max(math.Abs(cu), math.Abs(cv)) {
+				return 0, 0, false
+			}
+			return c, ku, true
+		}
+
+	case *ast.FunctionCall:
+		if u, ok := sqrtArg(e); ok {
+			cu, ku, ok := l.asymptote(u)
+			if !ok {
+				return 0, 0, false
+			}
+			return leadingPower(cu, ku, 0.5)
+		}
+	}
+	return 0, 0, false
+}
+
+// leadingPower returns the leading term c |x|^k raised to n.
+func leadingPower(c, k, n float64) (float64, float64, bool) {
+	p := math.Pow(c, n)
+	return p, k * n, p != 0 && !math.IsInf(p, 0) && !math.IsNaN(p)
+}
+
+// lHopital replaces the limit of f/g by that of f'/g'.
+func (l *limiter) lHopital(f, g ast.Expression) (float64, error) {
+	if err := l.rewrite(infix(f, "/", g)); err != nil {
+		return 0, err
+	}
+	df, err := Derive(f, l.x)
+	if err != nil {
+		return 0, err
+	}
+	dg, err := Derive(g, l.x)
+	if err != nil {
+		return 0, err
+	}
+	return l.quotient(df, dg)
+}
+
+// rewrite uses up one of the rewrites of an indeterminate form e.
+func (l *limiter) rewrite(e ast.Expression) error {
+	if l.rewrites == 0 {
+		return fmt.Errorf("%w: %s at %v", ErrIndeterminate, brief(e), l.a)
+	}
+	l.rewrites--
+	return nil
+}
+
+// series resolves the 0/0 form f/g by comparing the leading terms of the
+// Taylor series of f and g in x - a, or in 1/x at an infinity. It reports
+// false when either series does not exist.
+func (l *limiter) series(f, g ast.Expression) (float64, bool) {
+	s := l
+	if math.IsInf(l.a, 0) {
+		// x → ±∞ is t = 1/x → 0 from the other side
+		t := infix(num(1), "/", ast.NewIdentifier(l.x))
+		s = &limiter{x: l.x, point: num(0), a: 0, side: -l.side}
+		f = collapse(Substitute(f, l.x, t))
+		g = collapse(Substitute(g, l.x, t))
+	}
+
+	cf, kf, ok := s.leading(f)
+	if !ok {
+		return 0, false
+	}
+	cg, kg, ok := s.leading(g)
+	if !ok {
+		return 0, false
+	}
+
+	switch {
+	case kf > kg:
+		return 0, true
+	case kf == kg:
+		return cf / cg, true
+	}
+	// (x - a)^(kf - kg) diverges, with a sign that flips on the left for
+	// odd powers
+	sign := cf / cg
+	if (kg-kf)%2 == 1 {
+		sign *= s.side
+	}
+	return math.Copysign(math.Inf(1), sign), true
+}
+
+// leading returns the coefficient and power of the lowest nonzero term of
+// the Taylor series of e at the point. It reports false when a derivative
+// is undefined there, or when every coefficient up to limitSeriesOrder is
+// zero.
+func (l *limiter) leading(e ast.Expression) (float64, int, bool) {
+	d := e
+	for k := 0; k <= limitSeriesOrder; k++ {
+		if k > 0 {
+			var err error
+			if d, err = Derive(d, l.x); err != nil {
+				return 0, 0, false
+			}
+		}
+		switch v := l.at(d, l.a); {
+		case math.IsNaN(v) || math.IsInf(v, 0):
+			return 0, 0, false
+		case !l.vanishes(d, v):
+			return v / eval.Factorial(float64(k)), k, true
+		}
+	}
+	return 0, 0, false
+}
+
+// unabs replaces abs(u) and |u| in e by u or -u, by the sign u takes near
+// the point, so that the one-sided limits of abs(x) / x are ±1.
+func (l *limiter) unabs(e ast.Expression) ast.Expression {
+	switch e := e.(type) {
+	case *ast.PrefixExpression:
+		return ast.NewPrefix(e.Operator, l.unabs(e.Right))
+	case *ast.PostfixExpression:
+		return ast.NewPostfix(l.unabs(e.Left), e.Operator)
+	case *ast.InfixExpression:
+		return infix(l.unabs(e.Left), e.Operator, l.unabs(e.Right))
+	case *ast.FunctionCall:
+		if _, _, _, _, ok := e.Integral(); ok {
+			return e
+		}
+		args := make([]ast.Expression, len(e.Arguments))
+		for i, arg := range e.Arguments {
+			args[i] = l.unabs(arg)
+		}
+		if name, ok := e.Function.(*ast.Identifier); ok && name.Value == "abs" &&
+			len(args) == 1 && DependsOn(args[0], l.x) {
+			switch s := l.probe(args[0]); {
+			case s > 0:
+				return args[0]
+			case s < 0:
+				return ast.NewPrefix("-", args[0])
+			}
+		}
+		return &ast.FunctionCall{Token: e.Token, Function: e.Function, Arguments: args}
+	}
+	return e
+}
+
+// reciprocal returns 1/e, undoing a division rather than adding one.
+func reciprocal(e ast.Expression) ast.Expression {
+	switch e := e.(type) {
+	case *ast.InfixExpression:
+		if e.Operator == "/" {
+			if isOne(e.Left) {
+				return e.Right
+			}
+			return infix(e.Right, "/", e.Left)
+		}
+	case *ast.PrefixExpression:
+		if e.Operator == "-" {
+			return ast.NewPrefix("-", reciprocal(e.Right))
+		}
+	}
+	return infix(num(1), "/", e)
+}
+
+// collapse rewrites 1 / (1 / u) as u throughout e.
+func collapse(e ast.Expression) ast.Expression {
+	switch e := e.(type) {
+	case *ast.PrefixExpression:
+		return ast.NewPrefix(e.Operator, collapse(e.Right))
+	case *ast.PostfixExpression:
+		return ast.NewPostfix(collapse(e.Left), e.Operator)
+	case *ast.InfixExpression:
+		left, right := collapse(e.Left), collapse(e.Right)
+		if e.Operator == "/" && isOne(left) {
+			return reciprocal(right)
+		}
+		return infix(left, e.Operator, right)
+	case *ast.FunctionCall:
+		args := make([]ast.Expression, len(e.Arguments))
+		for i, arg := range e.Arguments {
+			args[i] = collapse(arg)
+		}
+		return &ast.FunctionCall{Token: e.Token, Function: e.Function, Arguments: args}
+	}
+	return e
+}
+
+// maxBrief is the length at which brief cuts an expression short.
+const maxBrief = 60
+
+// brief formats e for an error message, cut short when it is long, as the
+// rewrites of an indeterminate form can be.
+func brief(e ast.Expression) string {
+	s := []rune(e.String())
+	if len(s) <= maxBrief {
+		return string(s)
+	}
+	return string(s[:maxBrief]) + "…"
+}
+
+func isOne(e ast.Expression) bool {
+	n, ok := e.(*ast.NumberLiteral)
+	return ok && n.Value == 1 && !n.IsImaginary()
+}
+
+// probe returns the value of e close to the point on the limiter's side,
+// trying points further away when e is zero or undefined right next to it.
+func (l *limiter) probe(e ast.Expression) float64 {
+	v := math.NaN()
+	for _, x := range l.probePoints() {
+		if w := l.at(e, x); !math.IsNaN(w) {
+			v = w
+			if w != 0 {
+				break
+			}
+		}
+	}
+	return v
+}
+
+// probePoints returns points close to the point on the limiter's side,
+// closest first.
+func (l *limiter) probePoints() []float64 {
+	if math.IsInf(l.a, 0) {
+		return []float64{-l.side * 1e6, -l.side * 1e4, -l.side * 1e2}
+	}
+	points := []float64{1e-9, 1e-6, 1e-3}
+	for i, h := range points {
+		points[i] = l.a + l.side*h*math.Max(1, math.Abs(l.a))
+	}
+	return points
+}
+
+// at evaluates e with the variable bound to x, NaN on failure.
+func (l *limiter) at(e ast.Expression, x float64) float64 {
+	env := object.NewEnvironment()
+	env.Set(l.x, &object.Number{Value: x})
+	v, err := eval.Float(e, env)
+	if err != nil {
+		return math.NaN()
+	}
+	return v
+}
+
+// vanishes reports whether e, whose value or limit at the point is v, is
+// zero there. Rounding leaves residues such as sin(pi) = 1.2e-16, so a
+// nonzero v still counts as zero when e at the point is a residue. That
+// needs e continuous at the point, so not for conditionals.
+func (l *limiter) vanishes(e ast.Expression, v float64) bool {
+	switch {
+	case v == 0:
+		return true
+	case math.IsNaN(v) || math.IsInf(v, 0) || hasConditional(e):
+		return false
+	case DependsOn(e, l.x):
+		if l.point == nil || math.IsInf(l.a, 0) {
+			return false
+		}
+		e = Substitute(e, l.x, l.point)
+	}
+	return residue(e)
+}
+
+// residue reports whether e, which has no free identifiers, is zero but for
+// rounding: evaluated with twice the precision, it shrinks with it.
+func residue(e ast.Expression) bool {
+	coarse, ok := bigValue(e, zeroPrec)
+	if !ok {
+		return false
+	}
+	fine, ok := bigValue(e, 2*zeroPrec)
+	if !ok {
+		return false
+	}
+	if fine.Sign() == 0 {
+		return true
+	}
+	// a residue shrinks by about 2^-zeroPrec, a value stays put
+	coarse.Abs(coarse).SetMantExp(coarse, -zeroPrec/2)
+	return new(big.Float).Abs(fine).Cmp(coarse) < 0
+}
+
+// bigValue evaluates e, which has no free identifiers, with prec bits.
+func bigValue(e ast.Expression, prec uint) (*big.Float, bool) {
+	ev := eval.New(eval.WithMode(eval.BigFloat), eval.WithPrecision(prec))
+	obj, err := ev.Eval(&ast.Function{Expression: e}, nil)
+	if err != nil {
+		return nil, false
+	}
+	f, ok := obj.(*object.BigFloat)
+	if !ok || f.Value.IsInf() {
+		return nil, false
+	}
+	return new(big.Float).Set(f.Value), true
+}
+
+// hasConditional reports whether e holds a conditional, whose value at the
+// point may differ from its limit.
+func hasConditional(e ast.Expression) bool {
+	switch e := e.(type) {
+	case *ast.ConditionalExpression:
+		return true
+	case *ast.PrefixExpression:
+		return hasConditional(e.Right)
+	case *ast.PostfixExpression:
+		return hasConditional(e.Left)
+	case *ast.InfixExpression:
+		return hasConditional(e.Left) || hasConditional(e.Right)
+	case *ast.FunctionCall:
+		for _, arg := range e.Arguments {
+			if hasConditional(arg) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sameOrder reports whether the powers a and b are equal up to rounding.
+func sameOrder(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-12*math.Max(math.Abs(a), math.Abs(b))
+}
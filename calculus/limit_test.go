@@ -0,0 +1,104 @@
+package calculus_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/ArtroxGabriel/sigma-parser/calculus"
+)
+
+func TestLimit(t *testing.T) {
+	inf := math.Inf(1)
+	tests := []struct {
+		input, point string
+		dir          calculus.Direction
+		want         float64
+	}{
+		{"x^2 + 3", "2", calculus.Both, 7},
+		{"sin(x) / x", "0", calculus.Both, 1},
+		{"(1 - cos(x)) / x^2", "0", calculus.Both, 0.5},
+		{"(sin(x) - x) / x^3", "0", calculus.Both, -1.0 / 6},
+		{"(x^2 - 1) / (x - 1)", "1", calculus.Both, 2},
+		{"sin(x) / (x - pi)", "pi", calculus.Both, -1},
+		{"1 / x - 1 / sin(x)", "0", calculus.Both, 0},
+		{"1 / x^2", "0", calculus.Both, inf},
+		{"1 / x", "0", calculus.Left, -inf},
+		{"1 / x", "0", calculus.Right, inf},
+		{"abs(x) / x", "0", calculus.Left, -1},
+		{"|x| / x", "0", calculus.Right, 1},
+		{"x * ln(x)", "0", calculus.Right, 0},
+		{"x^x", "0", calculus.Right, 1},
+		{"sqrt(x)", "0", calculus.Right, 0},
+		{"(1 + 1 / x)^x", "1/0", calculus.Both, math.E},
+		{"(x + 1) / (2 * x + 3)", "1/0", calculus.Both, 0.5},
+		{"exp(x) / x^3", "1/0", calculus.Both, inf},
+		{"x / exp(x)", "1/0", calculus.Both, 0},
+		{"atan(x)", "-1/0", calculus.Both, -math.Pi / 2},
+		{"x < 0 ? -1 : 1", "0", calculus.Left, -1},
+		{"x < 0 ? -1 : 1", "0", calculus.Right, 1},
+		{"x < 0 ? 1 : 1 / x", "0", calculus.Left, 1},
+		{"x < 0 ? 1 : 1 / x", "0", calculus.Right, inf},
+		{"x * sin(1 / x)", "0", calculus.Both, 0},
+		{"sin(x) / x", "1/0", calculus.Both, 0},
+		{"x - sqrt(x^2 + x)", "1/0", calculus.Both, -0.5},
+		{"x + sqrt(x^2 + x)", "-1/0", calculus.Both, -0.5},
+		{"sqrt(x^2 + 1) / x", "-1/0", calculus.Both, -1},
+		{"integrate(t, t, 0, x)", "2", calculus.Both, 2},
+		{"(x - 0.0000000000001) / x", "0", calculus.Right, -inf},
+		{"(x - 0.000000001)^2 / x^2", "0", calculus.Both, inf},
+		{"x!", "-1", calculus.Left, -inf},
+		{"x!", "-1", calculus.Right, inf},
+		{"(x - 1)!", "-1", calculus.Right, -inf},
+		{"1 / x!", "-1", calculus.Both, 0},
+		{"sqrt(x + 1) - sqrt(x)", "1/0", calculus.Both, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input+"@"+tt.point, func(t *testing.T) {
+			got, err := calculus.Limit(parse(t, tt.input), "x", parse(t, tt.point), tt.dir)
+			if err != nil {
+				t.Fatalf("Limit() error = %v", err)
+			}
+			if got != tt.want && math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Limit() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimitErrors(t *testing.T) {
+	tests := []struct {
+		input, point string
+		dir          calculus.Direction
+		want         error
+	}{
+		{"1 / x", "0", calculus.Both, calculus.ErrNoLimit},
+		{"abs(x) / x", "0", calculus.Both, calculus.ErrNoLimit},
+		{"sin(x)", "1/0", calculus.Both, calculus.ErrNoLimit},
+		{"sqrt(x)", "0", calculus.Left, calculus.ErrNoLimit},
+		{"x < 0 ? -1 : 1", "0", calculus.Both, calculus.ErrNoLimit},
+		{"sin(1 / x)", "0", calculus.Right, calculus.ErrNoLimit},
+		{"x < 0.0000001 ? 0 : 1", "0", calculus.Right, calculus.ErrIndeterminate},
+		{"x!", "-1", calculus.Both, calculus.ErrNoLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input+"@"+tt.point, func(t *testing.T) {
+			_, err := calculus.Limit(parse(t, tt.input), "x", parse(t, tt.point), tt.dir)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Limit() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := calculus.Limit(parse(t, "x"), "x", parse(t, "x + 1"), calculus.Both); err == nil {
+		t.Error("Limit() at a point depending on x succeeded")
+	}
+
+	// the rewrites of an indeterminate form grow, but the message must not
+	_, err := calculus.Limit(parse(t, "(exp(x) + x) / (exp(x) - x)"), "x", parse(t, "1/0"), calculus.Both)
+	if err != nil && len(err.Error()) > 200 {
+		t.Errorf("Limit() error is %d bytes long: %v", len(err.Error()), err)
+	}
+}
@@ -0,0 +1,240 @@
+package calculus
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ArtroxGabriel/sigma-parser/ast"
+	"github.com/ArtroxGabriel/sigma-parser/eval"
+	poly "github.com/ArtroxGabriel/sigma-parser/polynomial"
+	"github.com/ArtroxGabriel/sigma-parser/simplify"
+)
+
+// MaxSeriesOrder bounds the order of Series: derivatives tend to grow
+// quickly, and k! overflows a float64 past 170.
+const MaxSeriesOrder = 64
+
+// seriesLookahead is how many orders past the last term Series searches for
+// a nonzero remainder term.
+const seriesLookahead = 8
+
+// maxSeriesNodes bounds the size of the derivatives Series computes. Those
+// of x^x grow quickly with the order even with like terms collected, and
+// would otherwise take minutes to reach MaxSeriesOrder.
+const maxSeriesNodes = 10000
+
+// residueRatio is how far below the largest earlier derivative a derivative
+// must be for Series to check whether it is a rounding residue.
+const residueRatio = 1e-6
+
+// Expansion is a truncated Taylor series.
+type Expansion struct {
+	// Polynomial is the sum of f⁽ᵏ⁾(a) / k! (x - a)^k for k up to the order.
+	Polynomial ast.Expression
+
+	// Remainder is the first nonzero term after the polynomial, which
+	// approximates the truncation error for x near a. It is 0 only when a
+	// derivative past the order is identically zero, as for a polynomial
+	// of at most the order's degree.
+	Remainder ast.Expression
+}
+
+// Series returns the Taylor expansion of e in variable around the point
+// around, up to (x - around)^order, by repeated symbolic differentiation.
+// Around 0 it is the Maclaurin series. Coefficients that are numbers are
+// evaluated, while those that depend on other identifiers, such as exp(a)
+// around a, stay symbolic.
+func Series(e ast.Expression, variable string, around ast.Expression, order int) (*Expansion, error) {
+	if order < 0 || order > MaxSeriesOrder {
+		return nil, fmt.Errorf("series order %d out of range [0, %d]", order, MaxSeriesOrder)
+	}
+	if DependsOn(around, variable) {
+		return nil, fmt.Errorf("expansion point %s depends on %s", around, variable)
+	}
+
+	// (x - a)^k, or x^k around 0
+	dx := infix(ast.NewIdentifier(variable), "-", around)
+	if v, ok := around.(*ast.NumberLiteral); ok && v.Value == 0 && !v.IsImaginary() {
+		dx = ast.NewIdentifier(variable)
+	}
+
+	d := simplify.Simplify(e)
+	var poly ast.Expression = num(0)
+	scale := 0.0 // the largest derivative at the point so far
+	for k := 0; k <= order+seriesLookahead; k++ {
+		if k > 0 {
+			var err error
+			if d, err = Derive(d, variable); err != nil {
+				return nil, err
+			}
+			d = collect(d, variable)
+			if size(d) > maxSeriesNodes {
+				return nil, fmt.Errorf("no series for %s around %s: derivative %d exceeds %d nodes",
+					e, around, k, maxSeriesNodes)
+			}
+		}
+		if isZero(d) {
+			// every later derivative is zero too
+			return &Expansion{Polynomial: poly, Remainder: num(0)}, nil
+		}
+
+		term, v, err := taylorTerm(Substitute(d, variable, around), dx, k, scale)
+		scale = math.Max(scale, math.Abs(v))
+		if err != nil {
+			return nil, fmt.Errorf("no series for %s around %s: %w", e, around, err)
+		}
+		switch {
+		case isZero(term):
+		case k <= order:
+			poly = simplify.Simplify(infix(poly, "+", term))
+		default:
+			return &Expansion{Polynomial: poly, Remainder: term}, nil
+		}
+	}
+	return nil, fmt.Errorf("no series for %s around %s: no nonzero term within order %d",
+		e, around, order+seriesLookahead)
+}
+
+// taylorTerm returns the term f⁽ᵏ⁾(a) / k! (x - a)^k, given f⁽ᵏ⁾(a) and
+// x - a, and the value of f⁽ᵏ⁾(a) when it has one. A value far below scale,
+// the largest earlier derivative, may be a rounding residue such as
+// sin(pi), and is 0 when evaluating with more precision says so.
+func taylorTerm(deriv, dx ast.Expression, k int, scale float64) (ast.Expression, float64, error) {
+	fact := eval.Factorial(float64(k))
+	power := infix(dx, "^", num(float64(k)))
+
+	v, err := eval.Float(deriv, nil)
+	if err != nil {
+		// a coefficient in other identifiers
+		return simplify.Simplify(infix(infix(deriv, "*", power), "/", num(fact))), 0, nil
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil, 0, fmt.Errorf("derivative %d is %v", k, v)
+	}
+	if math.Abs(v) <= residueRatio*scale || scale == 0 {
+		if v != 0 && residue(deriv) {
+			v = 0
+		}
+	}
+
+	var term ast.Expression
+	switch c := math.Abs(v) / fact; {
+	case c == 0:
+		return num(0), 0, nil
+	case c == math.Trunc(c):
+		term = infix(num(c), "*", power)
+	case 1/c == math.Trunc(1/c):
+		// x^3 / 6 rather than 0.16666666666666666 * x^3
+		term = infix(power, "/", num(1/c))
+	default:
+		term = infix(num(c), "*", power)
+	}
+	if v < 0 {
+		term = ast.NewPrefix("-", term)
+	}
+	return simplify.Simplify(term), v, nil
+}
+
+// collect multiplies out a derivative and collects its like terms, taking
+// calls and other parts that are not polynomial in x as atoms, and 1/v as
+// an atom whose powers divide by powers of v: 2 cos(x) sin(x) + 2 sin(x)
+// cos(x) is 4 cos(x) sin(x). Simplify leaves such terms apart, and each
+// derivative would double them. It returns d as is when that is smaller.
+func collect(d ast.Expression, x string) ast.Expression {
+	atoms := map[string]ast.Expression{}
+	vars := []string{x}
+	atom := func(key string, e ast.Expression) ast.Expression {
+		// the keys are the formatted atoms, names no identifier can have
+		if _, ok := atoms[key]; !ok {
+			atoms[key] = e
+			vars = append(vars, key)
+		}
+		return ast.NewIdentifier(key)
+	}
+	var replace func(e ast.Expression) ast.Expression
+	replace = func(e ast.Expression) ast.Expression {
+		if !DependsOn(e, x) {
+			return e
+		}
+		switch e := e.(type) {
+		case *ast.Identifier:
+			return e
+		case *ast.PrefixExpression:
+			if e.Operator == "-" {
+				return ast.NewPrefix("-", replace(e.Right))
+			}
+		case *ast.InfixExpression:
+			switch e.Operator {
+			case "+", "-", "*":
+				return infix(replace(e.Left), e.Operator, replace(e.Right))
+			case "/":
+				if !DependsOn(e.Right, x) {
+					return infix(replace(e.Left), "/", e.Right)
+				}
+				// u / v^n = u (1/v)^n
+				v, n := e.Right, ast.Expression(num(1))
+				if p, ok := v.(*ast.InfixExpression); ok && p.Operator == "^" && naturalPower(p.Right) {
+					v, n = p.Left, p.Right
+				}
+				r := atom("1 / "+v.String(), infix(num(1), "/", v))
+				return infix(replace(e.Left), "*", infix(r, "^", n))
+			case "^":
+				if naturalPower(e.Right) {
+					return infix(replace(e.Left), "^", e.Right)
+				}
+			}
+		}
+		return atom(e.String(), e)
+	}
+
+	p, err := poly.FromExpression(replace(d), vars...)
+	if err != nil {
+		return d
+	}
+	c := p.Expression()
+	for key, e := range atoms {
+		c = Substitute(c, key, e)
+	}
+	c = simplify.Simplify(c)
+	if size(c) >= size(d) {
+		return d
+	}
+	return c
+}
+
+// naturalPower reports whether n is a literal positive integer.
+func naturalPower(n ast.Expression) bool {
+	v, ok := n.(*ast.NumberLiteral)
+	return ok && !v.IsImaginary() && v.Value > 0 && v.Value == math.Trunc(v.Value)
+}
+
+// isZero reports whether e is the literal 0.
+func isZero(e ast.Expression) bool {
+	v, ok := e.(*ast.NumberLiteral)
+	return ok && v.Value == 0 && !v.IsImaginary()
+}
+
+// size returns the number of nodes in e.
+func size(e ast.Expression) int {
+	switch e := e.(type) {
+	case *ast.PrefixExpression:
+		return 1 + size(e.Right)
+	case *ast.PostfixExpression:
+		return 1 + size(e.Left)
+	case *ast.InfixExpression:
+		return 1 + size(e.Left) + size(e.Right)
+	case *ast.ConditionalExpression:
+		n := 1 + size(e.Condition) + size(e.Consequence)
+		if e.Alternative != nil {
+			n += size(e.Alternative)
+		}
+		return n
+	case *ast.FunctionCall:
+		n := 1
+		for _, arg := range e.Arguments {
+			n += size(arg)
+		}
+		return n
+	}
+	return 1
+}
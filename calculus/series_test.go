@@ -0,0 +1,121 @@
+package calculus_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ArtroxGabriel/sigma-parser/ast"
+	"github.com/ArtroxGabriel/sigma-parser/calculus"
+	"github.com/ArtroxGabriel/sigma-parser/printer"
+)
+
+func TestSeries(t *testing.T) {
+	tests := []struct {
+		input, around string
+		order         int
+		want, rest    string
+	}{
+		{"exp(x)", "0", 4, "1 + x + x^2 / 2 + x^3 / 6 + x^4 / 24", "x^5 / 120"},
+		{"sin(x)", "0", 4, "x - x^3 / 6", "x^5 / 120"},
+		{"cos(x)", "0", 3, "1 - x^2 / 2", "x^4 / 24"},
+		{"ln(x)", "1", 3, "x - 1 - (x - 1)^2 / 2 + (x - 1)^3 / 3", "-((x - 1)^4 / 4)"},
+		{"1 / (1 - x)", "0", 2, "1 + x + x^2", "x^3"},
+		{"x^3 + 2 * x", "1", 5, "3 + 5 * (x - 1) + 3 * (x - 1)^2 + (x - 1)^3", "0"},
+		{"exp(x)", "a", 2, "exp(a) + exp(a) * (x - a) + exp(a) * (x - a)^2 / 2", "exp(a) * (x - a)^3 / 6"},
+		{"sqrt(x)", "4", 1, "2 + (x - 4) / 4", "-((x - 4)^2 / 64)"},
+		{"cos(x)", "0", 0, "1", "-(x^2 / 2)"},
+		{"cos(x)", "pi", 4, "-1 + (x - pi)^2 / 2 - (x - pi)^4 / 24", "(x - pi)^6 / 720"},
+		{"sin(x)", "pi", 3, "-(x - pi) + (x - pi)^3 / 6", "-((x - pi)^5 / 120)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input+"@"+tt.around, func(t *testing.T) {
+			s, err := calculus.Series(parse(t, tt.input), "x", parse(t, tt.around), tt.order)
+			if err != nil {
+				t.Fatalf("Series() error = %v", err)
+			}
+			if got := printer.Format(s.Polynomial); got != tt.want {
+				t.Errorf("Polynomial = %q, want %q", got, tt.want)
+			}
+			if got := printer.Format(s.Remainder); got != tt.rest {
+				t.Errorf("Remainder = %q, want %q", got, tt.rest)
+			}
+		})
+	}
+}
+
+// TestSeriesApproximates checks that the error of the polynomial near the
+// expansion point is about the size of the remainder term.
+func TestSeriesApproximates(t *testing.T) {
+	tests := []struct {
+		input  string
+		around float64
+	}{
+		{"exp(x) * sin(x)", 0},
+		{"atan(x)", 0},
+		{"1 / (2 + x)", 1},
+		{"sqrt(1 + x^2)", 0.5},
+		{"ln(1 + x) / (1 + x)", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			f := parse(t, tt.input)
+			s, err := calculus.Series(f, "x", ast.NewNumber(tt.around), 5)
+			if err != nil {
+				t.Fatalf("Series() error = %v", err)
+			}
+			x := tt.around + 0.05
+			got := evalAt(t, s.Polynomial, x)
+			want := evalAt(t, f, x)
+			rest := math.Abs(evalAt(t, s.Remainder, x))
+			if err := math.Abs(got - want); err > 2*rest+1e-15 || rest > 1e-6 {
+				t.Errorf("P(%v) = %v, f = %v: error %v, remainder %v", x, got, want, err, rest)
+			}
+		})
+	}
+}
+
+func TestSeriesErrors(t *testing.T) {
+	tests := []struct {
+		input, around string
+		order         int
+	}{
+		{"sqrt(x)", "0", 2},
+		{"exp(x)", "x + 1", 2},
+		{"exp(x)", "0", -1},
+		{"exp(x)", "0", calculus.MaxSeriesOrder + 1},
+		{"x^12 + 1", "0", 1},
+		{"1 / (1 + sin(x)^2)", "0", calculus.MaxSeriesOrder},
+		{"integrate(t * x, t, 0, 1)", "0", 2},
+	}
+
+	for _, tt := range tests {
+		if s, err := calculus.Series(parse(t, tt.input), "x", parse(t, tt.around), tt.order); err == nil {
+			t.Errorf("Series(%s, %s, %d) = %s, want an error", tt.input, tt.around, tt.order, s.Polynomial)
+		}
+	}
+}
+
+// TestSeriesTan checks a series whose derivatives grow unless their like
+// terms are collected.
+func TestSeriesTan(t *testing.T) {
+	s, err := calculus.Series(parse(t, "tan(x)"), "x", parse(t, "0"), 12)
+	if err != nil {
+		t.Fatalf("Series() error = %v", err)
+	}
+	got, want := evalAt(t, s.Polynomial, 0.5), math.Tan(0.5)
+	if rest := evalAt(t, s.Remainder, 0.5); math.Abs(got-want) > 2*rest {
+		t.Errorf("P(0.5) = %v, want %v within %v", got, want, rest)
+	}
+}
+
+func TestSeriesMaxOrder(t *testing.T) {
+	s, err := calculus.Series(parse(t, "exp(x)"), "x", parse(t, "0"), calculus.MaxSeriesOrder)
+	if err != nil {
+		t.Fatalf("Series() error = %v", err)
+	}
+	if got, want := evalAt(t, s.Polynomial, 1), math.E; math.Abs(got-want) > 1e-15 {
+		t.Errorf("P(1) = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,231 @@
+// Package cli implements the sigma command line: the interactive REPL and the
+// non-interactive subcommands used from scripts.
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ArtroxGabriel/sigma-parser/eval"
+	"github.com/ArtroxGabriel/sigma-parser/lexer"
+	"github.com/ArtroxGabriel/sigma-parser/parser"
+	"github.com/ArtroxGabriel/sigma-parser/repl"
+	"github.com/ArtroxGabriel/sigma-parser/token"
+)
+
+// Exit codes returned by Run.
+const (
+	ExitOK         = 0
+	ExitError      = 1 // I/O and other unexpected failures
+	ExitUsage      = 2 // bad command line
+	ExitLexError   = 3 // the input contains characters that are not tokens
+	ExitParseError = 4 // the tokens do not form an expression
+	ExitEvalError  = 5 // the expression could not be evaluated or transformed
+)
+
+const usage = `usage: sigma [command] [flags] [expression...]
+
+With no command, sigma starts an interactive REPL.
+
+Commands:
+  parse    print the syntax tree
+  eval     evaluate, binding variables with --var name=value;
+           --mode complex computes with complex numbers (sqrt(-1), 3+4i),
+           --mode rational exactly (0.1 + 0.2 is 3/10), and --mode bigfloat
+           with --prec bits of precision
+  tokens   print the tokens
+  fmt      print the expression in canonical form
+  deriv    differentiate with respect to --wrt
+  solve    solve an equation such as "v = u + a*t" for the variable
+           given with --for, numerically where it cannot be rearranged
+           (binding the other variables with --var name=value)
+  range    bound the value over intervals given with --in name=lo:hi,
+           rounding outward so that the bounds are guaranteed
+  csv      append computed columns to CSV read from stdin:
+           sigma csv --expr "sqrt(x^2+y^2)" --out r
+  gen      write Go functions for a YAML or JSON list of formulas
+           ({name, expr, params} each) read from a file or stdin:
+           sigma gen --package physics --out formulas_gen.go formulas.yaml
+  serve    serve POST /parse, /eval, /simplify, /derive and /render
+           as a JSON HTTP API on --addr
+  repl     start the interactive REPL
+
+Except for csv and gen, expressions are read from the arguments, or one
+per line from stdin.
+Put -- before an expression that starts with a minus sign.
+Every command accepts --json to emit one JSON object per expression.
+`
+
+// command is a subcommand that processes one expression at a time.
+type command struct {
+	flags *flag.FlagSet
+	json  *bool
+	// run handles a single expression and returns the text and JSON results
+	run func(input string) (text string, value any, err error)
+}
+
+// Run executes the command line args and returns the process exit code.
+func Run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		repl.Start(stdin, stdout)
+		return ExitOK
+	}
+
+	name, args := args[0], args[1:]
+	var cmd *command
+
+	switch name {
+	case "repl":
+		repl.Start(stdin, stdout)
+		return ExitOK
+	case "parse":
+		cmd = parseCommand()
+	case "eval":
+		cmd = evalCommand()
+	case "tokens":
+		cmd = tokensCommand()
+	case "fmt":
+		cmd = fmtCommand()
+	case "deriv":
+		cmd = derivCommand()
+	case "solve":
+		cmd = solveCommand()
+	case "range":
+		cmd = rangeCommand()
+	case "csv":
+		return runCSV(args, stdin, stdout, stderr)
+	case "serve":
+		return runServe(args, stderr)
+	case "gen":
+		return runGen(args, stdin, stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return ExitOK
+	default:
+		fmt.Fprintf(stderr, "sigma: unknown command %q\n\n%s", name, usage)
+		return ExitUsage
+	}
+
+	cmd.flags.SetOutput(stderr)
+	if err := cmd.flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK
+		}
+		return ExitUsage
+	}
+
+	inputs, err := readInputs(cmd.flags.Args(), stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "sigma: %s\n", err)
+		return ExitError
+	}
+
+	return cmd.execute(inputs, stdout, stderr)
+}
+
+func newCommand(name string, run func(string) (string, any, error)) *command {
+	flags := flag.NewFlagSet("sigma "+name, flag.ContinueOnError)
+	return &command{
+		flags: flags,
+		json:  flags.Bool("json", false, "emit one JSON object per expression"),
+		run:   run,
+	}
+}
+
+// execute runs the command over every input. It keeps going after a failure
+// and returns the exit code of the first one.
+func (c *command) execute(inputs []string, stdout, stderr io.Writer) int {
+	code := ExitOK
+	enc := json.NewEncoder(stdout)
+	enc.SetEscapeHTML(false) // keep comparisons such as x < 1 readable
+
+	for _, input := range inputs {
+		text, value, err := c.run(input)
+
+		if err != nil {
+			f := classify(err)
+			if code == ExitOK {
+				code = f.code
+			}
+			if *c.json {
+				_ = enc.Encode(map[string]any{"input": input, "error": f})
+			} else {
+				fmt.Fprintf(stderr, "sigma: %s\n", f)
+			}
+			continue
+		}
+
+		if *c.json {
+			if err := enc.Encode(map[string]any{"input": input, "result": value}); err != nil {
+				fmt.Fprintf(stderr, "sigma: %s\n", err)
+				if code == ExitOK {
+					code = ExitError
+				}
+			}
+		} else {
+			fmt.Fprintln(stdout, text)
+		}
+	}
+
+	return code
+}
+
+// readInputs returns the expression given as arguments, or every non-empty
+// line of stdin when there are none.
+func readInputs(args []string, stdin io.Reader) ([]string, error) {
+	if len(args) > 0 {
+		return []string{strings.Join(args, " ")}, nil
+	}
+
+	var inputs []string
+	scanner := bufio.NewScanner(stdin)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			inputs = append(inputs, line)
+		}
+	}
+	return inputs, scanner.Err()
+}
+
+// failure is the reported form of an error.
+type failure struct {
+	code    int
+	Kind    string          `json:"kind"`
+	Message string          `json:"message"`
+	Pos     *token.Position `json:"pos,omitempty"`
+}
+
+func (f *failure) String() string {
+	if f.Pos != nil {
+		return fmt.Sprintf("%s error at %s: %s", f.Kind, f.Pos, f.Message)
+	}
+	return fmt.Sprintf("%s error: %s", f.Kind, f.Message)
+}
+
+// classify maps err to its exit code and report.
+func classify(err error) *failure {
+	var (
+		lexErr   *lexer.Error
+		parseErr *parser.Error
+		evalErr  *eval.Error
+	)
+
+	switch {
+	case errors.As(err, &lexErr):
+		return &failure{ExitLexError, "lex", fmt.Sprintf("illegal character %q", lexErr.Literal), &lexErr.Pos}
+	case errors.As(err, &parseErr):
+		return &failure{ExitParseError, "parse", parseErr.Msg, &parseErr.Pos}
+	case errors.As(err, &evalErr):
+		f := &failure{code: ExitEvalError, Kind: "eval", Message: evalErr.Msg}
+		if evalErr.Pos.Line > 0 {
+			f.Pos = &evalErr.Pos
+		}
+		return f
+	}
+	return &failure{code: ExitEvalError, Kind: "eval", Message: err.Error()}
+}
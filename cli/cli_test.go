@@ -0,0 +1,317 @@
+package cli_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ArtroxGabriel/sigma-parser/cli"
+)
+
+func run(args []string, stdin string) (code int, stdout, stderr string) {
+	var out, errOut bytes.Buffer
+	code = cli.Run(args, strings.NewReader(stdin), &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		stdin      string
+		wantCode   int
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			name:       "eval with variables",
+			args:       []string{"eval", "--var", "x=2", "--var", "y=0.5", "x ^ 2 * y"},
+			wantStdout: "2\n",
+		},
+		{
+			name:       "eval from stdin",
+			args:       []string{"eval"},
+			stdin:      "1 + 1\n\n2 * 3\n",
+			wantStdout: "2\n6\n",
+		},
+		{
+			name:       "eval json",
+			args:       []string{"eval", "--json", "sqrt(16)"},
+			wantStdout: `{"input":"sqrt(16)","result":4}` + "\n",
+		},
+		{
+			name:       "eval booleans and conditionals",
+			args:       []string{"eval", "--json", "--var", "x=12"},
+			stdin:      "x < 10\nx < 10 ? 5 : 7\n",
+			wantStdout: `{"input":"x < 10","result":false}` + "\n" + `{"input":"x < 10 ? 5 : 7","result":7}` + "\n",
+		},
+		{
+			name:       "eval complex",
+			args:       []string{"eval", "--mode", "complex", "--json", "(1 + 2i) * (3 - i)"},
+			wantStdout: `{"input":"(1 + 2i) * (3 - i)","result":{"im":5,"re":5}}` + "\n",
+		},
+		{
+			name:       "eval rational",
+			args:       []string{"eval", "--mode", "rational", "--json", "0.1 + 0.2"},
+			wantStdout: `{"input":"0.1 + 0.2","result":"3/10"}` + "\n",
+		},
+		{
+			name:  "eval json with infinities",
+			args:  []string{"eval", "--json"},
+			stdin: "1/0\n0/0\n2\n",
+			wantStdout: `{"input":"1/0","result":"+Inf"}` + "\n" + `{"input":"0/0","result":"NaN"}` + "\n" +
+				`{"input":"2","result":2}` + "\n",
+		},
+		{
+			name:       "eval complex json with infinities",
+			args:       []string{"eval", "--mode", "complex", "--json", "ln(0)"},
+			wantStdout: `{"input":"ln(0)","result":{"im":0,"re":"-Inf"}}` + "\n",
+		},
+		{
+			name:       "eval bigfloat",
+			args:       []string{"eval", "--mode", "bigfloat", "--prec", "100", "sqrt(2)"},
+			wantStdout: "1.414213562373095048801688724209\n",
+		},
+		{
+			name:       "fmt",
+			args:       []string{"fmt", "((a+b))*c"},
+			wantStdout: "(a + b) * c\n",
+		},
+		{
+			name:       "deriv",
+			args:       []string{"deriv", "--wrt", "t", "t^3 + 2*t"},
+			wantStdout: "3 * t^2 + 2\n",
+		},
+		{
+			name:       "solve",
+			args:       []string{"solve", "--for", "t", "v = u + a*t"},
+			wantStdout: "t = (v - u) / a\n",
+		},
+		{
+			name:       "solve numerically",
+			args:       []string{"solve", "--var", "k=1", "x = k * cos(x)"},
+			wantStdout: "x = 0.7390851332151559\n",
+		},
+		{
+			name:       "range",
+			args:       []string{"range", "--in", "x=-2:3", "x^2 - 1"},
+			wantStdout: "[-1, 8]\n",
+		},
+		{
+			name:       "range json",
+			args:       []string{"range", "--json", "--in", "x=1:2", "--in", "y=4", "y / x"},
+			wantStdout: `{"input":"y / x","result":{"hi":4,"lo":2}}` + "\n",
+		},
+		{
+			name:       "range json unbounded",
+			args:       []string{"range", "--json", "--in", "x=-1:1", "1/x"},
+			wantStdout: `{"input":"1/x","result":{"hi":"+Inf","lo":"-Inf"}}` + "\n",
+		},
+		{
+			name:       "tokens",
+			args:       []string{"tokens", "x*2"},
+			wantStdout: "1:1\tIDENT    \"x\"\n1:2\t*        \"*\"\n1:3\tNUMBER   \"2\"\n",
+		},
+		{
+			name:       "parse",
+			args:       []string{"parse", "--", "-x"},
+			wantStdout: "Function\n  PrefixExpression -\n    Identifier x\n",
+		},
+		{
+			name:       "lex error",
+			args:       []string{"parse", "1 + $"},
+			wantCode:   cli.ExitLexError,
+			wantStderr: "sigma: lex error at 1:5: illegal character \"$\"\n",
+		},
+		{
+			name:       "parse error",
+			args:       []string{"eval", "(1 + 2"},
+			wantCode:   cli.ExitParseError,
+			wantStderr: "sigma: parse error at 1:7: Expected next token to be ), got EOF instead\n",
+		},
+		{
+			name:       "eval error",
+			args:       []string{"eval", "2 * y"},
+			wantCode:   cli.ExitEvalError,
+			wantStderr: "sigma: eval error at 1:5: identifier not found: y\n",
+		},
+		{
+			name:       "json error",
+			args:       []string{"eval", "--json", "y"},
+			wantCode:   cli.ExitEvalError,
+			wantStdout: `{"error":{"kind":"eval","message":"identifier not found: y","pos":{"offset":0,"line":1,"column":1}},"input":"y"}` + "\n",
+		},
+		{
+			name:       "first error decides the exit code",
+			args:       []string{"eval"},
+			stdin:      "1 +\n@\n3\n",
+			wantCode:   cli.ExitParseError,
+			wantStdout: "3\n",
+			wantStderr: "sigma: parse error at 1:4: no prefix parse function for EOF found\n" +
+				"sigma: lex error at 1:1: illegal character \"@\"\n",
+		},
+		{
+			name:     "bad variable",
+			args:     []string{"eval", "--var", "x", "x"},
+			wantCode: cli.ExitUsage,
+		},
+		{
+			name:     "empty range",
+			args:     []string{"range", "--in", "x=3:1", "x"},
+			wantCode: cli.ExitUsage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, stderr := run(tt.args, tt.stdin)
+
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d (stderr %q)", code, tt.wantCode, stderr)
+			}
+			if stdout != tt.wantStdout {
+				t.Errorf("stdout = %q, want %q", stdout, tt.wantStdout)
+			}
+			if tt.wantStderr != "" && stderr != tt.wantStderr {
+				t.Errorf("stderr = %q, want %q", stderr, tt.wantStderr)
+			}
+		})
+	}
+}
+
+func TestRunCSV(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		stdin      string
+		wantCode   int
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			name:       "single expression",
+			args:       []string{"csv", "--expr", "sqrt(x^2+y^2)", "--out", "r"},
+			stdin:      "x,y\n3,4\n6,8\n",
+			wantStdout: "x,y,r\n3,4,5\n6,8,10\n",
+		},
+		{
+			name:       "multiple expressions and unused text columns",
+			args:       []string{"csv", "--expr", "x + y", "--out", "sum", "--expr", "x * y * z", "--out", "product"},
+			stdin:      "id, x, y, z\nfirst,1,2,3\nsecond,2,2,2\n",
+			wantStdout: "id,x,y,z,sum,product\nfirst,1,2,3,3,6\nsecond,2,2,2,4,8\n",
+		},
+		{
+			name:       "expression text names the column by default",
+			args:       []string{"csv", "--expr", "2*x"},
+			stdin:      "x\n1\n",
+			wantStdout: "x,2*x\n1,2\n",
+		},
+		{
+			name:       "bad rows are reported by line",
+			args:       []string{"csv", "--expr", "x / y", "--out", "q"},
+			stdin:      "x,y\n1,2\nabc,2\n4\n3,4\n",
+			wantCode:   cli.ExitEvalError,
+			wantStdout: "x,y,q\n1,2,0.5\nabc,2,\n4,\n3,4,0.75\n",
+			wantStderr: "sigma: line 3: column \"x\": invalid number \"abc\"\n" +
+				"sigma: line 4: missing column \"y\"\n",
+		},
+		{
+			name:       "unknown column",
+			args:       []string{"csv", "--expr", "x + w"},
+			stdin:      "x,y\n1,2\n",
+			wantCode:   cli.ExitEvalError,
+			wantStderr: "sigma: --expr \"x + w\": no column named \"w\"\n",
+		},
+		{
+			name:       "parse error in expression",
+			args:       []string{"csv", "--expr", "x +"},
+			stdin:      "x\n1\n",
+			wantCode:   cli.ExitParseError,
+			wantStderr: "sigma: --expr \"x +\": parse error at 1:4: no prefix parse function for EOF found\n",
+		},
+		{
+			name:     "mismatched --out",
+			args:     []string{"csv", "--expr", "x", "--expr", "y", "--out", "a"},
+			wantCode: cli.ExitUsage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, stderr := run(tt.args, tt.stdin)
+
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d (stderr %q)", code, tt.wantCode, stderr)
+			}
+			if stdout != tt.wantStdout {
+				t.Errorf("stdout = %q, want %q", stdout, tt.wantStdout)
+			}
+			if tt.wantStderr != "" && stderr != tt.wantStderr {
+				t.Errorf("stderr = %q, want %q", stderr, tt.wantStderr)
+			}
+		})
+	}
+}
+
+func TestRunGen(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		stdin      string
+		wantCode   int
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			name: "yaml",
+			args: []string{"gen", "--package", "physics"},
+			stdin: "- name: Hypot\n  expr: sqrt(x^2 + y^2)\n" +
+				"- name: Position\n  expr: x0 + v*t\n  params: [t, x0, v]\n",
+			wantStdout: "// Code generated by sigma gen. DO NOT EDIT.\n\npackage physics\n\nimport \"math\"\n\n" +
+				"// Hypot computes sqrt(x^2 + y^2).\nfunc Hypot(x, y float64) float64 {\n\treturn math.Sqrt(math.Pow(x, 2) + math.Pow(y, 2))\n}\n\n" +
+				"// Position computes x0 + v * t.\nfunc Position(t, x0, v float64) float64 {\n\treturn x0 + v*t\n}\n",
+		},
+		{
+			name:       "json",
+			args:       []string{"gen", "--package", "geo"},
+			stdin:      `[{"name": "Area", "expr": "w * h"}]`,
+			wantStdout: "// Code generated by sigma gen. DO NOT EDIT.\n\npackage geo\n\n// Area computes w * h.\nfunc Area(h, w float64) float64 {\n\treturn w * h\n}\n",
+		},
+		{
+			name:       "parse error in formula",
+			args:       []string{"gen", "--package", "p"},
+			stdin:      "- name: Bad\n  expr: x +\n",
+			wantCode:   cli.ExitParseError,
+			wantStderr: "sigma: formula Bad: parse error at 1:4: no prefix parse function for EOF found\n",
+		},
+		{
+			name:       "no Go equivalent",
+			args:       []string{"gen", "--package", "p"},
+			stdin:      "- name: Root\n  expr: sqrt(x) * 2i\n",
+			wantCode:   cli.ExitEvalError,
+			wantStderr: "sigma: formula Root: unsupported in generated code: imaginary number 2i\n",
+		},
+		{
+			name:     "missing package",
+			args:     []string{"gen"},
+			wantCode: cli.ExitUsage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GOPACKAGE", "")
+			code, stdout, stderr := run(tt.args, tt.stdin)
+
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d (stderr %q)", code, tt.wantCode, stderr)
+			}
+			if stdout != tt.wantStdout {
+				t.Errorf("stdout = %q, want %q", stdout, tt.wantStdout)
+			}
+			if tt.wantStderr != "" && stderr != tt.wantStderr {
+				t.Errorf("stderr = %q, want %q", stderr, tt.wantStderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package cli
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ArtroxGabriel/sigma-parser/calculus"
+	"github.com/ArtroxGabriel/sigma-parser/eval"
+	"github.com/ArtroxGabriel/sigma-parser/interval"
+	"github.com/ArtroxGabriel/sigma-parser/lexer"
+	"github.com/ArtroxGabriel/sigma-parser/object"
+	"github.com/ArtroxGabriel/sigma-parser/parser"
+	"github.com/ArtroxGabriel/sigma-parser/printer"
+	"github.com/ArtroxGabriel/sigma-parser/solve"
+	"github.com/ArtroxGabriel/sigma-parser/token"
+)
+
+func parseCommand() *command {
+	return newCommand("parse", func(input string) (string, any, error) {
+		function, err := parser.Parse(input)
+		if err != nil {
+			return "", nil, err
+		}
+		return strings.TrimSuffix(printer.Tree(function), "\n"), function, nil
+	})
+}
+
+func evalCommand() *command {
+	vars := varsFlag{}
+	mode := eval.Real
+	var prec uint
+	cmd := newCommand("eval", func(input string) (string, any, error) {
+		function, err := parser.Parse(input)
+		if err != nil {
+			return "", nil, err
+		}
+
+		env := object.NewEnvironment()
+		for name, value := range vars {
+			env.Set(name, &object.Number{Value: value})
+		}
+
+		obj, err := eval.New(eval.WithMode(mode), eval.WithPrecision(prec)).Eval(function, env)
+		if err != nil {
+			return "", nil, err
+		}
+		switch obj := obj.(type) {
+		case *object.Number:
+			return obj.Inspect(), jsonNumber(obj.Value), nil
+		case *object.Complex:
+			return obj.Inspect(), map[string]any{"re": jsonNumber(real(obj.Value)), "im": jsonNumber(imag(obj.Value))}, nil
+		case *object.Rational, *object.BigFloat:
+			// as text, since a JSON number would round them to a float64
+			return obj.Inspect(), obj.Inspect(), nil
+		case *object.Boolean:
+			return obj.Inspect(), obj.Value, nil
+		}
+		return obj.Inspect(), nil, nil
+	})
+	cmd.flags.Var(vars, "var", "bind a variable, as `name=value` (repeatable)")
+	cmd.flags.TextVar(&mode, "mode", eval.Real, "number `mode`: real, complex, rational or bigfloat")
+	cmd.flags.UintVar(&prec, "prec", eval.DefaultPrecision, "`bits` of precision in the rational and bigfloat modes")
+	return cmd
+}
+
+// jsonNumber returns v for encoding as JSON, which has no numbers for NaN
+// and the infinities: those are sent as the text "NaN", "+Inf" and "-Inf".
+func jsonNumber(v float64) any {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return v
+}
+
+func tokensCommand() *command {
+	type jsonToken struct {
+		Type    token.TokenType `json:"type"`
+		Literal string          `json:"literal"`
+		Pos     token.Position  `json:"pos"`
+	}
+
+	return newCommand("tokens", func(input string) (string, any, error) {
+		var (
+			lines  []string
+			tokens []jsonToken
+		)
+
+		l := lexer.New(input)
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+			if tok.Type == token.ILLEGAL {
+				return "", nil, &lexer.Error{Pos: tok.Pos, End: tok.End(), Literal: tok.Literal}
+			}
+			lines = append(lines, fmt.Sprintf("%s\t%-8s %q", tok.Pos, tok.Type, tok.Literal))
+			tokens = append(tokens, jsonToken{tok.Type, tok.Literal, tok.Pos})
+		}
+
+		return strings.Join(lines, "\n"), tokens, nil
+	})
+}
+
+func fmtCommand() *command {
+	return newCommand("fmt", func(input string) (string, any, error) {
+		function, err := parser.Parse(input)
+		if err != nil {
+			return "", nil, err
+		}
+		out := printer.Format(function)
+		return out, out, nil
+	})
+}
+
+func derivCommand() *command {
+	var wrt string
+	cmd := newCommand("deriv", func(input string) (string, any, error) {
+		function, err := parser.Parse(input)
+		if err != nil {
+			return "", nil, err
+		}
+
+		d, err := calculus.Derive(function.Expression, wrt)
+		if err != nil {
+			return "", nil, err
+		}
+		out := printer.Format(d)
+		return out, out, nil
+	})
+	cmd.flags.StringVar(&wrt, "wrt", "x", "variable to differentiate with respect to")
+	return cmd
+}
+
+func solveCommand() *command {
+	vars := varsFlag{}
+	var target string
+	cmd := newCommand("solve", func(input string) (string, any, error) {
+		eq, err := parser.ParseEquation(input)
+		if err != nil {
+			return "", nil, err
+		}
+
+		env := object.NewEnvironment()
+		for name, value := range vars {
+			env.Set(name, &object.Number{Value: value})
+		}
+
+		x, err := solve.Equation(eq, target, solve.WithEnv(env))
+		if err != nil {
+			return "", nil, err
+		}
+		out := target + " = " + printer.Format(x)
+		return out, out, nil
+	})
+	cmd.flags.StringVar(&target, "for", "x", "variable to solve for")
+	cmd.flags.Var(vars, "var", "bind a variable for numeric solving, as `name=value` (repeatable)")
+	return cmd
+}
+
+func rangeCommand() *command {
+	ranges := rangesFlag{}
+	cmd := newCommand("range", func(input string) (string, any, error) {
+		function, err := parser.Parse(input)
+		if err != nil {
+			return "", nil, err
+		}
+
+		x, err := interval.Eval(function, ranges)
+		if err != nil {
+			return "", nil, err
+		}
+		if x.IsEmpty() {
+			return x.String(), nil, nil
+		}
+		return x.String(), map[string]any{"lo": jsonNumber(x.Lo), "hi": jsonNumber(x.Hi)}, nil
+	})
+	cmd.flags.Var(ranges, "in", "bound a variable, as `name=lo:hi` or name=value (repeatable)")
+	return cmd
+}
+
+// rangesFlag collects repeated --in name=lo:hi flags.
+type rangesFlag map[string]interval.Interval
+
+func (r rangesFlag) String() string {
+	pairs := make([]string, 0, len(r))
+	for name, x := range r {
+		pairs = append(pairs, name+"="+x.String())
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (r rangesFlag) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("expected name=lo:hi, got %q", s)
+	}
+
+	lo, hi, ok := strings.Cut(value, ":")
+	if !ok {
+		hi = lo
+	}
+	a, err := strconv.ParseFloat(strings.TrimSpace(lo), 64)
+	if err != nil {
+		return fmt.Errorf("invalid lower bound for %s: %q", name, lo)
+	}
+	b, err := strconv.ParseFloat(strings.TrimSpace(hi), 64)
+	if err != nil {
+		return fmt.Errorf("invalid upper bound for %s: %q", name, hi)
+	}
+	if a > b {
+		return fmt.Errorf("empty interval for %s: %s > %s", name, lo, hi)
+	}
+	r[strings.TrimSpace(name)] = interval.New(a, b)
+	return nil
+}
+
+// varsFlag collects repeated --var name=value flags.
+type varsFlag map[string]float64
+
+func (v varsFlag) String() string {
+	pairs := make([]string, 0, len(v))
+	for name, value := range v {
+		pairs = append(pairs, name+"="+strconv.FormatFloat(value, 'g', -1, 64))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (v varsFlag) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("expected name=value, got %q", s)
+	}
+
+	f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %q", name, value)
+	}
+	v[strings.TrimSpace(name)] = f
+	return nil
+}
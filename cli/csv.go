@@ -0,0 +1,179 @@
+package cli
+
+import (
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ArtroxGabriel/sigma-parser/analysis"
+	"github.com/ArtroxGabriel/sigma-parser/ast"
+	"github.com/ArtroxGabriel/sigma-parser/eval"
+	"github.com/ArtroxGabriel/sigma-parser/object"
+	"github.com/ArtroxGabriel/sigma-parser/parser"
+)
+
+// runCSV streams CSV rows from stdin to stdout, appending one column per
+// --expr evaluated with the row's cells bound to the column headers.
+func runCSV(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var exprs, outs stringsFlag
+
+	flags := flag.NewFlagSet("sigma csv", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Var(&exprs, "expr", "expression to compute for each row (repeatable)")
+	flags.Var(&outs, "out", "name of the column for the matching --expr (repeatable)")
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK
+		}
+		return ExitUsage
+	}
+	if len(exprs) == 0 {
+		fmt.Fprintln(stderr, "sigma: csv needs at least one --expr")
+		return ExitUsage
+	}
+	if len(outs) != 0 && len(outs) != len(exprs) {
+		fmt.Fprintf(stderr, "sigma: got %d --out for %d --expr\n", len(outs), len(exprs))
+		return ExitUsage
+	}
+	if len(outs) == 0 {
+		outs = exprs
+	}
+
+	// parse every expression once, up front
+	functions := make([]*ast.Function, len(exprs))
+	for i, input := range exprs {
+		function, err := parser.Parse(input)
+		if err != nil {
+			f := classify(err)
+			fmt.Fprintf(stderr, "sigma: --expr %q: %s\n", input, f)
+			return f.code
+		}
+		functions[i] = function
+	}
+
+	r := csv.NewReader(stdin)
+	r.FieldsPerRecord = -1 // short rows are reported per line below
+	w := csv.NewWriter(stdout)
+	defer w.Flush()
+
+	header, err := r.Read()
+	if err != nil {
+		fmt.Fprintf(stderr, "sigma: reading header: %s\n", err)
+		return ExitError
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+
+	// only the columns the expressions use need to hold numbers
+	var columns []int
+	for i, function := range functions {
+		used, err := usedColumns(function, header)
+		if err != nil {
+			fmt.Fprintf(stderr, "sigma: --expr %q: %s\n", exprs[i], err)
+			return ExitEvalError
+		}
+		columns = append(columns, used...)
+	}
+	slices.Sort(columns)
+	columns = slices.Compact(columns)
+
+	if err := w.Write(append(slices.Clone(header), outs...)); err != nil {
+		fmt.Fprintf(stderr, "sigma: %s\n", err)
+		return ExitError
+	}
+
+	code := ExitOK
+	env := object.NewEnvironment()
+
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "sigma: %s\n", err)
+			return ExitError
+		}
+		line, _ := r.FieldPos(0)
+
+		results := make([]string, len(functions))
+		if err := bindRow(env, header, columns, record); err != nil {
+			fmt.Fprintf(stderr, "sigma: line %d: %s\n", line, err)
+			code = ExitEvalError
+		} else {
+			for i, function := range functions {
+				val, err := eval.Eval(function, env)
+				if err != nil {
+					fmt.Fprintf(stderr, "sigma: line %d: %s: %s\n", line, outs[i], classify(err).Message)
+					code = ExitEvalError
+					continue
+				}
+				results[i] = val.Inspect()
+			}
+		}
+
+		if err := w.Write(append(record, results...)); err != nil {
+			fmt.Fprintf(stderr, "sigma: %s\n", err)
+			return ExitError
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		fmt.Fprintf(stderr, "sigma: %s\n", err)
+		return ExitError
+	}
+	return code
+}
+
+// usedColumns returns the indexes of the columns function reads. It fails if
+// a variable names no column or a called function is not a known built-in.
+func usedColumns(function *ast.Function, header []string) ([]int, error) {
+	info := analysis.Analyze(function)
+	if err := info.Check(); err != nil {
+		return nil, err
+	}
+
+	var used []int
+	for _, name := range info.Variables {
+		col := slices.Index(header, name)
+		if col < 0 {
+			return nil, fmt.Errorf("no column named %q", name)
+		}
+		used = append(used, col)
+	}
+	return used, nil
+}
+
+// bindRow binds the cells of record in the given columns to their names.
+func bindRow(env *object.Environment, header []string, columns []int, record []string) error {
+	for _, col := range columns {
+		name := header[col]
+		if col >= len(record) {
+			return fmt.Errorf("missing column %q", name)
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(record[col]), 64)
+		if err != nil {
+			return fmt.Errorf("column %q: invalid number %q", name, record[col])
+		}
+		env.Set(name, &object.Number{Value: v})
+	}
+	return nil
+}
+
+// stringsFlag collects the values of a repeated string flag.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string { return strings.Join(*s, ",") }
+
+func (s *stringsFlag) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
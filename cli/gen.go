@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ArtroxGabriel/sigma-parser/codegen"
+	"github.com/ArtroxGabriel/sigma-parser/parser"
+)
+
+// formulaSpec is one entry of the list of formulas read by gen.
+type formulaSpec struct {
+	Name   string   `yaml:"name"`
+	Expr   string   `yaml:"expr"`
+	Params []string `yaml:"params"`
+}
+
+// runGen reads a YAML or JSON list of named formulas from the file given as
+// argument, or from stdin, and writes Go source defining one function per
+// formula. It is meant for go:generate, whose $GOPACKAGE is the default
+// package name:
+//
+//	//go:generate sigma gen --out formulas_gen.go formulas.yaml
+func runGen(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("sigma gen", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	pkg := flags.String("package", os.Getenv("GOPACKAGE"), "package of the generated file")
+	out := flags.String("out", "", "file to write instead of stdout")
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK
+		}
+		return ExitUsage
+	}
+	if *pkg == "" {
+		fmt.Fprintln(stderr, "sigma: gen needs --package outside go generate")
+		return ExitUsage
+	}
+	if flags.NArg() > 1 {
+		fmt.Fprintln(stderr, "sigma: gen reads a single file of formulas")
+		return ExitUsage
+	}
+
+	in, name := stdin, "stdin"
+	if flags.NArg() == 1 {
+		name = flags.Arg(0)
+		f, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintf(stderr, "sigma: %s\n", err)
+			return ExitError
+		}
+		defer f.Close()
+		in = f
+	}
+
+	// YAML is a superset of JSON, so one decoder reads both
+	var specs []formulaSpec
+	if err := yaml.NewDecoder(in).Decode(&specs); err != nil && err != io.EOF {
+		fmt.Fprintf(stderr, "sigma: %s: %s\n", name, err)
+		return ExitError
+	}
+
+	formulas := make([]codegen.Formula, len(specs))
+	for i, spec := range specs {
+		if spec.Name == "" {
+			fmt.Fprintf(stderr, "sigma: %s: formula %d has no name\n", name, i+1)
+			return ExitError
+		}
+		function, err := parser.Parse(spec.Expr)
+		if err != nil {
+			f := classify(err)
+			fmt.Fprintf(stderr, "sigma: formula %s: %s\n", spec.Name, f)
+			return f.code
+		}
+		formulas[i] = codegen.Formula{Name: spec.Name, Function: function, Params: spec.Params}
+	}
+
+	src, err := codegen.File(*pkg, "sigma gen", formulas)
+	if err != nil {
+		fmt.Fprintf(stderr, "sigma: %s\n", err)
+		return ExitEvalError
+	}
+
+	if *out == "" {
+		if _, err := stdout.Write(src); err != nil {
+			fmt.Fprintf(stderr, "sigma: %s\n", err)
+			return ExitError
+		}
+		return ExitOK
+	}
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		fmt.Fprintf(stderr, "sigma: %s\n", err)
+		return ExitError
+	}
+	return ExitOK
+}
@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ArtroxGabriel/sigma-parser/server"
+)
+
+// runServe starts the HTTP JSON API and blocks until it fails.
+func runServe(args []string, stderr io.Writer) int {
+	var opts server.Options
+
+	flags := flag.NewFlagSet("sigma serve", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	addr := flags.String("addr", "localhost:8080", "address to listen on")
+	flags.Int64Var(&opts.MaxBodyBytes, "max-body", 64<<10, "largest accepted request body in bytes")
+	flags.DurationVar(&opts.Timeout, "timeout", 5*time.Second, "time allowed per request")
+	flags.IntVar(&opts.CacheSize, "cache", 1024, "number of parsed expressions to cache")
+	flags.IntVar(&opts.MaxDepth, "max-depth", 256, "deepest accepted expression nesting")
+	flags.IntVar(&opts.MaxNodes, "max-nodes", 10000, "largest accepted expression in nodes")
+	flags.IntVar(&opts.MaxSteps, "max-steps", 1000000, "evaluation step budget per request")
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK
+		}
+		return ExitUsage
+	}
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           server.New(opts),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	fmt.Fprintf(stderr, "sigma: listening on %s\n", *addr)
+	if err := srv.ListenAndServe(); err != nil {
+		fmt.Fprintf(stderr, "sigma: %s\n", err)
+		return ExitError
+	}
+	return ExitOK
+}
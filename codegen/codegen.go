@@ -0,0 +1,429 @@
+// Package codegen compiles parsed functions into Go source, so that
+// formulas used on hot paths can be generated at build time instead of
+// being evaluated from their syntax trees.
+package codegen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/token"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ArtroxGabriel/sigma-parser/analysis"
+	"github.com/ArtroxGabriel/sigma-parser/ast"
+	"github.com/ArtroxGabriel/sigma-parser/builtin"
+	"github.com/ArtroxGabriel/sigma-parser/eval"
+	"github.com/ArtroxGabriel/sigma-parser/printer"
+)
+
+// ErrUnsupported is returned for expressions that have no Go equivalent,
+// such as complex numbers and calls to functions that are not built-ins.
+var ErrUnsupported = errors.New("unsupported in generated code")
+
+// Formula is a named function to generate.
+type Formula struct {
+	Name     string        // name of the Go function, which must be a Go identifier
+	Function *ast.Function // the formula
+	Params   []string      // parameters in order; empty means the free variables, sorted
+}
+
+// Func returns the source of a Go function named name that computes fn,
+// such as func Hypot(x, y float64) float64 for sqrt(x^2 + y^2). Its
+// parameters are params, in order, or when there are none the free
+// variables of fn as analysis.Analyze sorts them.
+//
+// Operators and built-ins become their counterparts in the math package:
+// ^ is math.Pow, ln is math.Log and x! is math.Gamma(x + 1). Conditionals
+// are if statements, returning NaN when no condition holds. Subexpressions
+// without variables are evaluated once, here.
+func Func(name string, fn *ast.Function, params ...string) (string, error) {
+	if !token.IsIdentifier(name) {
+		return "", fmt.Errorf("function name %q is not a Go identifier", name)
+	}
+	if fn == nil || fn.Expression == nil {
+		return "", errors.New("empty function")
+	}
+
+	info := analysis.Analyze(fn)
+	if err := info.Check(); err != nil {
+		return "", err
+	}
+	if len(params) == 0 {
+		params = info.Variables
+	}
+	for _, v := range info.Variables {
+		if !slices.Contains(params, v) {
+			return "", fmt.Errorf("variable %s is not a parameter of %s", v, name)
+		}
+	}
+
+	g := &generator{names: make(map[string]string)}
+	goParams := make([]string, len(params))
+	for i, p := range params {
+		if g.names[p] != "" {
+			return "", fmt.Errorf("parameter %s is repeated", p)
+		}
+		id, err := goName(p)
+		if err != nil {
+			return "", err
+		}
+		g.names[p] = id
+		goParams[i] = id
+	}
+
+	body, err := g.body(fn.Expression)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "// %s computes %s.\n", name, printer.Format(fn.Expression))
+	fmt.Fprintf(&out, "func %s(", name)
+	if len(goParams) > 0 {
+		fmt.Fprintf(&out, "%s float64", strings.Join(goParams, ", "))
+	}
+	fmt.Fprintf(&out, ") float64 {\n%s}\n", body)
+
+	src, err := format.Source([]byte(out.String()))
+	if err != nil {
+		return "", fmt.Errorf("generating %s: %w", name, err)
+	}
+	return string(src), nil
+}
+
+// File returns a formatted Go source file in package pkg that defines a
+// function for each formula, marked as generated by the named command.
+func File(pkg, command string, formulas []Formula) ([]byte, error) {
+	if !token.IsIdentifier(pkg) {
+		return nil, fmt.Errorf("package name %q is not a Go identifier", pkg)
+	}
+
+	var funcs bytes.Buffer
+	seen := make(map[string]bool)
+	for _, f := range formulas {
+		if seen[f.Name] {
+			return nil, fmt.Errorf("formula %s is defined twice", f.Name)
+		}
+		seen[f.Name] = true
+
+		src, err := Func(f.Name, f.Function, f.Params...)
+		if err != nil {
+			return nil, fmt.Errorf("formula %s: %w", f.Name, err)
+		}
+		funcs.WriteString("\n")
+		funcs.WriteString(src)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by %s. DO NOT EDIT.\n\n", command)
+	fmt.Fprintf(&out, "package %s\n", pkg)
+	if bytes.Contains(funcs.Bytes(), []byte("math.")) {
+		out.WriteString("\nimport \"math\"\n")
+	}
+	out.Write(funcs.Bytes())
+	return format.Source(out.Bytes())
+}
+
+// goName returns the Go identifier for the variable v, which gets a
+// trailing underscore when it would clash with a keyword or with a name
+// the generated code uses.
+func goName(v string) (string, error) {
+	switch {
+	case token.IsKeyword(v) || v == "math" || v == "float64":
+		return v + "_", nil
+	case !token.IsIdentifier(v):
+		return "", fmt.Errorf("variable %q is not a Go identifier", v)
+	}
+	return v, nil
+}
+
+// Operator precedence in Go, from loosest to tightest.
+const (
+	precOr = iota + 1
+	precAnd
+	precCompare
+	precAdd
+	precMul
+	precUnary
+	precPrimary
+)
+
+var infixPrec = map[string]int{
+	"||": precOr,
+	"&&": precAnd,
+	"==": precCompare, "!=": precCompare,
+	"<": precCompare, "<=": precCompare, ">": precCompare, ">=": precCompare,
+	"+": precAdd, "-": precAdd,
+	"*": precMul, "/": precMul,
+}
+
+// mathFuncs maps built-ins to the math functions that compute them.
+var mathFuncs = map[string]string{
+	"sin":  "math.Sin",
+	"cos":  "math.Cos",
+	"tan":  "math.Tan",
+	"asin": "math.Asin",
+	"acos": "math.Acos",
+	"atan": "math.Atan",
+	"sinh": "math.Sinh",
+	"cosh": "math.Cosh",
+	"tanh": "math.Tanh",
+	"sqrt": "math.Sqrt",
+	"exp":  "math.Exp",
+	"ln":   "math.Log",
+	"log":  "math.Log10",
+	"log2": "math.Log2",
+	"abs":  "math.Abs",
+}
+
+// code is a generated Go expression.
+type code struct {
+	src     string
+	prec    int
+	boolean bool
+	zero    bool // the constant 0
+}
+
+type generator struct {
+	names map[string]string // Go identifiers of the parameters
+}
+
+// body returns the statements of a function returning e.
+func (g *generator) body(e ast.Expression) (string, error) {
+	var out strings.Builder
+	for {
+		c, ok := e.(*ast.ConditionalExpression)
+		if !ok {
+			break
+		}
+		cond, err := g.condition(c.Condition)
+		if err != nil {
+			return "", err
+		}
+		then, err := g.number(c.Consequence)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&out, "if %s {\nreturn %s\n}\n", cond, then)
+
+		if e = c.Alternative; e == nil {
+			out.WriteString("return math.NaN()\n")
+			return out.String(), nil
+		}
+	}
+
+	v, err := g.number(e)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(&out, "return %s\n", v)
+	return out.String(), nil
+}
+
+// number generates e, which must be a number.
+func (g *generator) number(e ast.Expression) (string, error) {
+	c, err := g.expr(e)
+	if err != nil {
+		return "", err
+	}
+	if c.boolean {
+		return "", fmt.Errorf("%s is not a number", e)
+	}
+	return c.src, nil
+}
+
+// condition generates e, which must be a boolean.
+func (g *generator) condition(e ast.Expression) (string, error) {
+	c, err := g.expr(e)
+	if err != nil {
+		return "", err
+	}
+	if !c.boolean {
+		return "", fmt.Errorf("%s is not a condition", e)
+	}
+	return c.src, nil
+}
+
+func (g *generator) expr(e ast.Expression) (code, error) {
+	if id, ok := e.(*ast.Identifier); ok {
+		if name, ok := g.names[id.Value]; ok {
+			return code{src: name, prec: precPrimary}, nil
+		}
+		switch id.Value {
+		case "pi", "PI":
+			return code{src: "math.Pi", prec: precPrimary}, nil
+		case "e", "E":
+			return code{src: "math.E", prec: precPrimary}, nil
+		}
+	}
+	if n, ok := e.(*ast.NumberLiteral); ok && n.IsImaginary() {
+		return code{}, fmt.Errorf("%w: imaginary number %s", ErrUnsupported, n)
+	}
+	if v, err := eval.Float(e, nil); err == nil {
+		return literal(v), nil
+	}
+
+	switch e := e.(type) {
+	case *ast.PrefixExpression:
+		return g.prefix(e)
+	case *ast.PostfixExpression:
+		return g.postfix(e)
+	case *ast.InfixExpression:
+		return g.infix(e)
+	case *ast.ConditionalExpression:
+		// a function literal stands in for the conditional expression Go
+		// lacks
+		body, err := g.body(e)
+		if err != nil {
+			return code{}, err
+		}
+		return code{src: "func() float64 {\n" + body + "}()", prec: precPrimary}, nil
+	case *ast.FunctionCall:
+		return g.call(e)
+	}
+	return code{}, fmt.Errorf("%w: %s", ErrUnsupported, e)
+}
+
+// literal returns v as a Go constant, or as a math call for the values Go
+// has no constant for.
+func literal(v float64) code {
+	switch {
+	case math.IsNaN(v):
+		return code{src: "math.NaN()", prec: precPrimary}
+	case math.IsInf(v, 1):
+		return code{src: "math.Inf(1)", prec: precPrimary}
+	case math.IsInf(v, -1):
+		return code{src: "math.Inf(-1)", prec: precPrimary}
+	case v == 0:
+		return code{src: "0", prec: precPrimary, zero: true}
+	case v < 0:
+		return code{src: strconv.FormatFloat(v, 'g', -1, 64), prec: precUnary}
+	}
+	return code{src: strconv.FormatFloat(v, 'g', -1, 64), prec: precPrimary}
+}
+
+func (g *generator) prefix(e *ast.PrefixExpression) (code, error) {
+	right, err := g.expr(e.Right)
+	if err != nil {
+		return code{}, err
+	}
+
+	switch e.Operator {
+	case "-", "+":
+		if right.boolean {
+			return code{}, fmt.Errorf("%s is not a number", e.Right)
+		}
+		if e.Operator == "+" {
+			return right, nil
+		}
+		// parenthesize -x too, as --x would be a decrement
+		if right.prec < precPrimary {
+			right.src = "(" + right.src + ")"
+		}
+		return code{src: "-" + right.src, prec: precUnary}, nil
+	case "!":
+		if !right.boolean {
+			return code{}, fmt.Errorf("%s is not a condition", e.Right)
+		}
+		return code{src: "!" + paren(right, precUnary), prec: precUnary, boolean: true}, nil
+	}
+	return code{}, fmt.Errorf("%w: operator %s", ErrUnsupported, e.Operator)
+}
+
+func (g *generator) postfix(e *ast.PostfixExpression) (code, error) {
+	left, err := g.expr(e.Left)
+	if err != nil {
+		return code{}, err
+	}
+	if left.boolean {
+		return code{}, fmt.Errorf("%s is not a number", e.Left)
+	}
+
+	switch e.Operator {
+	case "!":
+		return code{src: "math.Gamma(" + left.src + " + 1)", prec: precPrimary}, nil
+	case "%":
+		return code{src: paren(left, precMul) + " / 100", prec: precMul}, nil
+	}
+	return code{}, fmt.Errorf("%w: operator %s", ErrUnsupported, e.Operator)
+}
+
+func (g *generator) infix(e *ast.InfixExpression) (code, error) {
+	left, err := g.expr(e.Left)
+	if err != nil {
+		return code{}, err
+	}
+	right, err := g.expr(e.Right)
+	if err != nil {
+		return code{}, err
+	}
+
+	logical := e.Operator == "&&" || e.Operator == "||"
+	equality := e.Operator == "==" || e.Operator == "!="
+	switch {
+	case logical && (!left.boolean || !right.boolean):
+		return code{}, fmt.Errorf("operands of %s must be conditions in %s", e.Operator, e)
+	case equality && left.boolean != right.boolean:
+		return code{}, fmt.Errorf("type mismatch in %s", e)
+	case !logical && !equality && (left.boolean || right.boolean):
+		return code{}, fmt.Errorf("operands of %s must be numbers in %s", e.Operator, e)
+	}
+
+	if e.Operator == "^" {
+		return code{src: "math.Pow(" + left.src + ", " + right.src + ")", prec: precPrimary}, nil
+	}
+	if e.Operator == "/" && right.zero {
+		return code{}, fmt.Errorf("division by zero in %s", e)
+	}
+
+	prec, ok := infixPrec[e.Operator]
+	if !ok {
+		return code{}, fmt.Errorf("%w: operator %s", ErrUnsupported, e.Operator)
+	}
+	// the tree fixes the order of evaluation, which matters for rounding,
+	// so a right operand of equal precedence keeps its parentheses
+	src := paren(left, prec) + " " + e.Operator + " " + paren(right, prec+1)
+	return code{src: src, prec: prec, boolean: prec <= precCompare}, nil
+}
+
+func (g *generator) call(e *ast.FunctionCall) (code, error) {
+	name, ok := e.Function.(*ast.Identifier)
+	if !ok {
+		return code{}, fmt.Errorf("%w: call of %s", ErrUnsupported, e.Function)
+	}
+	if _, ok := builtin.Lookup(name.Value); !ok || len(e.Arguments) != 1 {
+		return code{}, fmt.Errorf("%w: function %s", ErrUnsupported, name.Value)
+	}
+	arg, err := g.number(e.Arguments[0])
+	if err != nil {
+		return code{}, err
+	}
+
+	switch name.Value {
+	case "re", "conj":
+		// the identity on real numbers
+		return g.expr(e.Arguments[0])
+	case "im":
+		return literal(0), nil
+	case "arg":
+		return code{src: "math.Atan2(0, " + arg + ")", prec: precPrimary}, nil
+	}
+	fn, ok := mathFuncs[name.Value]
+	if !ok {
+		return code{}, fmt.Errorf("%w: function %s", ErrUnsupported, name.Value)
+	}
+	return code{src: fn + "(" + arg + ")", prec: precPrimary}, nil
+}
+
+// paren returns c, parenthesized if it binds more loosely than prec.
+func paren(c code, prec int) string {
+	if c.prec < prec {
+		return "(" + c.src + ")"
+	}
+	return c.src
+}
@@ -0,0 +1,127 @@
+package codegen_test
+
+import (
+	"errors"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/ArtroxGabriel/sigma-parser/codegen"
+	sigma "github.com/ArtroxGabriel/sigma-parser/parser"
+)
+
+func TestFunc(t *testing.T) {
+	tests := []struct {
+		input  string
+		params []string
+		want   string
+	}{
+		{"sqrt(x^2 + y^2)", nil, "func F(x, y float64) float64 {\n\treturn math.Sqrt(math.Pow(x, 2) + math.Pow(y, 2))\n}\n"},
+		{"x - (y - z)", nil, "func F(x, y, z float64) float64 {\n\treturn x - (y - z)\n}\n"},
+		{"x0 + v * t + a * t^2 / 2", []string{"t", "x0", "v", "a"},
+			"func F(t, x0, v, a float64) float64 {\n\treturn x0 + v*t + a*math.Pow(t, 2)/2\n}\n"},
+		{"-(a + b) * ln(c)", nil, "func F(a, b, c float64) float64 {\n\treturn -(a + b) * math.Log(c)\n}\n"},
+		{"2 * pi * r", nil, "func F(r float64) float64 {\n\treturn 6.283185307179586 * r\n}\n"},
+		{"e^x + x! + x%", nil, "func F(x float64) float64 {\n\treturn math.Pow(math.E, x) + math.Gamma(x+1) + x/100\n}\n"},
+		{"|x| + 1/0", nil, "func F(x float64) float64 {\n\treturn math.Abs(x) + math.Inf(1)\n}\n"},
+		{"func * type", nil, "func F(func_, type_ float64) float64 {\n\treturn func_ * type_\n}\n"},
+		{"3!", nil, "func F() float64 {\n\treturn 6\n}\n"},
+		{"piecewise(x < 0, 0, x < 1 && y > 0, x)", nil,
+			"func F(x, y float64) float64 {\n\tif x < 0 {\n\t\treturn 0\n\t}\n\tif x < 1 && y > 0 {\n\t\treturn x\n\t}\n\treturn math.NaN()\n}\n"},
+		{"1 + (x < 0 ? -x : x)", nil,
+			"func F(x float64) float64 {\n\treturn 1 + func() float64 {\n\t\tif x < 0 {\n\t\t\treturn -x\n\t\t}\n\t\treturn x\n\t}()\n}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			fn, err := sigma.Parse(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := codegen.Func("F", fn, tt.params...)
+			if err != nil {
+				t.Fatalf("Func() error = %v", err)
+			}
+			// skip the doc comment
+			got = got[strings.Index(got, "\n")+1:]
+			if got != tt.want {
+				t.Errorf("Func() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFuncErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		params []string
+		is     error
+	}{
+		{"2i * x", nil, codegen.ErrUnsupported},
+		{"f(x) + 1", nil, nil},
+		{"integrate(t, t, 0, x)", nil, codegen.ErrUnsupported},
+		{"x / 0", nil, nil},
+		{"x < 1", nil, nil},
+		{"x + y", []string{"x"}, nil},
+		{"x", []string{"x", "x"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			fn, err := sigma.Parse(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = codegen.Func("F", fn, tt.params...)
+			if err == nil {
+				t.Fatal("Func() succeeded")
+			}
+			if tt.is != nil && !errors.Is(err, tt.is) {
+				t.Errorf("Func() error = %v, want %v", err, tt.is)
+			}
+		})
+	}
+}
+
+// TestFileTypeChecks checks that a generated file is valid Go.
+func TestFileTypeChecks(t *testing.T) {
+	var formulas []codegen.Formula
+	for name, input := range map[string]string{
+		"Hypot":   "sqrt(x^2 + y^2)",
+		"Clamp":   "x < lo ? lo : x > hi ? hi : x",
+		"Sigmoid": "1 / (1 + exp(-x))",
+		"Answer":  "6 * 7",
+	} {
+		fn, err := sigma.Parse(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		formulas = append(formulas, codegen.Formula{Name: name, Function: fn})
+	}
+
+	src, err := codegen.File("formulas", "sigma gen", formulas)
+	if err != nil {
+		t.Fatalf("File() error = %v", err)
+	}
+	if !strings.HasPrefix(string(src), "// Code generated by sigma gen. DO NOT EDIT.\n") {
+		t.Errorf("File() lacks the generated code header:\n%s", src)
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "formulas.go", src, 0)
+	if err != nil {
+		t.Fatalf("parsing generated code: %v\n%s", err, src)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("formulas", fset, []*ast.File{file}, nil); err != nil {
+		t.Fatalf("type checking generated code: %v\n%s", err, src)
+	}
+
+	if _, err := codegen.File("formulas", "sigma gen", append(formulas, formulas[0])); err == nil {
+		t.Error("File() accepted a formula defined twice")
+	}
+}
@@ -0,0 +1,412 @@
+package eval
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+
+	"github.com/ArtroxGabriel/sigma-parser/ast"
+	"github.com/ArtroxGabriel/sigma-parser/bigmath"
+	"github.com/ArtroxGabriel/sigma-parser/builtin"
+	"github.com/ArtroxGabriel/sigma-parser/object"
+	"github.com/ArtroxGabriel/sigma-parser/token"
+)
+
+// DefaultPrecision is the precision in bits of big.Float results, about 77
+// decimal digits.
+const DefaultPrecision = 256
+
+// limits that keep exact results to a reasonable size; beyond them powers
+// are rounded to big.Float and factorials fail
+const (
+	maxExactBits      = 1 << 22 // bits of the numerator and denominator of a power
+	maxExactFactorial = 100000
+)
+
+// limits on the binary exponent of big.Float values, past which printing a
+// result, or reducing the argument of a periodic function by as many bits
+// of π, takes seconds to minutes
+const (
+	maxBigExponent      = 1 << 20
+	maxPeriodicExponent = 1 << 16
+)
+
+// periodic are the built-in functions whose big.Float argument is reduced
+// modulo π.
+var periodic = map[string]bool{"sin": true, "cos": true, "tan": true}
+
+// checkSize fails with ErrTooLarge when the binary exponent of f is beyond
+// limit, as the work on f would take seconds to minutes.
+func checkSize(tok token.Token, node ast.Expression, f *big.Float, limit int) error {
+	if exp := f.MantExp(nil); f.IsInf() || -limit <= exp && exp <= limit {
+		return nil
+	}
+	err := newError(tok, "%s is too large", node)
+	err.Err = ErrTooLarge
+	return err
+}
+
+// WithPrecision sets the precision in bits of the big.Float values computed
+// in the Rational and BigFloat modes.
+func WithPrecision(bits uint) Option {
+	return func(e *Evaluator) { e.prec = bits }
+}
+
+// isBig reports whether arithmetic on objs has to be done with math/big.
+func (e *Evaluator) isBig(objs ...object.Object) bool {
+	if e.mode == Rational || e.mode == BigFloat {
+		return true
+	}
+	for _, obj := range objs {
+		switch obj.(type) {
+		case *object.Rational, *object.BigFloat:
+			return true
+		}
+	}
+	return false
+}
+
+// bigLiteral converts the literal text of node, so that 0.1 is exactly 1/10
+// rather than the nearest float64.
+func (e *Evaluator) bigLiteral(node *ast.NumberLiteral) (object.Object, error) {
+	literal := node.Token.Literal
+	if e.mode == Rational {
+		if r, ok := new(big.Rat).SetString(literal); ok {
+			return &object.Rational{Value: r}, nil
+		}
+	} else if f, _, err := big.ParseFloat(literal, 10, e.prec, big.ToNearestEven); err == nil {
+		return &object.BigFloat{Value: f}, nil
+	}
+
+	// built literals such as +Inf have no exact form
+	f, ok := e.toBigFloat(&object.Number{Value: node.Value})
+	if !ok {
+		return nil, newError(node.Token, "%s is not a number", node)
+	}
+	return &object.BigFloat{Value: f}, nil
+}
+
+// bigConstant returns the named constant to the evaluator's precision.
+func (e *Evaluator) bigConstant(name string, value float64) object.Object {
+	if f, ok := builtin.LookupBigConstant(name, e.prec); ok {
+		return &object.BigFloat{Value: f}
+	}
+	return &object.BigFloat{Value: new(big.Float).SetPrec(e.prec).SetFloat64(value)}
+}
+
+// rationals converts objs to big.Rat values if all of them are exact and the
+// evaluator is not in BigFloat mode. Numbers convert through their shortest
+// decimal form, so the float64 0.1 becomes 1/10.
+func (e *Evaluator) rationals(objs ...object.Object) ([]*big.Rat, bool) {
+	if e.mode == BigFloat {
+		return nil, false
+	}
+
+	rs := make([]*big.Rat, len(objs))
+	for i, obj := range objs {
+		switch obj := obj.(type) {
+		case *object.Rational:
+			rs[i] = obj.Value
+		case *object.Number:
+			r, ok := new(big.Rat).SetString(strconv.FormatFloat(obj.Value, 'g', -1, 64))
+			if !ok {
+				return nil, false
+			}
+			rs[i] = r
+		default:
+			return nil, false
+		}
+	}
+	return rs, true
+}
+
+// bigFloats converts objs to big.Float values of the evaluator's precision.
+func (e *Evaluator) bigFloats(node ast.Expression, objs ...object.Object) ([]*big.Float, error) {
+	fs := make([]*big.Float, len(objs))
+	for i, obj := range objs {
+		f, ok := e.toBigFloat(obj)
+		if !ok {
+			return nil, newError(ast.TokenOf(node), "%s has no arbitrary-precision value", inspectType(obj))
+		}
+		fs[i] = f
+	}
+	return fs, nil
+}
+
+func (e *Evaluator) toBigFloat(obj object.Object) (*big.Float, bool) {
+	switch obj := obj.(type) {
+	case *object.BigFloat:
+		return obj.Value, true
+	case *object.Rational:
+		return new(big.Float).SetPrec(e.prec).SetRat(obj.Value), true
+	case *object.Number:
+		switch {
+		case math.IsNaN(obj.Value):
+			return nil, false
+		case math.IsInf(obj.Value, 0):
+			return new(big.Float).SetPrec(e.prec).SetInf(obj.Value < 0), true
+		}
+		s := strconv.FormatFloat(obj.Value, 'g', -1, 64)
+		f, _, err := big.ParseFloat(s, 10, e.prec, big.ToNearestEven)
+		return f, err == nil
+	}
+	return nil, false
+}
+
+// catchNaN turns the big.ErrNaN panic of an operation without a numeric
+// result, such as 0/0 or ln(-1), into an error about node at tok.
+func catchNaN(tok token.Token, node ast.Expression, err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if _, ok := r.(big.ErrNaN); !ok {
+		panic(r)
+	}
+	*err = newError(tok, "%s is not a number", node)
+}
+
+func (e *Evaluator) evalBigPrefixExpression(node *ast.PrefixExpression, right object.Object) (object.Object, error) {
+	if node.Operator != "-" && node.Operator != "+" {
+		return nil, newError(node.Token, "unknown operator: %s", node.Operator)
+	}
+	negate := node.Operator == "-"
+
+	if rs, ok := e.rationals(right); ok {
+		r := new(big.Rat).Set(rs[0])
+		if negate {
+			r.Neg(r)
+		}
+		return &object.Rational{Value: r}, nil
+	}
+
+	fs, err := e.bigFloats(node, right)
+	if err != nil {
+		return nil, err
+	}
+	f := new(big.Float).SetPrec(e.prec).Set(fs[0])
+	if negate {
+		f.Neg(f)
+	}
+	return &object.BigFloat{Value: f}, nil
+}
+
+func (e *Evaluator) evalBigPostfixExpression(node *ast.PostfixExpression, left object.Object) (object.Object, error) {
+	switch node.Operator {
+	case "!":
+		return e.bigFactorial(node, left)
+	case "%":
+		if rs, ok := e.rationals(left); ok {
+			return &object.Rational{Value: new(big.Rat).Quo(rs[0], big.NewRat(100, 1))}, nil
+		}
+		fs, err := e.bigFloats(node, left)
+		if err != nil {
+			return nil, err
+		}
+		return &object.BigFloat{Value: new(big.Float).SetPrec(e.prec).Quo(fs[0], big.NewFloat(100))}, nil
+	}
+	return nil, newError(node.Token, "unknown operator: %s", node.Operator)
+}
+
+// bigFactorial computes n! exactly for integers n up to maxExactFactorial.
+func (e *Evaluator) bigFactorial(node *ast.PostfixExpression, left object.Object) (object.Object, error) {
+	var n *big.Int
+	rs, exact := e.rationals(left)
+	if exact {
+		if rs[0].IsInt() {
+			n = rs[0].Num()
+		}
+	} else {
+		fs, err := e.bigFloats(node, left)
+		if err != nil {
+			return nil, err
+		}
+		if fs[0].IsInt() {
+			n, _ = fs[0].Int(nil)
+		}
+	}
+
+	switch {
+	case n == nil:
+		return nil, newError(node.Token, "factorial of non-integer %s needs real mode", left.Inspect())
+	case n.Sign() < 0:
+		return nil, newError(node.Token, "factorial of negative number %s", left.Inspect())
+	case n.Cmp(big.NewInt(maxExactFactorial)) > 0:
+		err := newError(node.Token, "factorial of %s is too large", left.Inspect())
+		err.Err = ErrTooLarge
+		return nil, err
+	}
+
+	f := new(big.Int).MulRange(1, n.Int64())
+	if exact {
+		return &object.Rational{Value: new(big.Rat).SetInt(f)}, nil
+	}
+	return &object.BigFloat{Value: new(big.Float).SetPrec(e.prec).SetInt(f)}, nil
+}
+
+func (e *Evaluator) evalBigInfixExpression(node *ast.InfixExpression, left, right object.Object) (result object.Object, err error) {
+	if rs, ok := e.rationals(left, right); ok {
+		obj, err := evalRationalInfixExpression(node, rs[0], rs[1])
+		if obj != nil || err != nil {
+			return obj, err
+		}
+	}
+
+	defer catchNaN(node.Token, node, &err)
+
+	fs, err := e.bigFloats(node, left, right)
+	if err != nil {
+		return nil, err
+	}
+	l, r := fs[0], fs[1]
+	val := new(big.Float).SetPrec(e.prec)
+
+	switch node.Operator {
+	case "+":
+		val.Add(l, r)
+	case "-":
+		val.Sub(l, r)
+	case "*":
+		val.Mul(l, r)
+	case "/":
+		val.Quo(l, r)
+	case "^":
+		val = bigmath.Pow(l, r, e.prec)
+	case "<", "<=", ">", ">=", "==", "!=":
+		return compare(node.Operator, l.Cmp(r)), nil
+	default:
+		return nil, newError(node.Token, "unknown operator: %s", node.Operator)
+	}
+
+	if err := checkSize(node.Token, node, val, maxBigExponent); err != nil {
+		return nil, err
+	}
+	return &object.BigFloat{Value: val}, nil
+}
+
+// evalRationalInfixExpression computes an exact result, or returns nil
+// without an error when there is none, as for 2^0.5.
+func evalRationalInfixExpression(node *ast.InfixExpression, left, right *big.Rat) (object.Object, error) {
+	val := new(big.Rat)
+
+	switch node.Operator {
+	case "+":
+		val.Add(left, right)
+	case "-":
+		val.Sub(left, right)
+	case "*":
+		val.Mul(left, right)
+	case "/":
+		if right.Sign() == 0 {
+			return nil, newError(node.Token, "division by zero")
+		}
+		val.Quo(left, right)
+	case "^":
+		if left.Sign() == 0 && right.Sign() < 0 {
+			// 0^-1 is 1/0
+			return nil, newError(node.Token, "division by zero")
+		}
+		var ok bool
+		if val, ok = ratPow(left, right); !ok {
+			return nil, nil
+		}
+	case "<", "<=", ">", ">=", "==", "!=":
+		return compare(node.Operator, left.Cmp(right)), nil
+	default:
+		return nil, newError(node.Token, "unknown operator: %s", node.Operator)
+	}
+
+	return &object.Rational{Value: val}, nil
+}
+
+// ratPow returns x^y for an integer y, unless the result would exceed
+// maxExactBits or divide by zero.
+func ratPow(x, y *big.Rat) (*big.Rat, bool) {
+	if !y.IsInt() || !y.Num().IsInt64() {
+		return nil, false
+	}
+	n := y.Num().Int64()
+	k := max(n, -n)
+	if n < 0 && x.Sign() == 0 {
+		return nil, false
+	}
+	if bits := int64(x.Num().BitLen() + x.Denom().BitLen()); k > 0 && bits > maxExactBits/k {
+		return nil, false
+	}
+
+	num := new(big.Int).Exp(x.Num(), big.NewInt(k), nil)
+	den := new(big.Int).Exp(x.Denom(), big.NewInt(k), nil)
+	if n < 0 {
+		num, den = den, num
+	}
+	return new(big.Rat).SetFrac(num, den), true
+}
+
+// compare turns the result of a Cmp into the value of a comparison.
+func compare(operator string, cmp int) *object.Boolean {
+	switch operator {
+	case "<":
+		return nativeBoolToBooleanObject(cmp < 0)
+	case "<=":
+		return nativeBoolToBooleanObject(cmp <= 0)
+	case ">":
+		return nativeBoolToBooleanObject(cmp > 0)
+	case ">=":
+		return nativeBoolToBooleanObject(cmp >= 0)
+	case "==":
+		return nativeBoolToBooleanObject(cmp == 0)
+	}
+	return nativeBoolToBooleanObject(cmp != 0)
+}
+
+// exactBuiltins compute built-in functions without rounding where the
+// result of a rational argument is rational; ok is false otherwise.
+var exactBuiltins = map[string]func(x *big.Rat) (result *big.Rat, ok bool){
+	"abs":  func(x *big.Rat) (*big.Rat, bool) { return new(big.Rat).Abs(x), true },
+	"re":   func(x *big.Rat) (*big.Rat, bool) { return x, true },
+	"conj": func(x *big.Rat) (*big.Rat, bool) { return x, true },
+	"im":   func(*big.Rat) (*big.Rat, bool) { return new(big.Rat), true },
+	"sqrt": ratSqrt,
+}
+
+// ratSqrt returns the square root of x when its numerator and denominator
+// are perfect squares.
+func ratSqrt(x *big.Rat) (*big.Rat, bool) {
+	if x.Sign() < 0 {
+		return nil, false
+	}
+	num := new(big.Int).Sqrt(x.Num())
+	den := new(big.Int).Sqrt(x.Denom())
+	if new(big.Int).Mul(num, num).Cmp(x.Num()) != 0 || new(big.Int).Mul(den, den).Cmp(x.Denom()) != 0 {
+		return nil, false
+	}
+	return new(big.Rat).SetFrac(num, den), true
+}
+
+func (e *Evaluator) callBig(node *ast.FunctionCall, fn *builtin.Function, args []object.Object) (result object.Object, err error) {
+	if exact, ok := exactBuiltins[fn.Name]; ok {
+		if rs, ok := e.rationals(args...); ok {
+			if r, ok := exact(rs[0]); ok {
+				return &object.Rational{Value: r}, nil
+			}
+		}
+	}
+
+	defer catchNaN(ast.TokenOf(node.Function), node, &err)
+
+	tok := ast.TokenOf(node.Function)
+	fs, err := e.bigFloats(node, args...)
+	if err != nil {
+		return nil, err
+	}
+	if periodic[fn.Name] {
+		if err := checkSize(tok, node, fs[0], maxPeriodicExponent); err != nil {
+			return nil, err
+		}
+	}
+	val := fn.Big(e.prec, fs...)
+	if err := checkSize(tok, node, val, maxBigExponent); err != nil {
+		return nil, err
+	}
+	return &object.BigFloat{Value: val}, nil
+}
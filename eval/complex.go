@@ -0,0 +1,230 @@
+package eval
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+
+	"github.com/ArtroxGabriel/sigma-parser/ast"
+	"github.com/ArtroxGabriel/sigma-parser/object"
+)
+
+// Mode selects the kind of number an Evaluator computes with.
+type Mode int
+
+const (
+	Real     Mode = iota // float64 arithmetic, the default
+	Complex              // complex128 arithmetic, with i and j as the imaginary unit
+	Rational             // exact big.Rat arithmetic, big.Float where no exact result exists
+	BigFloat             // big.Float arithmetic at the evaluator's precision
+)
+
+var modeNames = map[Mode]string{
+	Real:     "real",
+	Complex:  "complex",
+	Rational: "rational",
+	BigFloat: "bigfloat",
+}
+
+func (m Mode) String() string {
+	if name, ok := modeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("Mode(%d)", int(m))
+}
+
+// ParseMode returns the mode with the given name, such as "complex".
+func ParseMode(name string) (Mode, error) {
+	for m, n := range modeNames {
+		if n == name {
+			return m, nil
+		}
+	}
+	return Real, fmt.Errorf("unknown evaluation mode %q", name)
+}
+
+// MarshalText encodes the mode as its name.
+func (m Mode) MarshalText() ([]byte, error) { return []byte(m.String()), nil }
+
+// UnmarshalText decodes a mode name, so that modes can be read from flags
+// and JSON.
+func (m *Mode) UnmarshalText(text []byte) error {
+	mode, err := ParseMode(string(text))
+	if err != nil {
+		return err
+	}
+	*m = mode
+	return nil
+}
+
+// WithMode makes the evaluator compute in the given mode. The AST is the
+// same in every mode; only the values built from it differ.
+func WithMode(m Mode) Option {
+	return func(e *Evaluator) { e.mode = m }
+}
+
+// fromFloat wraps v in the number object of the evaluator's mode.
+func (e *Evaluator) fromFloat(v float64) object.Object {
+	if e.mode == Complex {
+		return &object.Complex{Value: complex(v, 0)}
+	}
+	return &object.Number{Value: v}
+}
+
+func (e *Evaluator) evalNumberLiteral(node *ast.NumberLiteral) (object.Object, error) {
+	if node.IsImaginary() {
+		if e.mode != Complex {
+			return nil, newError(node.Token, "imaginary number %s needs complex mode", node)
+		}
+		return &object.Complex{Value: complex(0, node.Value)}, nil
+	}
+	if e.isBig() {
+		return e.bigLiteral(node)
+	}
+	return e.fromFloat(node.Value), nil
+}
+
+// constant returns the value of a named constant in the evaluator's mode.
+func (e *Evaluator) constant(name string, value float64) object.Object {
+	if e.isBig() {
+		return e.bigConstant(name, value)
+	}
+	return e.fromFloat(value)
+}
+
+// isImaginaryUnit reports whether name stands for i when it is not bound.
+func (e *Evaluator) isImaginaryUnit(name string) bool {
+	return e.mode == Complex && (name == "i" || name == "j")
+}
+
+// isComplex reports whether arithmetic on objs has to be done in complex128.
+func (e *Evaluator) isComplex(objs ...object.Object) bool {
+	if e.mode == Complex {
+		return true
+	}
+	for _, obj := range objs {
+		if _, ok := obj.(*object.Complex); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func isNumeric(obj object.Object) bool {
+	switch obj.Type() {
+	case object.NUMBER_OBJ, object.COMPLEX_OBJ, object.RATIONAL_OBJ, object.BIGFLOAT_OBJ:
+		return true
+	}
+	return false
+}
+
+// toComplex converts a number object to a complex128.
+func toComplex(obj object.Object) complex128 {
+	if c, ok := obj.(*object.Complex); ok {
+		return c.Value
+	}
+	if v, ok := realValue(obj); ok {
+		return complex(v, 0)
+	}
+	return cmplx.NaN()
+}
+
+// realValue returns the value of a real number object, or of a Complex
+// without an imaginary part, as the nearest float64.
+func realValue(obj object.Object) (float64, bool) {
+	switch obj := obj.(type) {
+	case *object.Number:
+		return obj.Value, true
+	case *object.Rational:
+		v, _ := obj.Value.Float64()
+		return v, true
+	case *object.BigFloat:
+		v, _ := obj.Value.Float64()
+		return v, true
+	case *object.Complex:
+		if imag(obj.Value) == 0 {
+			return real(obj.Value), true
+		}
+	}
+	return 0, false
+}
+
+func evalComplexPrefixExpression(node *ast.PrefixExpression, right complex128) (object.Object, error) {
+	switch node.Operator {
+	case "-":
+		// 0 - z rather than -z, so that -1 is -1+0i and not -1-0i, which
+		// sqrt and ln would put on the wrong side of their branch cut
+		return &object.Complex{Value: 0 - right}, nil
+	case "+":
+		return &object.Complex{Value: right}, nil
+	}
+	return nil, newError(node.Token, "unknown operator: %s", node.Operator)
+}
+
+func evalComplexPostfixExpression(node *ast.PostfixExpression, left complex128) (object.Object, error) {
+	switch node.Operator {
+	case "!":
+		if imag(left) != 0 {
+			return nil, newError(node.Token, "factorial of complex number %s",
+				(&object.Complex{Value: left}).Inspect())
+		}
+		return &object.Complex{Value: complex(Factorial(real(left)), 0)}, nil
+	case "%":
+		return &object.Complex{Value: left / 100}, nil
+	}
+	return nil, newError(node.Token, "unknown operator: %s", node.Operator)
+}
+
+func evalComplexInfixExpression(node *ast.InfixExpression, left, right complex128) (object.Object, error) {
+	var val complex128
+
+	switch node.Operator {
+	case "+":
+		val = left + right
+	case "-":
+		val = left - right
+	case "*":
+		val = left * right
+	case "/":
+		val = left / right
+	case "^":
+		val = powComplex(left, right)
+	case "==":
+		return nativeBoolToBooleanObject(left == right), nil
+	case "!=":
+		return nativeBoolToBooleanObject(left != right), nil
+	case "<", "<=", ">", ">=":
+		if imag(left) != 0 || imag(right) != 0 {
+			return nil, newError(node.Token, "cannot order complex numbers: %s %s %s",
+				(&object.Complex{Value: left}).Inspect(), node.Operator,
+				(&object.Complex{Value: right}).Inspect())
+		}
+		return evalNumberInfixExpression(node, real(left), real(right))
+	default:
+		return nil, newError(node.Token, "unknown operator: %s", node.Operator)
+	}
+
+	return &object.Complex{Value: val}, nil
+}
+
+// powComplex returns z^w. Small integer powers are computed by repeated
+// multiplication so that i^2 is exactly -1 rather than cmplx.Pow's
+// -1+1.2e-16i.
+func powComplex(z, w complex128) complex128 {
+	n := real(w)
+	if imag(w) != 0 || n != math.Trunc(n) || math.Abs(n) > 1024 {
+		return cmplx.Pow(z, w)
+	}
+
+	result, base := complex(1, 0), z
+	for k := int(math.Abs(n)); k > 0; k >>= 1 {
+		if k&1 == 1 {
+			result *= base
+		}
+		base *= base
+	}
+	if n < 0 {
+		return 1 / result
+	}
+	return result
+}
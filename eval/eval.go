@@ -0,0 +1,605 @@
+// Package eval evaluates parsed expressions.
+package eval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/ArtroxGabriel/sigma-parser/analysis"
+	"github.com/ArtroxGabriel/sigma-parser/ast"
+	"github.com/ArtroxGabriel/sigma-parser/builtin"
+	"github.com/ArtroxGabriel/sigma-parser/object"
+	"github.com/ArtroxGabriel/sigma-parser/token"
+)
+
+var (
+	// ErrStepLimit is returned when an evaluation exceeds its step budget.
+	ErrStepLimit = errors.New("evaluation step limit exceeded")
+
+	// ErrTooLarge is returned when a result of the Rational or BigFloat
+	// modes is too large to compute or print in reasonable time, whatever
+	// the step budget.
+	ErrTooLarge = errors.New("number too large")
+)
+
+// TRUE and FALSE are the only Boolean values the evaluator produces.
+var (
+	TRUE  = &object.Boolean{Value: true}
+	FALSE = &object.Boolean{Value: false}
+)
+
+// Error is an evaluation error anchored at the node that caused it.
+type Error struct {
+	Pos token.Position // start of the node's token
+	End token.Position // end of the node's token
+	Msg string
+	Err error // underlying cause, such as ErrStepLimit or a context error
+}
+
+func (e *Error) Error() string { return e.Pos.String() + ": " + e.Msg }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+func newError(tok token.Token, format string, args ...any) *Error {
+	return &Error{Pos: tok.Pos, End: tok.End(), Msg: fmt.Sprintf(format, args...)}
+}
+
+// Evaluator evaluates expressions under an optional context and step budget.
+type Evaluator struct {
+	ctx      context.Context
+	maxSteps int
+	steps    int
+	mode     Mode
+	prec     uint // bits of big.Float results
+}
+
+// Option configures an Evaluator.
+type Option func(*Evaluator)
+
+// WithContext aborts evaluation with the context's error once ctx is done.
+func WithContext(ctx context.Context) Option {
+	return func(e *Evaluator) { e.ctx = ctx }
+}
+
+// WithMaxSteps limits each evaluation to n node visits; zero means no limit.
+func WithMaxSteps(n int) Option {
+	return func(e *Evaluator) { e.maxSteps = n }
+}
+
+// New creates an Evaluator with the given options.
+func New(opts ...Option) *Evaluator {
+	e := &Evaluator{ctx: context.Background(), prec: DefaultPrecision}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Eval evaluates node using the variables bound in env.
+func Eval(node ast.Node, env *object.Environment) (object.Object, error) {
+	return New().Eval(node, env)
+}
+
+// Float evaluates node and returns its value as a float64.
+func Float(node ast.Node, env *object.Environment) (float64, error) {
+	return New().Float(node, env)
+}
+
+// Eval evaluates node using the variables bound in env. The step budget
+// applies to each call separately.
+func (e *Evaluator) Eval(node ast.Node, env *object.Environment) (object.Object, error) {
+	e.steps = 0
+	return e.eval(node, env)
+}
+
+// Float evaluates node and returns its value as a float64.
+func (e *Evaluator) Float(node ast.Node, env *object.Environment) (float64, error) {
+	e.steps = 0
+	return e.float(node, env)
+}
+
+// how many steps pass between checks of the context
+const contextCheckInterval = 256
+
+// step accounts for one node visit and reports budget or context failures.
+func (e *Evaluator) step(tok token.Token) error {
+	e.steps++
+
+	if e.maxSteps > 0 && e.steps > e.maxSteps {
+		err := newError(tok, "%s (%d steps)", ErrStepLimit, e.maxSteps)
+		err.Err = ErrStepLimit
+		return err
+	}
+	if e.steps%contextCheckInterval == 1 {
+		if cerr := e.ctx.Err(); cerr != nil {
+			err := newError(tok, "evaluation aborted: %s", cerr)
+			err.Err = cerr
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Evaluator) eval(node ast.Node, env *object.Environment) (object.Object, error) {
+	if fn, ok := node.(*ast.Function); ok {
+		if fn.Expression == nil {
+			return nil, &Error{Msg: "empty expression"}
+		}
+		node = fn.Expression
+	}
+
+	if exp, ok := node.(ast.Expression); ok && exp != nil {
+		if err := e.step(ast.TokenOf(exp)); err != nil {
+			return nil, err
+		}
+	}
+
+	switch node := node.(type) {
+	case *ast.NumberLiteral:
+		return e.evalNumberLiteral(node)
+
+	case *ast.Constant:
+		return e.constant(node.Name, node.Value), nil
+
+	case *ast.Identifier:
+		return e.evalIdentifier(node, env)
+
+	case *ast.PrefixExpression:
+		if node.Operator == "!" {
+			right, err := e.boolean(node.Right, env)
+			if err != nil {
+				return nil, err
+			}
+			return nativeBoolToBooleanObject(!right), nil
+		}
+		right, err := e.numeric(node.Right, env)
+		if err != nil {
+			return nil, err
+		}
+		if e.isComplex(right) {
+			return evalComplexPrefixExpression(node, toComplex(right))
+		}
+		if e.isBig(right) {
+			return e.evalBigPrefixExpression(node, right)
+		}
+		return evalPrefixExpression(node, right.(*object.Number).Value)
+
+	case *ast.PostfixExpression:
+		left, err := e.numeric(node.Left, env)
+		if err != nil {
+			return nil, err
+		}
+		if e.isComplex(left) {
+			return evalComplexPostfixExpression(node, toComplex(left))
+		}
+		if e.isBig(left) {
+			return e.evalBigPostfixExpression(node, left)
+		}
+		return evalPostfixExpression(node, left.(*object.Number).Value)
+
+	case *ast.InfixExpression:
+		if node.Operator == "&&" || node.Operator == "||" {
+			return e.evalLogicalExpression(node, env)
+		}
+		left, err := e.eval(node.Left, env)
+		if err != nil {
+			return nil, err
+		}
+		right, err := e.eval(node.Right, env)
+		if err != nil {
+			return nil, err
+		}
+		return e.evalInfixExpression(node, left, right)
+
+	case *ast.ConditionalExpression:
+		return e.evalConditionalExpression(node, env)
+
+	case *ast.FunctionCall:
+		return e.evalFunctionCall(node, env)
+
+	case *ast.Program:
+		return e.evalProgram(node, env)
+	}
+
+	return nil, &Error{Msg: fmt.Sprintf("cannot evaluate %T", node)}
+}
+
+func (e *Evaluator) float(node ast.Node, env *object.Environment) (float64, error) {
+	if fn, ok := node.(*ast.Function); ok && fn.Expression != nil {
+		node = fn.Expression
+	}
+	if exp, ok := node.(ast.Expression); ok {
+		return e.number(exp, env)
+	}
+
+	obj, err := e.eval(node, env)
+	if err != nil {
+		return 0, err
+	}
+	v, ok := realValue(obj)
+	if !ok {
+		return 0, &Error{Msg: fmt.Sprintf("expected a real number, got %s", inspectType(obj))}
+	}
+	return v, nil
+}
+
+// number evaluates node to a real number; a complex value qualifies when
+// its imaginary part is zero.
+func (e *Evaluator) number(node ast.Expression, env *object.Environment) (float64, error) {
+	obj, err := e.numeric(node, env)
+	if err != nil {
+		return 0, err
+	}
+	v, ok := realValue(obj)
+	if !ok {
+		return 0, newError(ast.TokenOf(node), "expected a real number, got %s", obj.Inspect())
+	}
+	return v, nil
+}
+
+// numeric evaluates node to a Number or a Complex.
+func (e *Evaluator) numeric(node ast.Expression, env *object.Environment) (object.Object, error) {
+	if node == nil {
+		return nil, &Error{Msg: "missing operand"}
+	}
+	obj, err := e.eval(node, env)
+	if err != nil {
+		return nil, err
+	}
+	if !isNumeric(obj) {
+		return nil, newError(ast.TokenOf(node), "expected a number, got %s", obj.Type())
+	}
+	return obj, nil
+}
+
+// inspectType describes obj by its value if it is a number, else by its type.
+func inspectType(obj object.Object) string {
+	if isNumeric(obj) {
+		return obj.Inspect()
+	}
+	return string(obj.Type())
+}
+
+func (e *Evaluator) boolean(node ast.Expression, env *object.Environment) (bool, error) {
+	if node == nil {
+		return false, &Error{Msg: "missing operand"}
+	}
+	obj, err := e.eval(node, env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := obj.(*object.Boolean)
+	if !ok {
+		return false, newError(ast.TokenOf(node), "expected a boolean, got %s", obj.Type())
+	}
+	return b.Value, nil
+}
+
+func nativeBoolToBooleanObject(input bool) *object.Boolean {
+	if input {
+		return TRUE
+	}
+	return FALSE
+}
+
+func (e *Evaluator) evalIdentifier(node *ast.Identifier, env *object.Environment) (object.Object, error) {
+	if env != nil {
+		if val, ok := env.Get(node.Value); ok {
+			if _, isFn := val.(*object.Function); isFn {
+				return nil, newError(node.Token, "%s is a function, call it with arguments", node.Value)
+			}
+			return val, nil
+		}
+	}
+	if e.isImaginaryUnit(node.Value) {
+		return &object.Complex{Value: 1i}, nil
+	}
+	if val, ok := builtin.LookupConstant(node.Value); ok {
+		return e.constant(node.Value, val), nil
+	}
+	return nil, newError(node.Token, "identifier not found: %s", node.Value)
+}
+
+func evalPrefixExpression(node *ast.PrefixExpression, right float64) (object.Object, error) {
+	switch node.Operator {
+	case "-":
+		return &object.Number{Value: -right}, nil
+	case "+":
+		return &object.Number{Value: right}, nil
+	}
+	return nil, newError(node.Token, "unknown operator: %s", node.Operator)
+}
+
+func evalPostfixExpression(node *ast.PostfixExpression, left float64) (object.Object, error) {
+	switch node.Operator {
+	case "!":
+		return &object.Number{Value: Factorial(left)}, nil
+	case "%":
+		return &object.Number{Value: left / 100}, nil
+	}
+	return nil, newError(node.Token, "unknown operator: %s", node.Operator)
+}
+
+// Factorial returns x!, extended to non-integers as Gamma(x + 1).
+func Factorial(x float64) float64 {
+	if x >= 0 && x <= 170 && x == math.Trunc(x) {
+		// exact for every integer whose factorial fits a float64
+		f := 1.0
+		for i := 2.0; i <= x; i++ {
+			f *= i
+		}
+		return f
+	}
+	return math.Gamma(x + 1)
+}
+
+func (e *Evaluator) evalInfixExpression(node *ast.InfixExpression, left, right object.Object) (object.Object, error) {
+	switch {
+	case isNumeric(left) && isNumeric(right) && e.isComplex(left, right):
+		return evalComplexInfixExpression(node, toComplex(left), toComplex(right))
+	case isNumeric(left) && isNumeric(right) && e.isBig(left, right):
+		return e.evalBigInfixExpression(node, left, right)
+	case left.Type() == object.NUMBER_OBJ && right.Type() == object.NUMBER_OBJ:
+		return evalNumberInfixExpression(node, left.(*object.Number).Value, right.(*object.Number).Value)
+	case left.Type() == object.BOOLEAN_OBJ && right.Type() == object.BOOLEAN_OBJ:
+		return evalBooleanInfixExpression(node, left.(*object.Boolean).Value, right.(*object.Boolean).Value)
+	}
+	return nil, newError(node.Token, "type mismatch: %s %s %s", left.Type(), node.Operator, right.Type())
+}
+
+func evalBooleanInfixExpression(node *ast.InfixExpression, left, right bool) (object.Object, error) {
+	switch node.Operator {
+	case "==":
+		return nativeBoolToBooleanObject(left == right), nil
+	case "!=":
+		return nativeBoolToBooleanObject(left != right), nil
+	}
+	return nil, newError(node.Token, "unknown operator: BOOLEAN %s BOOLEAN", node.Operator)
+}
+
+func evalNumberInfixExpression(node *ast.InfixExpression, left, right float64) (object.Object, error) {
+	var val float64
+
+	switch node.Operator {
+	case "+":
+		val = left + right
+	case "-":
+		val = left - right
+	case "*":
+		val = left * right
+	case "/":
+		val = left / right
+	case "^":
+		val = math.Pow(left, right)
+	case "<":
+		return nativeBoolToBooleanObject(left < right), nil
+	case "<=":
+		return nativeBoolToBooleanObject(left <= right), nil
+	case ">":
+		return nativeBoolToBooleanObject(left > right), nil
+	case ">=":
+		return nativeBoolToBooleanObject(left >= right), nil
+	case "==":
+		return nativeBoolToBooleanObject(left == right), nil
+	case "!=":
+		return nativeBoolToBooleanObject(left != right), nil
+	default:
+		return nil, newError(node.Token, "unknown operator: %s", node.Operator)
+	}
+
+	return &object.Number{Value: val}, nil
+}
+
+// evalLogicalExpression evaluates && and ||, skipping the right operand
+// when the left one decides the result.
+func (e *Evaluator) evalLogicalExpression(node *ast.InfixExpression, env *object.Environment) (object.Object, error) {
+	left, err := e.boolean(node.Left, env)
+	if err != nil {
+		return nil, err
+	}
+	if left == (node.Operator == "||") {
+		return nativeBoolToBooleanObject(left), nil
+	}
+	right, err := e.boolean(node.Right, env)
+	if err != nil {
+		return nil, err
+	}
+	return nativeBoolToBooleanObject(right), nil
+}
+
+// evalConditionalExpression evaluates only the branch chosen by the
+// condition.
+func (e *Evaluator) evalConditionalExpression(node *ast.ConditionalExpression, env *object.Environment) (object.Object, error) {
+	cond, err := e.boolean(node.Condition, env)
+	if err != nil {
+		return nil, err
+	}
+	if cond {
+		return e.eval(node.Consequence, env)
+	}
+	if node.Alternative == nil {
+		return nil, newError(node.Token, "no condition of %s holds", node)
+	}
+	return e.eval(node.Alternative, env)
+}
+
+func (e *Evaluator) evalFunctionCall(node *ast.FunctionCall, env *object.Environment) (object.Object, error) {
+	ident, ok := node.Function.(*ast.Identifier)
+	if !ok {
+		return nil, newError(node.Token, "not a function: %s", node.Function)
+	}
+	if _, _, _, _, ok := node.Integral(); ok {
+		return e.evalIntegral(node, env)
+	}
+
+	if env != nil {
+		if obj, ok := env.Get(ident.Value); ok {
+			if fn, ok := obj.(*object.Function); ok {
+				return e.applyFunction(node, ident, fn, env)
+			}
+		}
+	}
+
+	fn, ok := builtin.Lookup(ident.Value)
+	if !ok {
+		return nil, newError(ident.Token, "unknown function: %s", ident.Value)
+	}
+	if len(node.Arguments) != fn.Arity {
+		return nil, newError(ident.Token,
+			"wrong number of arguments to %s: want %d, got %d",
+			fn.Name, fn.Arity, len(node.Arguments),
+		)
+	}
+
+	objs := make([]object.Object, len(node.Arguments))
+	for i, arg := range node.Arguments {
+		val, err := e.numeric(arg, env)
+		if err != nil {
+			return nil, err
+		}
+		objs[i] = val
+	}
+
+	if e.isComplex(objs...) {
+		args := make([]complex128, len(objs))
+		for i, obj := range objs {
+			args[i] = toComplex(obj)
+		}
+		return &object.Complex{Value: fn.Complex(args...)}, nil
+	}
+	if e.isBig(objs...) {
+		return e.callBig(node, fn, objs)
+	}
+
+	args := make([]float64, len(objs))
+	for i, obj := range objs {
+		args[i] = obj.(*object.Number).Value
+	}
+	return &object.Number{Value: fn.Fn(args...)}, nil
+}
+
+func (e *Evaluator) applyFunction(node *ast.FunctionCall, ident *ast.Identifier, fn *object.Function, env *object.Environment) (object.Object, error) {
+	params := fn.Definition.Parameters
+	if len(node.Arguments) != len(params) {
+		return nil, newError(ident.Token,
+			"wrong number of arguments to %s: want %d, got %d",
+			ident.Value, len(params), len(node.Arguments),
+		)
+	}
+
+	// arguments are evaluated where the call is, the body where the
+	// function was defined
+	inner := object.NewEnclosedEnvironment(fn.Env)
+	for i, arg := range node.Arguments {
+		val, err := e.eval(arg, env)
+		if err != nil {
+			return nil, err
+		}
+		inner.Set(params[i].Value, val)
+	}
+
+	return e.eval(fn.Definition.Body, inner)
+}
+
+// evalProgram runs the statements of program in order and returns the value
+// of the last one. Assignments and definitions bind names in env.
+func (e *Evaluator) evalProgram(program *ast.Program, env *object.Environment) (object.Object, error) {
+	if len(program.Statements) == 0 {
+		return nil, &Error{Msg: "empty program"}
+	}
+	if env == nil {
+		env = object.NewEnvironment()
+	}
+
+	var result object.Object
+	for _, stmt := range program.Statements {
+		var err error
+
+		switch stmt := stmt.(type) {
+		case *ast.ExpressionStatement:
+			result, err = e.eval(stmt.Expression, env)
+		case *ast.AssignStatement:
+			result, err = e.eval(stmt.Value, env)
+			if err == nil {
+				env.Set(stmt.Name.Value, result)
+			}
+		case *ast.FunctionDefinition:
+			result, err = defineFunction(stmt, env)
+		default:
+			err = &Error{Msg: fmt.Sprintf("cannot evaluate %T", stmt)}
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func defineFunction(def *ast.FunctionDefinition, env *object.Environment) (object.Object, error) {
+	name := def.Name.Value
+	if _, ok := builtin.Lookup(name); ok {
+		return nil, newError(def.Name.Token, "cannot redefine built-in function %s", name)
+	}
+
+	fn := &object.Function{Definition: def, Env: env}
+	if path := recursionPath(fn, env); path != nil {
+		return nil, newError(def.Name.Token,
+			"recursive definition of %s: %s", name, strings.Join(path, " -> "),
+		)
+	}
+
+	return env.Set(name, fn), nil
+}
+
+// recursionPath returns the chain of calls through which fn would call
+// itself once bound in env, such as [f g f], or nil if it never does.
+// Rejecting such definitions keeps every evaluation finite.
+func recursionPath(fn *object.Function, env *object.Environment) []string {
+	name := fn.Definition.Name.Value
+	visited := map[string]bool{}
+
+	var path []string
+	var visit func(caller *object.Function) bool
+	visit = func(caller *object.Function) bool {
+		path = append(path, caller.Definition.Name.Value)
+
+		// a parameter shadows any function of the same name
+		params := map[string]bool{}
+		for _, p := range caller.Definition.Parameters {
+			params[p.Value] = true
+		}
+
+		info := analysis.Analyze(&ast.Function{Expression: caller.Definition.Body})
+		for _, call := range info.Calls {
+			if params[call.Name] {
+				continue
+			}
+			if call.Name == name {
+				path = append(path, name)
+				return true
+			}
+			if visited[call.Name] {
+				continue
+			}
+			visited[call.Name] = true
+
+			if obj, ok := caller.Env.Get(call.Name); ok {
+				if callee, ok := obj.(*object.Function); ok && visit(callee) {
+					return true
+				}
+			}
+		}
+
+		path = path[:len(path)-1]
+		return false
+	}
+
+	if visit(fn) {
+		return path
+	}
+	return nil
+}
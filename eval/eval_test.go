@@ -0,0 +1,507 @@
+package eval_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/ArtroxGabriel/sigma-parser/eval"
+	"github.com/ArtroxGabriel/sigma-parser/lexer"
+	"github.com/ArtroxGabriel/sigma-parser/object"
+	"github.com/ArtroxGabriel/sigma-parser/parser"
+)
+
+func testEval(t *testing.T, input string, env *object.Environment) (float64, error) {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	function := p.ParseFunction()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return eval.Float(function, env)
+}
+
+func TestEvalNumberExpression(t *testing.T) {
+	tests := []struct {
+		input string
+		want  float64
+	}{
+		{"5", 5},
+		{"-2.5", -2.5},
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"10 / 4", 2.5},
+		{"2 ^ 10", 1024},
+		{"2 ^ 3 ^ 2", 64},
+		{"sqrt(16) + abs(-3)", 7},
+		{"sin(0) + cos(0)", 1},
+		{"ln(e)", 1},
+		{"log(1000) + log2(8)", 6},
+		{"2 * pi", 2 * math.Pi},
+		{"x * y - x", 3},
+		{"5!", 120},
+		{"0!", 1},
+		{"0.5!", math.Gamma(1.5)},
+		{"5! / (2!3!)", 10},
+		{"2 ^ 3!", 64},
+		{"15%", 0.15},
+		{"200 * 15%", 30},
+		{"x < 10 ? 1 : 2", 1},
+		{"if(y > 5, 1, 2)", 2},
+		{"x > 1 && y <= 3 ? x : -x", 1.5},
+		{"!(x == 1.5) || y != 3 ? 1 : 0", 0},
+		{"piecewise(y < 1, 10, y < 5, 20, 30)", 20},
+		{"piecewise(y < 1, 10, y < 5, 20)", 20},
+		{"1 > 2 == 3 > 4 ? 1 : 0", 1},
+		{"|x - 3|", 1.5},
+		{"||x - 5| - 10|", 6.5},
+	}
+
+	env := object.NewEnvironment()
+	env.Set("x", &object.Number{Value: 1.5})
+	env.Set("y", &object.Number{Value: 3})
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := testEval(t, tt.input, env)
+			if err != nil {
+				t.Fatalf("Eval() error = %v", err)
+			}
+			if math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("Eval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"x + 1", "1:1: identifier not found: x"},
+		{"2 * foo(3)", "1:5: unknown function: foo"},
+		{"sin(1, 2)", "1:1: wrong number of arguments to sin: want 1, got 2"},
+		{"(1 < 2) + 1", "1:9: type mismatch: BOOLEAN + NUMBER"},
+		{"1 < 2 < 3", "1:7: type mismatch: BOOLEAN < NUMBER"},
+		{"1 ? 2 : 3", "1:1: expected a boolean, got NUMBER"},
+		{"-(1 < 2)", "1:5: expected a number, got BOOLEAN"},
+		{"piecewise(1 > 2, 3)", "1:1: no condition of piecewise((1 > 2), 3) holds"},
+		{"1 < 2", "1:3: expected a number, got BOOLEAN"},
+		{"integrate(x * y, x, 0, 1)", "1:15: identifier not found: y"},
+		{"integrate(1 / x, x, 0, 1)", "1:1: integrate((1 / x), x, 0, 1) has no finite value"},
+		{"integrate(x, x, 0, 1 < 2)", "1:22: expected a number, got BOOLEAN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := testEval(t, tt.input, object.NewEnvironment())
+			if err == nil {
+				t.Fatalf("Eval() error = nil, want %q", tt.want)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("Eval() error = %q, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluatorLimits(t *testing.T) {
+	p := parser.New(lexer.New("1 + 2 + 3 + 4"))
+	function := p.ParseFunction()
+
+	e := eval.New(eval.WithMaxSteps(5))
+	if _, err := e.Eval(function, nil); !errors.Is(err, eval.ErrStepLimit) {
+		t.Errorf("Eval() error = %v, want ErrStepLimit", err)
+	}
+
+	// results too large to print fail with or without a budget
+	p = parser.New(lexer.New("(10^100000)^1000"))
+	_, err := eval.New(eval.WithMode(eval.Rational)).Eval(p.ParseFunction(), nil)
+	if !errors.Is(err, eval.ErrTooLarge) || errors.Is(err, eval.ErrStepLimit) {
+		t.Errorf("Eval() error = %v, want ErrTooLarge", err)
+	}
+
+	// the budget applies to each evaluation, not to the evaluator
+	e = eval.New(eval.WithMaxSteps(7))
+	for range 3 {
+		if v, err := e.Float(function, nil); err != nil || v != 10 {
+			t.Fatalf("Float() = %v, %v, want 10", v, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	e = eval.New(eval.WithContext(ctx))
+	if _, err := e.Eval(function, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Eval() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestEvalBoolean(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"1 < 2", true},
+		{"2 <= 1", false},
+		{"3 >= 3 && 4 > 5", false},
+		{"1 == 1 || 1 != 1", true},
+		{"!(1 < 2)", false},
+		{"(1 < 2) == (3 < 4)", true},
+		{"0 / 0 == 0 / 0", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			p := parser.New(lexer.New(tt.input))
+			got, err := eval.Eval(p.ParseFunction(), nil)
+			if err != nil {
+				t.Fatalf("Eval() error = %v", err)
+			}
+			b, ok := got.(*object.Boolean)
+			if !ok {
+				t.Fatalf("Eval() = %T, want *object.Boolean", got)
+			}
+			if b.Value != tt.want {
+				t.Errorf("Eval() = %t, want %t", b.Value, tt.want)
+			}
+		})
+	}
+}
+
+// TestEvalLazyBranches checks that && and || short-circuit and that
+// conditionals only evaluate the branch they choose, so the unbound
+// identifier in the skipped operand is never looked up.
+func TestEvalLazyBranches(t *testing.T) {
+	tests := []string{
+		"1 < 2 ? 1 : missing",
+		"1 > 2 ? missing : 1",
+		"1 > 2 && missing ? 0 : 1",
+		"1 < 2 || missing ? 1 : 0",
+		"piecewise(1 < 2, 1, missing, 2)",
+	}
+
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			got, err := testEval(t, input, object.NewEnvironment())
+			if err != nil {
+				t.Fatalf("Eval() error = %v", err)
+			}
+			if got != 1 {
+				t.Errorf("Eval() = %v, want 1", got)
+			}
+		})
+	}
+}
+
+func TestEvalComplex(t *testing.T) {
+	tests := []struct {
+		input string
+		want  complex128
+	}{
+		{"sqrt(-1)", 1i},
+		{"abs(3+4i)", 5},
+		{"e^(i*PI)", -1},
+		{"(1 + 2i) * (3 - j)", 5 + 5i},
+		{"i^2", -1},
+		{"(1 + i)^-2", -0.5i},
+		{"1 / (1 + i)", 0.5 - 0.5i},
+		{"ln(-1)", math.Pi * 1i},
+		{"exp(i * pi / 2)", 1i},
+		{"re(3 - 4i) + im(3 - 4i)", -1},
+		{"conj(3 - 4i)", 3 + 4i},
+		{"arg(-1)", math.Pi},
+		{"x * i", 2i},
+		{"4! + 50%", 24.5},
+		{"2i == 2j ? 1 : 0", 1},
+		{"f(z) = z * conj(z); f(3 + 4i)", 25},
+	}
+
+	env := object.NewEnvironment()
+	env.Set("x", &object.Number{Value: 2})
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program, err := parser.ParseProgram(tt.input)
+			if err != nil {
+				t.Fatalf("ParseProgram() error = %v", err)
+			}
+			got, err := eval.New(eval.WithMode(eval.Complex)).Eval(program, env)
+			if err != nil {
+				t.Fatalf("Eval() error = %v", err)
+			}
+			c, ok := got.(*object.Complex)
+			if !ok {
+				t.Fatalf("Eval() = %T, want *object.Complex", got)
+			}
+			if cmplx.Abs(c.Value-tt.want) > 1e-12 {
+				t.Errorf("Eval() = %v, want %v", c.Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalComplexErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		mode  eval.Mode
+		want  string
+	}{
+		{"2 + 3i", eval.Real, "1:5: imaginary number 3i needs complex mode"},
+		{"i < 1", eval.Complex, "1:3: cannot order complex numbers: 1i < 1"},
+		{"(2 + i)!", eval.Complex, "1:8: factorial of complex number 2+1i"},
+		{"(1 < 2) * i", eval.Complex, "1:9: type mismatch: BOOLEAN * COMPLEX"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			p := parser.New(lexer.New(tt.input))
+			_, err := eval.New(eval.WithMode(tt.mode)).Eval(p.ParseFunction(), nil)
+			if err == nil {
+				t.Fatalf("Eval() error = nil, want %q", tt.want)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("Eval() error = %q, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestComplexInspect(t *testing.T) {
+	tests := []struct {
+		value complex128
+		want  string
+	}{
+		{3 + 4i, "3+4i"},
+		{3 - 4i, "3-4i"},
+		{-2.5i, "-2.5i"},
+		{7, "7"},
+		{complex(1, math.Inf(1)), "1+Infi"},
+	}
+
+	for _, tt := range tests {
+		if got := (&object.Complex{Value: tt.value}).Inspect(); got != tt.want {
+			t.Errorf("Inspect(%v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestEvalComplexFloat(t *testing.T) {
+	p := parser.New(lexer.New("abs(3 + 4i) + i"))
+	function := p.ParseFunction()
+
+	if _, err := eval.New(eval.WithMode(eval.Complex)).Float(function, nil); err == nil ||
+		err.Error() != "1:13: expected a real number, got 5+1i" {
+		t.Errorf("Float() error = %v, want expected a real number", err)
+	}
+
+	p = parser.New(lexer.New("(2i)^2"))
+	got, err := eval.New(eval.WithMode(eval.Complex)).Float(p.ParseFunction(), nil)
+	if err != nil || got != -4 {
+		t.Errorf("Float() = %v, %v, want -4", got, err)
+	}
+}
+
+func TestEvalBig(t *testing.T) {
+	tests := []struct {
+		input string
+		opts  []eval.Option
+		want  string
+	}{
+		{"0.1 + 0.2", []eval.Option{eval.WithMode(eval.Rational)}, "3/10"},
+		{"0.1 + 0.2 == 0.3 ? 1 : 0", []eval.Option{eval.WithMode(eval.Rational)}, "1"},
+		{"1/3 + 1/6", []eval.Option{eval.WithMode(eval.Rational)}, "1/2"},
+		{"(2/3)^-3 - 2^70", []eval.Option{eval.WithMode(eval.Rational)}, "-9444732965739290427365/8"},
+		{"25!", []eval.Option{eval.WithMode(eval.Rational)}, "15511210043330985984000000"},
+		{"sqrt(9/4) + abs(-1/2)", []eval.Option{eval.WithMode(eval.Rational)}, "2"},
+		{"12.5%", []eval.Option{eval.WithMode(eval.Rational)}, "1/8"},
+		{"x * 3", []eval.Option{eval.WithMode(eval.Rational)}, "3/10"},
+		{"f(n) = n^2 / 2; f(1/3)", []eval.Option{eval.WithMode(eval.Rational)}, "1/18"},
+		{"sqrt(2)", []eval.Option{eval.WithMode(eval.Rational), eval.WithPrecision(64)}, "1.4142135623730950488"},
+		{"pi", []eval.Option{eval.WithMode(eval.BigFloat), eval.WithPrecision(100)}, "3.14159265358979323846264338328"},
+		{"e^1", []eval.Option{eval.WithMode(eval.BigFloat), eval.WithPrecision(100)}, "2.71828182845904523536028747135"},
+		{"2^100 + 1", []eval.Option{eval.WithMode(eval.BigFloat)}, "1.267650600228229401496703205377e+30"},
+		{"1 - 0.9", []eval.Option{eval.WithMode(eval.BigFloat), eval.WithPrecision(64)}, "0.10000000000000000002"},
+		{"30!", []eval.Option{eval.WithMode(eval.BigFloat)}, "2.6525285981219105863630848e+32"},
+		{"1/0", []eval.Option{eval.WithMode(eval.BigFloat)}, "+Inf"},
+	}
+
+	env := object.NewEnvironment()
+	env.Set("x", &object.Number{Value: 0.1})
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program, err := parser.ParseProgram(tt.input)
+			if err != nil {
+				t.Fatalf("ParseProgram() error = %v", err)
+			}
+			got, err := eval.New(tt.opts...).Eval(program, env)
+			if err != nil {
+				t.Fatalf("Eval() error = %v", err)
+			}
+			if got.Inspect() != tt.want {
+				t.Errorf("Eval() = %s, want %s", got.Inspect(), tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalBigErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		mode  eval.Mode
+		want  string
+	}{
+		{"1 / (2 - 2)", eval.Rational, "1:3: division by zero"},
+		{"ln(-1)", eval.Rational, "1:1: ln((-1)) is not a number"},
+		{"0 / 0", eval.BigFloat, "1:3: (0 / 0) is not a number"},
+		{"(1/2)!", eval.Rational, "1:6: factorial of non-integer 1/2 needs real mode"},
+		{"200000!", eval.Rational, "1:7: factorial of 200000 is too large"},
+		{"(10^100000)^1000", eval.Rational, "1:12: ((10 ^ 100000) ^ 1000) is too large"},
+		{"sin(10^100000)", eval.BigFloat, "1:1: sin((10 ^ 100000)) is too large"},
+		{"exp(10^7)", eval.BigFloat, "1:1: exp((10 ^ 7)) is too large"},
+		{"0^-1", eval.Rational, "1:2: division by zero"},
+		{"(1 - 1)^(-1/2)", eval.Rational, "1:8: division by zero"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			p := parser.New(lexer.New(tt.input))
+			_, err := eval.New(eval.WithMode(tt.mode)).Eval(p.ParseFunction(), nil)
+			if err == nil {
+				t.Fatalf("Eval() error = nil, want %q", tt.want)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("Eval() error = %q, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalProgram(t *testing.T) {
+	tests := []struct {
+		input string
+		want  float64
+	}{
+		{"f(x) = x^2 + 1; g(x, y) = f(x) * y; g(2, 3)", 15},
+		{"a = 2\nb = a * 3\na + b", 8},
+		{"k = 2; scale(x) = k * x; k = 10; scale(3)", 30},
+		{"x = 5; f(x) = x + 1; f(1) + x", 7},
+		{"sign(x) = x < 0 ? -1 : x > 0 ? 1 : 0; sign(-3) + sign(4) + sign(0)", 0},
+		{"f(x) = x; f(x) = 2 * x; f(4)", 8},
+		{"pi = 3; 2 * pi", 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program, err := parser.ParseProgram(tt.input)
+			if err != nil {
+				t.Fatalf("ParseProgram() error = %v", err)
+			}
+			got, err := eval.Float(program, object.NewEnvironment())
+			if err != nil {
+				t.Fatalf("Eval() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Eval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalProgramErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"f(x) = f(x - 1)", "1:1: recursive definition of f: f -> f"},
+		{"f(x) = g(x); g(x) = h(x) + 1; h(x) = f(x)", "1:31: recursive definition of h: h -> f -> g -> h"},
+		{"f(x) = x; g(x) = f(x); f(x) = g(x)", "1:24: recursive definition of f: f -> g -> f"},
+		{"f(g) = g(1); g(x) = f(x); g(2)", "1:8: unknown function: g"},
+		{"sin(x) = x", "1:1: cannot redefine built-in function sin"},
+		{"f(x) = x; f(1, 2)", "1:11: wrong number of arguments to f: want 1, got 2"},
+		{"f(x) = x; f + 1", "1:11: f is a function, call it with arguments"},
+		{"f(x) = x + y; f(1)", "1:12: identifier not found: y"},
+		{"a = 1; b = c", "1:12: identifier not found: c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program, err := parser.ParseProgram(tt.input)
+			if err != nil {
+				t.Fatalf("ParseProgram() error = %v", err)
+			}
+			_, err = eval.Eval(program, nil)
+			if err == nil {
+				t.Fatalf("Eval() error = nil, want %q", tt.want)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("Eval() error = %q, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalProgramBindsEnvironment(t *testing.T) {
+	env := object.NewEnvironment()
+	for _, input := range []string{"rate = 0.5", "tax(x) = x * rate"} {
+		program, err := parser.ParseProgram(input)
+		if err != nil {
+			t.Fatalf("ParseProgram(%q) error = %v", input, err)
+		}
+		if _, err := eval.Eval(program, env); err != nil {
+			t.Fatalf("Eval(%q) error = %v", input, err)
+		}
+	}
+
+	got, err := testEval(t, "tax(10)", env)
+	if err != nil || got != 5 {
+		t.Errorf("Eval(tax(10)) = %v, %v, want 5", got, err)
+	}
+}
+
+func TestEvalIntegral(t *testing.T) {
+	tests := []struct {
+		input string
+		want  float64
+	}{
+		{"integrate(x^2, x, 0, 3)", 9},
+		{"integrate(sin(t), t, 0, pi)", 2},
+		{"integrate(1 / sqrt(x), x, 0, 1)", 2},
+		{"integrate(exp(-(x^2)), x, -1/0, 1/0)", math.Sqrt(math.Pi)},
+		{"integrate(x, x, 1, 0)", -0.5},
+		{"integrate(integrate(x * y, x, 0, 1), y, 0, 2)", 1},
+		{"x + integrate(x, x, 0, 2)", 12},
+		{"integrate(k * x, x, 0, 1)", 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			env := object.NewEnvironment()
+			env.Set("x", &object.Number{Value: 10})
+			env.Set("k", &object.Number{Value: 10})
+
+			got, err := testEval(t, tt.input, env)
+			if err != nil {
+				t.Fatalf("Eval() error = %v", err)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Eval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// the integrand sees the program's definitions
+	program, err := parser.ParseProgram("f(t) = 3 * t^2; integrate(f(s), s, 0, 2)")
+	if err != nil {
+		t.Fatalf("ParseProgram() error = %v", err)
+	}
+	got, err := eval.Float(program, object.NewEnvironment())
+	if err != nil || math.Abs(got-8) > 1e-9 {
+		t.Errorf("Eval() = %v, %v, want 8", got, err)
+	}
+}
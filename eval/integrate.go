@@ -0,0 +1,62 @@
+package eval
+
+import (
+	"math"
+
+	"github.com/ArtroxGabriel/sigma-parser/ast"
+	"github.com/ArtroxGabriel/sigma-parser/object"
+	"github.com/ArtroxGabriel/sigma-parser/quadrature"
+)
+
+// evalIntegral evaluates integrate(integrand, x, a, b) by numerical
+// quadrature, binding x in a scope of its own at every point. The integrand
+// is computed in float64 whatever the mode, since quadrature evaluates it
+// at thousands of points and only reaches float64 accuracy anyway.
+func (e *Evaluator) evalIntegral(node *ast.FunctionCall, env *object.Environment) (object.Object, error) {
+	integrand, x, lo, hi, _ := node.Integral()
+	name := node.Function.(*ast.Identifier)
+
+	a, err := e.number(lo, env)
+	if err != nil {
+		return nil, err
+	}
+	b, err := e.number(hi, env)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := object.NewEnvironment()
+	if env != nil {
+		scope = object.NewEnclosedEnvironment(env)
+	}
+
+	mode := e.mode
+	if mode == Rational || mode == BigFloat {
+		e.mode = Real
+	}
+	var failure error
+	r := quadrature.Integrate(func(v float64) float64 {
+		if failure != nil {
+			return math.NaN()
+		}
+		scope.Set(x.Value, e.fromFloat(v))
+		y, err := e.number(integrand, scope)
+		if err != nil {
+			failure = err
+			return math.NaN()
+		}
+		return y
+	}, a, b)
+	e.mode = mode
+
+	if failure != nil {
+		return nil, failure
+	}
+	switch {
+	case math.IsNaN(r.Value) || math.IsInf(r.Value, 0):
+		return nil, newError(name.Token, "%s has no finite value", node)
+	case !r.Converged:
+		return nil, newError(name.Token, "%s did not converge: estimated error %g", node, r.Error)
+	}
+	return e.fromFloat(r.Value), nil
+}
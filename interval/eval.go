@@ -0,0 +1,315 @@
+package interval
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/ArtroxGabriel/sigma-parser/ast"
+	"github.com/ArtroxGabriel/sigma-parser/builtin"
+	"github.com/ArtroxGabriel/sigma-parser/eval"
+	"github.com/ArtroxGabriel/sigma-parser/token"
+)
+
+// functions maps the built-in functions to their interval extensions
+var functions = map[string]func(Interval) Interval{
+	"sin":  Sin,
+	"cos":  Cos,
+	"tan":  Tan,
+	"asin": Asin,
+	"acos": Acos,
+	"atan": Atan,
+	"sinh": Sinh,
+	"cosh": Cosh,
+	"tanh": Tanh,
+	"sqrt": Sqrt,
+	"exp":  Exp,
+	"ln":   Log,
+	"log":  Log10,
+	"log2": Log2,
+	"abs":  Abs,
+	"re":   func(x Interval) Interval { return x },
+	"conj": func(x Interval) Interval { return x },
+	"im":   func(Interval) Interval { return Point(0) },
+	"arg":  Arg,
+}
+
+// truth is the value of a condition over intervals, which may hold for some
+// of their values and not for others
+type truth int
+
+const (
+	no truth = iota
+	yes
+	maybe
+)
+
+func (t truth) not() truth {
+	switch t {
+	case yes:
+		return no
+	case no:
+		return yes
+	}
+	return maybe
+}
+
+// Eval returns an interval that holds every value of node when each variable
+// ranges over its interval in vars. Literals and constants without an exact
+// float64 are widened to hold their true value. Where a condition holds for
+// some values and not for others, both branches are taken.
+func Eval(node ast.Node, vars map[string]Interval) (Interval, error) {
+	if fn, ok := node.(*ast.Function); ok {
+		if fn.Expression == nil {
+			return Empty(), &eval.Error{Msg: "empty expression"}
+		}
+		node = fn.Expression
+	}
+
+	exp, ok := node.(ast.Expression)
+	if !ok {
+		return Empty(), &eval.Error{Msg: fmt.Sprintf("cannot evaluate %T over intervals", node)}
+	}
+	return (&evaluator{vars: vars}).number(exp)
+}
+
+type evaluator struct {
+	vars map[string]Interval
+}
+
+func newError(tok token.Token, format string, args ...any) *eval.Error {
+	return &eval.Error{Pos: tok.Pos, End: tok.End(), Msg: fmt.Sprintf(format, args...)}
+}
+
+func (ev *evaluator) number(node ast.Expression) (Interval, error) {
+	switch node := node.(type) {
+	case *ast.NumberLiteral:
+		if node.IsImaginary() {
+			return Empty(), newError(node.Token, "imaginary number %s has no interval", node)
+		}
+		return literal(node), nil
+
+	case *ast.Constant:
+		return outwardInterval(node.Value), nil
+
+	case *ast.Identifier:
+		if x, ok := ev.vars[node.Value]; ok {
+			return x, nil
+		}
+		if v, ok := builtin.LookupConstant(node.Value); ok {
+			return outwardInterval(v), nil
+		}
+		return Empty(), newError(node.Token, "identifier not found: %s", node.Value)
+
+	case *ast.PrefixExpression:
+		if node.Operator == "!" {
+			return Empty(), newError(node.Token, "expected a number, got a condition")
+		}
+		right, err := ev.number(node.Right)
+		if err != nil {
+			return Empty(), err
+		}
+		if node.Operator == "-" {
+			return right.Neg(), nil
+		}
+		return right, nil
+
+	case *ast.PostfixExpression:
+		left, err := ev.number(node.Left)
+		if err != nil {
+			return Empty(), err
+		}
+		if node.Operator == "!" {
+			return Factorial(left), nil
+		}
+		return left.Div(Point(100)), nil
+
+	case *ast.InfixExpression:
+		return ev.infix(node)
+
+	case *ast.ConditionalExpression:
+		return ev.conditional(node)
+
+	case *ast.FunctionCall:
+		return ev.call(node)
+	}
+
+	return Empty(), &eval.Error{Msg: fmt.Sprintf("cannot evaluate %T over intervals", node)}
+}
+
+// literal converts the text of node, widening it unless the float64 is
+// exact, as for 0.5 but not 0.1.
+func literal(node *ast.NumberLiteral) Interval {
+	v := node.Value
+	exact, ok := new(big.Rat).SetString(node.Token.Literal)
+	if !ok || math.IsInf(v, 0) {
+		return outwardInterval(v)
+	}
+	switch new(big.Rat).SetFloat64(v).Cmp(exact) {
+	case 1:
+		return Interval{math.Nextafter(v, math.Inf(-1)), v}
+	case -1:
+		return Interval{v, math.Nextafter(v, math.Inf(1))}
+	}
+	return Point(v)
+}
+
+func outwardInterval(v float64) Interval {
+	lo, hi := outward(v)
+	return Interval{lo, hi}
+}
+
+func (ev *evaluator) infix(node *ast.InfixExpression) (Interval, error) {
+	switch node.Operator {
+	case "+", "-", "*", "/", "^":
+	default:
+		return Empty(), newError(node.Token, "expected a number, got a condition")
+	}
+
+	left, err := ev.number(node.Left)
+	if err != nil {
+		return Empty(), err
+	}
+	right, err := ev.number(node.Right)
+	if err != nil {
+		return Empty(), err
+	}
+
+	switch node.Operator {
+	case "+":
+		return left.Add(right), nil
+	case "-":
+		return left.Sub(right), nil
+	case "*":
+		return left.Mul(right), nil
+	case "/":
+		return left.Div(right), nil
+	}
+	return left.Pow(right), nil
+}
+
+func (ev *evaluator) conditional(node *ast.ConditionalExpression) (Interval, error) {
+	cond, err := ev.condition(node.Condition)
+	if err != nil {
+		return Empty(), err
+	}
+
+	var result Interval = Empty()
+	if cond != no {
+		if result, err = ev.number(node.Consequence); err != nil {
+			return Empty(), err
+		}
+	}
+	if cond != yes && node.Alternative != nil {
+		alt, err := ev.number(node.Alternative)
+		if err != nil {
+			return Empty(), err
+		}
+		result = result.Hull(alt)
+	}
+	return result, nil
+}
+
+func (ev *evaluator) condition(node ast.Expression) (truth, error) {
+	switch node := node.(type) {
+	case *ast.PrefixExpression:
+		if node.Operator == "!" {
+			t, err := ev.condition(node.Right)
+			return t.not(), err
+		}
+	case *ast.InfixExpression:
+		switch node.Operator {
+		case "&&", "||":
+			return ev.logical(node)
+		case "<", "<=", ">", ">=", "==", "!=":
+			left, err := ev.number(node.Left)
+			if err != nil {
+				return maybe, err
+			}
+			right, err := ev.number(node.Right)
+			if err != nil {
+				return maybe, err
+			}
+			return compare(node.Operator, left, right), nil
+		}
+	}
+	return maybe, newError(ast.TokenOf(node), "expected a condition, got a number")
+}
+
+// logical combines conditions in Kleene's three-valued logic, skipping the
+// right operand when the left one decides the result.
+func (ev *evaluator) logical(node *ast.InfixExpression) (truth, error) {
+	decisive := no
+	if node.Operator == "||" {
+		decisive = yes
+	}
+
+	left, err := ev.condition(node.Left)
+	if err != nil || left == decisive {
+		return left, err
+	}
+	right, err := ev.condition(node.Right)
+	if err != nil || right == decisive {
+		return right, err
+	}
+	if left == maybe || right == maybe {
+		return maybe, nil
+	}
+	return left, nil
+}
+
+// compare decides l op r for every pair of values in l and r, if it can.
+func compare(op string, l, r Interval) truth {
+	if l.IsEmpty() || r.IsEmpty() {
+		return maybe
+	}
+
+	switch op {
+	case ">":
+		return compare("<", r, l)
+	case ">=":
+		return compare("<=", r, l)
+	case "!=":
+		return compare("==", l, r).not()
+	}
+
+	var always, never bool
+	switch op {
+	case "<":
+		always, never = l.Hi < r.Lo, l.Lo >= r.Hi
+	case "<=":
+		always, never = l.Hi <= r.Lo, l.Lo > r.Hi
+	case "==":
+		always = l.Lo == l.Hi && r.Lo == r.Hi && l.Lo == r.Lo
+		never = l.Hi < r.Lo || r.Hi < l.Lo
+	}
+
+	switch {
+	case always:
+		return yes
+	case never:
+		return no
+	}
+	return maybe
+}
+
+func (ev *evaluator) call(node *ast.FunctionCall) (Interval, error) {
+	ident, ok := node.Function.(*ast.Identifier)
+	if !ok {
+		return Empty(), newError(node.Token, "not a function: %s", node.Function)
+	}
+	fn, ok := functions[ident.Value]
+	if !ok {
+		return Empty(), newError(ident.Token, "unknown function: %s", ident.Value)
+	}
+	if len(node.Arguments) != 1 {
+		return Empty(), newError(ident.Token,
+			"wrong number of arguments to %s: want 1, got %d", ident.Value, len(node.Arguments))
+	}
+
+	arg, err := ev.number(node.Arguments[0])
+	if err != nil {
+		return Empty(), err
+	}
+	return fn(arg), nil
+}
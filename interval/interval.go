@@ -0,0 +1,536 @@
+// Package interval evaluates expressions over intervals. The result is
+// guaranteed to contain every value the expression takes when its variables
+// range over their intervals, although it may be wider than the true range
+// when a variable occurs more than once, as in x - x.
+package interval
+
+import (
+	"math"
+	"strconv"
+)
+
+// Interval is the closed set of reals [Lo, Hi]. The bounds may be infinite;
+// an interval with NaN bounds is empty.
+type Interval struct {
+	Lo, Hi float64
+}
+
+// New returns [lo, hi], which is empty if lo > hi.
+func New(lo, hi float64) Interval {
+	if lo > hi || math.IsNaN(lo) || math.IsNaN(hi) {
+		return Empty()
+	}
+	return Interval{lo, hi}
+}
+
+// Point returns the interval holding only x.
+func Point(x float64) Interval { return New(x, x) }
+
+// Entire returns the whole real line.
+func Entire() Interval { return Interval{math.Inf(-1), math.Inf(1)} }
+
+// Empty returns the empty interval.
+func Empty() Interval { return Interval{math.NaN(), math.NaN()} }
+
+// IsEmpty reports whether x holds no value.
+func (x Interval) IsEmpty() bool { return math.IsNaN(x.Lo) || math.IsNaN(x.Hi) }
+
+// Contains reports whether v lies in x.
+func (x Interval) Contains(v float64) bool { return x.Lo <= v && v <= x.Hi }
+
+// Hull returns the smallest interval containing both x and y.
+func (x Interval) Hull(y Interval) Interval {
+	switch {
+	case x.IsEmpty():
+		return y
+	case y.IsEmpty():
+		return x
+	}
+	return Interval{math.Min(x.Lo, y.Lo), math.Max(x.Hi, y.Hi)}
+}
+
+func (x Interval) String() string {
+	if x.IsEmpty() {
+		return "[empty]"
+	}
+	return "[" + format(x.Lo) + ", " + format(x.Hi) + "]"
+}
+
+func format(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+
+// Neg returns -x.
+func (x Interval) Neg() Interval {
+	if x.IsEmpty() {
+		return x
+	}
+	return Interval{-x.Hi, -x.Lo}
+}
+
+// Add returns x + y.
+func (x Interval) Add(y Interval) Interval {
+	if x.IsEmpty() || y.IsEmpty() {
+		return Empty()
+	}
+	lo, _ := add(x.Lo, y.Lo)
+	_, hi := add(x.Hi, y.Hi)
+	return Interval{lo, hi}
+}
+
+// Sub returns x - y.
+func (x Interval) Sub(y Interval) Interval { return x.Add(y.Neg()) }
+
+// Mul returns x · y.
+func (x Interval) Mul(y Interval) Interval {
+	if x.IsEmpty() || y.IsEmpty() {
+		return Empty()
+	}
+	return corners(x, y, mul)
+}
+
+// Div returns x / y. Where y contains zero the quotient is unbounded; since
+// an Interval cannot hold the two pieces of [1, 2] / [-1, 1], Div returns
+// their hull. DivParts returns the pieces.
+func (x Interval) Div(y Interval) Interval {
+	a, b := x.DivParts(y)
+	return a.Hull(b)
+}
+
+// DivParts returns x / y as the union of at most two intervals; the second
+// is empty unless y has zero strictly inside it.
+func (x Interval) DivParts(y Interval) (Interval, Interval) {
+	switch {
+	case x.IsEmpty() || y.IsEmpty() || (y.Lo == 0 && y.Hi == 0):
+		return Empty(), Empty()
+	case !y.Contains(0):
+		return corners(x, y, quo), Empty()
+	case x.Contains(0):
+		return Entire(), Empty()
+	}
+
+	inf := math.Inf(1)
+	switch {
+	case y.Lo == 0 && x.Hi < 0:
+		_, hi := quo(x.Hi, y.Hi)
+		return Interval{-inf, hi}, Empty()
+	case y.Lo == 0:
+		lo, _ := quo(x.Lo, y.Hi)
+		return Interval{lo, inf}, Empty()
+	case y.Hi == 0 && x.Hi < 0:
+		lo, _ := quo(x.Hi, y.Lo)
+		return Interval{lo, inf}, Empty()
+	case y.Hi == 0:
+		_, hi := quo(x.Lo, y.Lo)
+		return Interval{-inf, hi}, Empty()
+	case x.Hi < 0:
+		_, hi1 := quo(x.Hi, y.Hi)
+		lo2, _ := quo(x.Hi, y.Lo)
+		return Interval{-inf, hi1}, Interval{lo2, inf}
+	default:
+		_, hi1 := quo(x.Lo, y.Lo)
+		lo2, _ := quo(x.Lo, y.Hi)
+		return Interval{-inf, hi1}, Interval{lo2, inf}
+	}
+}
+
+// corners applies a rounded operation to the four pairs of bounds and
+// returns the hull of the results.
+func corners(x, y Interval, op func(a, b float64) (lo, hi float64)) Interval {
+	r := Interval{math.Inf(1), math.Inf(-1)}
+	for _, a := range [2]float64{x.Lo, x.Hi} {
+		for _, b := range [2]float64{y.Lo, y.Hi} {
+			lo, hi := op(a, b)
+			r.Lo, r.Hi = math.Min(r.Lo, lo), math.Max(r.Hi, hi)
+		}
+	}
+	return r
+}
+
+// Pow returns x^y. An integer exponent allows any base, with even powers
+// non-negative; otherwise negative bases only have the powers of the
+// integers in y.
+func (x Interval) Pow(y Interval) Interval {
+	if x.IsEmpty() || y.IsEmpty() {
+		return Empty()
+	}
+	if y.Lo == y.Hi && y.Lo == math.Trunc(y.Lo) && math.Abs(y.Lo) <= 1<<31 {
+		return x.powInt(int(y.Lo))
+	}
+
+	r := Empty()
+	if neg := x.intersect(-inf, 0); !neg.IsEmpty() && neg.Lo < 0 {
+		first, last := math.Ceil(y.Lo), math.Floor(y.Hi)
+		switch {
+		case last-first > maxIntPowers || math.Abs(first) > 1<<31 || math.Abs(last) > 1<<31:
+			return Entire()
+		case first <= last:
+			for n := int(first); n <= int(last); n++ {
+				r = r.Hull(neg.powInt(n))
+			}
+		}
+	}
+
+	x = x.intersect(0, math.Inf(1))
+	if x.IsEmpty() {
+		return r
+	}
+	// x^y is monotonic in each argument for x >= 0, so it takes its
+	// extremes at the corners
+	return r.Hull(clamp(corners(x, y, func(a, b float64) (float64, float64) {
+		v := math.Pow(a, b)
+		return widen(v, v)
+	}), 0, math.Inf(1)))
+}
+
+// maxIntPowers is how many integer exponents Pow takes the hull of for a
+// negative base; past it the result is the entire line.
+const maxIntPowers = 64
+
+func (x Interval) powInt(n int) Interval {
+	switch {
+	case n == 0:
+		return Point(1)
+	case n < 0:
+		return Point(1).Div(x.powInt(-n))
+	}
+
+	pow := func(a float64) (float64, float64) {
+		v := math.Pow(a, float64(n))
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 && a == math.Trunc(a) {
+			return v, v // exact for small integers
+		}
+		return widen(v, v)
+	}
+
+	switch {
+	case n%2 == 1 || x.Lo >= 0:
+		lo, _ := pow(x.Lo)
+		_, hi := pow(x.Hi)
+		return Interval{lo, hi}
+	case x.Hi <= 0:
+		lo, _ := pow(x.Hi)
+		_, hi := pow(x.Lo)
+		return Interval{lo, hi}
+	}
+	_, hi := pow(math.Max(-x.Lo, x.Hi))
+	return Interval{0, hi}
+}
+
+// intersect returns the part of x within [lo, hi], the domain of a function.
+func (x Interval) intersect(lo, hi float64) Interval {
+	if x.IsEmpty() {
+		return x
+	}
+	return New(math.Max(x.Lo, lo), math.Min(x.Hi, hi))
+}
+
+// clamp limits the bounds of x to the range [lo, hi] of a function, which
+// widening may have crossed.
+func clamp(x Interval, lo, hi float64) Interval {
+	if x.IsEmpty() {
+		return x
+	}
+	return Interval{math.Max(x.Lo, lo), math.Min(x.Hi, hi)}
+}
+
+// The arithmetic below returns a result rounded toward -Inf and toward
+// +Inf. The error of the rounded-to-nearest result is computed exactly, so
+// exact results are not widened.
+
+func add(a, b float64) (lo, hi float64) {
+	s := a + b
+	if math.IsInf(s, 0) || math.IsNaN(s) {
+		return overflow(s, math.IsInf(a, 0) || math.IsInf(b, 0))
+	}
+	// Knuth's TwoSum
+	bb := s - a
+	err := (a - (s - bb)) + (b - bb)
+	return directed(s, err)
+}
+
+func mul(a, b float64) (lo, hi float64) {
+	if a == 0 || b == 0 {
+		return 0, 0 // including 0 · Inf, whose bound is 0
+	}
+	p := a * b
+	switch {
+	case math.IsInf(p, 0):
+		return overflow(p, math.IsInf(a, 0) || math.IsInf(b, 0))
+	case math.Abs(p) < tiny:
+		return outward(p)
+	}
+	return directed(p, math.FMA(a, b, -p))
+}
+
+func quo(a, b float64) (lo, hi float64) {
+	switch {
+	case math.IsInf(a, 0) && math.IsInf(b, 0):
+		// a bound of ∞/∞ may approach any value between 0 and ∞
+		if (a > 0) == (b > 0) {
+			return 0, math.Inf(1)
+		}
+		return math.Inf(-1), 0
+	case math.IsInf(b, 0):
+		return 0, 0
+	}
+	q := a / b
+	switch {
+	case math.IsInf(q, 0):
+		return overflow(q, math.IsInf(a, 0))
+	case math.Abs(q) < tiny || math.Abs(a) < tiny:
+		return outward(q)
+	}
+	// a/b - q = (a - q·b) / b, and a - q·b is exact
+	r := math.FMA(-q, b, a)
+	if b < 0 {
+		r = -r
+	}
+	return directed(q, r)
+}
+
+// below tiny the residual computed with FMA may underflow and be inexact
+const tiny = 0x1p-960
+
+// outward returns the neighbours of v, which hold any value that rounds to v.
+func outward(v float64) (lo, hi float64) {
+	return math.Nextafter(v, math.Inf(-1)), math.Nextafter(v, math.Inf(1))
+}
+
+// directed rounds v, whose exact value is v + err, down and up.
+func directed(v, err float64) (lo, hi float64) {
+	switch {
+	case err > 0:
+		return v, math.Nextafter(v, math.Inf(1))
+	case err < 0:
+		return math.Nextafter(v, math.Inf(-1)), v
+	}
+	return v, v
+}
+
+// overflow bounds a result that rounded to an infinity. Unless an operand
+// was infinite the exact result is finite, beyond the largest float64.
+func overflow(v float64, exact bool) (lo, hi float64) {
+	if exact || math.IsNaN(v) {
+		return v, v
+	}
+	if v > 0 {
+		return math.MaxFloat64, v
+	}
+	return v, -math.MaxFloat64
+}
+
+// widen moves lo and hi out by two ulps, more than the error of the math
+// package's elementary functions.
+func widen(lo, hi float64) (float64, float64) {
+	for range 2 {
+		lo = math.Nextafter(lo, math.Inf(-1))
+		hi = math.Nextafter(hi, math.Inf(1))
+	}
+	return lo, hi
+}
+
+// increasing applies a function that is increasing on the domain [lo, hi]
+// and whose range is [min, max].
+func increasing(x Interval, f func(float64) float64, lo, hi, min, max float64) Interval {
+	x = x.intersect(lo, hi)
+	if x.IsEmpty() {
+		return x
+	}
+	a, _ := widen(f(x.Lo), f(x.Lo))
+	_, b := widen(f(x.Hi), f(x.Hi))
+	return clamp(Interval{a, b}, min, max)
+}
+
+var inf = math.Inf(1)
+
+// Sqrt returns the square root of the non-negative part of x.
+func Sqrt(x Interval) Interval {
+	x = x.intersect(0, inf)
+	if x.IsEmpty() {
+		return x
+	}
+	lo, _ := sqrt(x.Lo)
+	_, hi := sqrt(x.Hi)
+	return Interval{lo, hi}
+}
+
+func sqrt(a float64) (lo, hi float64) {
+	s := math.Sqrt(a)
+	switch {
+	case math.IsInf(s, 0) || a == 0:
+		return s, s
+	case a < tiny:
+		return outward(s)
+	}
+	return directed(s, math.FMA(-s, s, a))
+}
+
+// Exp returns e^x.
+func Exp(x Interval) Interval { return increasing(x, math.Exp, -inf, inf, 0, inf) }
+
+// Log returns the natural logarithm of the positive part of x.
+func Log(x Interval) Interval { return increasing(x, math.Log, 0, inf, -inf, inf) }
+
+// Log10 returns the decimal logarithm of the positive part of x.
+func Log10(x Interval) Interval { return increasing(x, math.Log10, 0, inf, -inf, inf) }
+
+// Log2 returns the binary logarithm of the positive part of x.
+func Log2(x Interval) Interval { return increasing(x, math.Log2, 0, inf, -inf, inf) }
+
+// Asin returns the arcsine of the part of x within [-1, 1].
+func Asin(x Interval) Interval {
+	return increasing(x, math.Asin, -1, 1, -math.Pi/2-1e-15, math.Pi/2+1e-15)
+}
+
+// Acos returns the arccosine of the part of x within [-1, 1].
+func Acos(x Interval) Interval {
+	return increasing(x.Neg(), func(v float64) float64 { return math.Acos(-v) }, -1, 1, 0, math.Pi+1e-15)
+}
+
+// Atan returns the arctangent of x.
+func Atan(x Interval) Interval {
+	return increasing(x, math.Atan, -inf, inf, -math.Pi/2-1e-15, math.Pi/2+1e-15)
+}
+
+// Sinh returns the hyperbolic sine of x.
+func Sinh(x Interval) Interval { return increasing(x, math.Sinh, -inf, inf, -inf, inf) }
+
+// Tanh returns the hyperbolic tangent of x.
+func Tanh(x Interval) Interval { return increasing(x, math.Tanh, -inf, inf, -1, 1) }
+
+// Cosh returns the hyperbolic cosine of x, which is least at 0.
+func Cosh(x Interval) Interval { return clamp(even(x, math.Cosh), 1, inf) }
+
+// Abs returns the absolute value of x.
+func Abs(x Interval) Interval {
+	if x.IsEmpty() {
+		return x
+	}
+	switch {
+	case x.Lo >= 0:
+		return x
+	case x.Hi <= 0:
+		return x.Neg()
+	}
+	return Interval{0, math.Max(-x.Lo, x.Hi)}
+}
+
+// even applies a function that is even and increasing for positive
+// arguments.
+func even(x Interval, f func(float64) float64) Interval {
+	a := Abs(x)
+	if a.IsEmpty() {
+		return a
+	}
+	lo, _ := widen(f(a.Lo), f(a.Lo))
+	_, hi := widen(f(a.Hi), f(a.Hi))
+	return Interval{lo, hi}
+}
+
+// Sin returns the sine of x. It is monotonic between its extremes at
+// π/2 + kπ, so the result is the hull of the values at the bounds and at
+// the extremes inside x.
+func Sin(x Interval) Interval { return periodic(x, math.Sin, 0.5) }
+
+// Cos returns the cosine of x, whose extremes are at kπ.
+func Cos(x Interval) Interval { return periodic(x, math.Cos, 0) }
+
+// periodic evaluates sin or cos, whose maxima lie at (2k + offset)π and
+// minima at (2k + 1 + offset)π.
+func periodic(x Interval, f func(float64) float64, offset float64) Interval {
+	if x.IsEmpty() {
+		return x
+	}
+	if math.IsInf(x.Lo, 0) || math.IsInf(x.Hi, 0) || x.Hi-x.Lo >= 2*math.Pi {
+		return Interval{-1, 1}
+	}
+
+	// extremes are in x when kπ is, with k counted from offset; the slack
+	// admits extremes that rounding may have put just outside. Past
+	// maxReduced, x/π has no fractional bits left to place them by.
+	first, last := extremes(x, offset)
+	if last-first >= 1 || math.Max(math.Abs(x.Lo), math.Abs(x.Hi)) > maxReduced {
+		return Interval{-1, 1}
+	}
+
+	lo, hi := widen(math.Min(f(x.Lo), f(x.Hi)), math.Max(f(x.Lo), f(x.Hi)))
+	for k := first; k <= last; k++ {
+		if math.Mod(k, 2) == 0 {
+			hi = 1
+		} else {
+			lo = -1
+		}
+	}
+	return clamp(Interval{lo, hi}, -1, 1)
+}
+
+// maxReduced is the magnitude beyond which x/π is an integer in float64.
+const maxReduced = 1 << 52 * math.Pi
+
+// extremes returns the range of k with (k + offset)π in x, widened by the
+// rounding error of x/π.
+func extremes(x Interval, offset float64) (first, last float64) {
+	slack := 1e-12 * (1 + math.Max(math.Abs(x.Lo), math.Abs(x.Hi)))
+	return math.Ceil(x.Lo/math.Pi - offset - slack), math.Floor(x.Hi/math.Pi - offset + slack)
+}
+
+// Tan returns the tangent of x, which is unbounded if x holds a pole at
+// π/2 + kπ.
+func Tan(x Interval) Interval {
+	if x.IsEmpty() {
+		return x
+	}
+	if first, last := extremes(x, 0.5); first <= last || math.Max(math.Abs(x.Lo), math.Abs(x.Hi)) > maxReduced {
+		return Entire()
+	}
+	lo, _ := widen(math.Tan(x.Lo), math.Tan(x.Lo))
+	_, hi := widen(math.Tan(x.Hi), math.Tan(x.Hi))
+	return Interval{lo, hi}
+}
+
+// argmin of x! and its value, a little below the true minimum
+const (
+	factorialArgMin = 0.46163214496836234126
+	factorialMin    = 0.8856031944108886
+)
+
+// Factorial returns Γ(x + 1). It decreases up to factorialArgMin and
+// increases after; below 0 it has poles at the negative integers, so only a
+// single point there has a finite enclosure.
+func Factorial(x Interval) Interval {
+	f := func(v float64) float64 { return math.Gamma(v + 1) }
+	if !x.IsEmpty() && x.Lo < 0 {
+		if v := f(x.Lo); x.Lo == x.Hi && !math.IsInf(v, 0) && !math.IsNaN(v) {
+			return New(widen(v, v))
+		}
+		return Entire()
+	}
+	if x.IsEmpty() {
+		return x
+	}
+
+	switch {
+	case x.Lo >= factorialArgMin:
+		return increasing(x, f, 0, inf, 0, inf)
+	case x.Hi <= factorialArgMin:
+		lo, _ := widen(f(x.Hi), f(x.Hi))
+		_, hi := widen(f(x.Lo), f(x.Lo))
+		return Interval{lo, hi}
+	}
+	_, hi := widen(math.Max(f(x.Lo), f(x.Hi)), math.Max(f(x.Lo), f(x.Hi)))
+	return Interval{factorialMin, hi}
+}
+
+// Arg returns the phase of the reals in x: π for negative numbers, else 0.
+func Arg(x Interval) Interval {
+	if x.IsEmpty() {
+		return x
+	}
+	pi := Interval{math.Pi, math.Nextafter(math.Pi, 4)}
+	switch {
+	case x.Lo >= 0:
+		return Point(0)
+	case x.Hi < 0:
+		return pi
+	}
+	return Point(0).Hull(pi)
+}
@@ -0,0 +1,312 @@
+package interval_test
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/ArtroxGabriel/sigma-parser/eval"
+	"github.com/ArtroxGabriel/sigma-parser/interval"
+	"github.com/ArtroxGabriel/sigma-parser/object"
+	"github.com/ArtroxGabriel/sigma-parser/parser"
+)
+
+var inf = math.Inf(1)
+
+func iv(lo, hi float64) interval.Interval { return interval.New(lo, hi) }
+
+func same(a, b interval.Interval) bool {
+	if a.IsEmpty() || b.IsEmpty() {
+		return a.IsEmpty() && b.IsEmpty()
+	}
+	return a == b
+}
+
+func TestArithmetic(t *testing.T) {
+	tests := []struct {
+		name string
+		got  interval.Interval
+		want interval.Interval
+	}{
+		{"add exact", iv(1, 2).Add(iv(3, 4)), iv(4, 6)},
+		{"sub", iv(1, 2).Sub(iv(3, 4)), iv(-3, -1)},
+		{"mul signs", iv(-1, 2).Mul(iv(-3, 4)), iv(-6, 8)},
+		{"mul infinite", iv(0, inf).Mul(iv(1, 2)), iv(0, inf)},
+		{"div", iv(1, 2).Div(iv(4, 8)), iv(0.125, 0.5)},
+		{"div by zero width", iv(1, 2).Div(iv(0, 0)), interval.Empty()},
+		{"div positive over zero", iv(1, 2).Div(iv(0, 1)), iv(1, inf)},
+		{"div negative over zero", iv(1, 2).Div(iv(-1, 0)), iv(-inf, -1)},
+		{"div across zero", iv(1, 2).Div(iv(-1, 1)), interval.Entire()},
+		{"div zero by zero", iv(-1, 1).Div(iv(-1, 1)), interval.Entire()},
+		{"even power", iv(-2, 3).Pow(interval.Point(2)), iv(0, 9)},
+		{"even power negative", iv(-3, -2).Pow(interval.Point(2)), iv(4, 9)},
+		{"odd power", iv(-2, 3).Pow(interval.Point(3)), iv(-8, 27)},
+		{"negative power", iv(2, 4).Pow(interval.Point(-1)), iv(0.25, 0.5)},
+		{"negative even power across zero", iv(-1, 2).Pow(interval.Point(-2)), iv(0.25, inf)},
+		{"zero power", iv(-1, 2).Pow(interval.Point(0)), iv(1, 1)},
+		{"abs", interval.Abs(iv(-3, 2)), iv(0, 3)},
+		{"sqrt exact", interval.Sqrt(iv(4, 9)), iv(2, 3)},
+		{"sqrt clips domain", interval.Sqrt(iv(-4, 9)), iv(0, 3)},
+		{"sqrt outside domain", interval.Sqrt(iv(-4, -1)), interval.Empty()},
+		{"empty stays empty", interval.Empty().Add(iv(1, 2)), interval.Empty()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !same(tt.got, tt.want) {
+				t.Errorf("got %s, want %s", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+// TestOutwardRounding checks that inexact results are widened to hold the
+// true value rather than rounded to the nearest float64.
+func TestOutwardRounding(t *testing.T) {
+	tenth := iv(0.1, 0.1)
+	sum := tenth.Add(interval.Point(0.2))
+	if sum.Lo == sum.Hi || !sum.Contains(0.1+0.2) {
+		t.Errorf("0.1 + 0.2 = %s, want a widened interval around %v", sum, 0.1+0.2)
+	}
+
+	third := interval.Point(1).Div(interval.Point(3))
+	if third.Hi != math.Nextafter(third.Lo, inf) || !third.Contains(1.0/3) {
+		t.Errorf("1/3 = %s, want the two float64 around 1/3", third)
+	}
+
+	big := interval.Point(math.MaxFloat64).Add(interval.Point(math.MaxFloat64))
+	if big.Lo != math.MaxFloat64 || big.Hi != inf {
+		t.Errorf("max + max = %s, want [max, +Inf]", big)
+	}
+}
+
+func TestDivParts(t *testing.T) {
+	tests := []struct {
+		name  string
+		x, y  interval.Interval
+		wantA interval.Interval
+		wantB interval.Interval
+	}{
+		{"no zero", iv(1, 2), iv(2, 4), iv(0.25, 1), interval.Empty()},
+		{"zero inside", iv(1, 2), iv(-1, 4), iv(-inf, -1), iv(0.25, inf)},
+		{"zero inside negative", iv(-2, -1), iv(-1, 4), iv(-inf, -0.25), iv(1, inf)},
+		{"zero bound", iv(1, 2), iv(0, 4), iv(0.25, inf), interval.Empty()},
+		{"numerator holds zero", iv(-1, 2), iv(-1, 4), interval.Entire(), interval.Empty()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := tt.x.DivParts(tt.y)
+			if !same(a, tt.wantA) || !same(b, tt.wantB) {
+				t.Errorf("%s / %s = %s ∪ %s, want %s ∪ %s", tt.x, tt.y, a, b, tt.wantA, tt.wantB)
+			}
+		})
+	}
+}
+
+func TestPeriodic(t *testing.T) {
+	tests := []struct {
+		name   string
+		got    interval.Interval
+		lo, hi float64
+	}{
+		{"sin rising", interval.Sin(iv(0, 1)), 0, math.Sin(1)},
+		{"sin over maximum", interval.Sin(iv(1, 2)), math.Sin(1), 1},
+		{"sin over minimum", interval.Sin(iv(4, 5)), -1, math.Sin(4)},
+		{"sin full period", interval.Sin(iv(0, 7)), -1, 1},
+		{"cos over maximum", interval.Cos(iv(-1, 1)), math.Cos(1), 1},
+		{"cos falling", interval.Cos(iv(0.5, 3)), math.Cos(3), math.Cos(0.5)},
+		{"cos far away", interval.Cos(iv(1e6*math.Pi, 1e6*math.Pi+0.1)), math.Cos(1e6*math.Pi + 0.1), 1},
+		{"factorial", interval.Factorial(iv(0, 4)), 0.8856031944108886, 24},
+		{"sin huge", interval.Sin(iv(1e300, 1e300)), -1, 1},
+		{"cos huge", interval.Cos(iv(-1e300, 1e300)), -1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.got.Contains(tt.lo) || !tt.got.Contains(tt.hi) {
+				t.Errorf("got %s, want it to hold [%v, %v]", tt.got, tt.lo, tt.hi)
+			}
+			if tt.got.Lo < tt.lo-1e-6 || tt.got.Hi > tt.hi+1e-6 {
+				t.Errorf("got %s, want about [%v, %v]", tt.got, tt.lo, tt.hi)
+			}
+		})
+	}
+
+	if got := interval.Tan(iv(1, 2)); !same(got, interval.Entire()) {
+		t.Errorf("tan([1, 2]) = %s, want the entire line across the pole", got)
+	}
+	if got := interval.Tan(iv(1e300, 1e300)); !same(got, interval.Entire()) {
+		t.Errorf("tan([1e300, 1e300]) = %s, want the entire line", got)
+	}
+	if got := interval.Tan(iv(-1, 1)); got.Lo > math.Tan(-1) || got.Hi < math.Tan(1) || got.Hi > 2 {
+		t.Errorf("tan([-1, 1]) = %s, want about [%v, %v]", got, math.Tan(-1), math.Tan(1))
+	}
+}
+
+// TestNegativeDomain checks that values defined for negative arguments are
+// in the enclosure rather than dropped.
+func TestNegativeDomain(t *testing.T) {
+	tests := []struct {
+		name string
+		got  interval.Interval
+		want []float64
+	}{
+		{"factorial of -0.5", interval.Factorial(iv(-0.5, -0.5)), []float64{math.Sqrt(math.Pi)}},
+		{"factorial across poles", interval.Factorial(iv(-2, -1)), []float64{math.Gamma(-0.5), math.Gamma(-0.2)}},
+		{"integer powers of negatives", iv(-2, -1).Pow(iv(2, 3)), []float64{-8, 4, -1, 1}},
+		{"mixed bases", iv(-2, 3).Pow(iv(1, 3)), []float64{-8, 27, 0.5}},
+	}
+
+	for _, tt := range tests {
+		for _, v := range tt.want {
+			if !tt.got.Contains(v) {
+				t.Errorf("%s = %s, want it to hold %v", tt.name, tt.got, v)
+			}
+		}
+	}
+	if got := iv(-2, -1).Pow(iv(2.2, 2.8)); !got.IsEmpty() {
+		t.Errorf("[-2, -1]^[2.2, 2.8] = %s, want empty", got)
+	}
+}
+
+// TestContainment samples points of random intervals and checks that every
+// function value lies within the interval result.
+func TestContainment(t *testing.T) {
+	unary := map[string]func(interval.Interval) interval.Interval{
+		"sin": interval.Sin, "cos": interval.Cos, "exp": interval.Exp, "log": interval.Log,
+		"sqrt": interval.Sqrt, "atan": interval.Atan, "tanh": interval.Tanh, "cosh": interval.Cosh,
+		"factorial": interval.Factorial,
+	}
+	point := map[string]func(float64) float64{
+		"sin": math.Sin, "cos": math.Cos, "exp": math.Exp, "log": math.Log,
+		"sqrt": math.Sqrt, "atan": math.Atan, "tanh": math.Tanh, "cosh": math.Cosh,
+		"factorial": func(v float64) float64 { return math.Gamma(v + 1) },
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		a, b := r.Float64()*20-5, r.Float64()*20-5
+		x := iv(math.Min(a, b), math.Max(a, b))
+		for name, f := range unary {
+			y := f(x)
+			for j := 0; j <= 8; j++ {
+				v := math.Min(x.Lo+(x.Hi-x.Lo)*float64(j)/8, x.Hi)
+				if fv := point[name](v); !math.IsNaN(fv) && !y.Contains(fv) {
+					t.Fatalf("%s(%v) = %v, not in %s(%s) = %s", name, v, fv, name, x, y)
+				}
+			}
+		}
+	}
+}
+
+func TestEval(t *testing.T) {
+	vars := map[string]interval.Interval{"x": iv(-1, 2), "y": iv(1, 2)}
+
+	tests := []struct {
+		input string
+		want  interval.Interval
+	}{
+		{"x + y", iv(0, 4)},
+		{"x^2", iv(0, 4)},
+		{"x * x", iv(-2, 4)},
+		{"1 / y", iv(0.5, 1)},
+		{"-x", iv(-2, 1)},
+		{"50%", iv(0.5, 0.5)},
+		{"abs(x)", iv(0, 2)},
+		{"im(x)", iv(0, 0)},
+		{"x < 3 ? 1 : 2", iv(1, 1)},
+		{"x > 3 ? 1 : 2", iv(2, 2)},
+		{"x > 0 ? 1 : 2", iv(1, 2)},
+		{"y >= 1 && !(y > 2) ? y : z", iv(1, 2)},
+		{"x > 5 || y < 3 ? 1 : z", iv(1, 1)},
+		{"x == x ? 1 : 2", iv(1, 2)},
+		{"piecewise(y > 5, 1)", interval.Empty()},
+		{"piecewise(x < 0, -1, x < 1, 0)", iv(-1, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			function, err := parser.Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.input, err)
+			}
+			got, err := interval.Eval(function, vars)
+			if err != nil {
+				t.Fatalf("Eval(%q) error = %v", tt.input, err)
+			}
+			if !same(got, tt.want) {
+				t.Errorf("Eval(%q) = %s, want %s", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestEvalEnclosesPoints checks the interval result against the ordinary
+// evaluator at points of the input interval.
+func TestEvalEnclosesPoints(t *testing.T) {
+	inputs := []string{
+		"0.1 * x + 0.2",
+		"sin(x)^2 + cos(x)^2",
+		"exp(-x^2) / (1 + x^2)",
+		"x^3 - 3*x + pi",
+		"sqrt(abs(x)) * ln(2 + x)",
+		"x < 0.5 ? tan(x) : atan(x)",
+	}
+
+	x := iv(-1, 1.25)
+	for _, input := range inputs {
+		function, err := parser.Parse(input)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", input, err)
+		}
+		got, err := interval.Eval(function, map[string]interval.Interval{"x": x})
+		if err != nil {
+			t.Fatalf("Eval(%q) error = %v", input, err)
+		}
+
+		for j := 0; j <= 100; j++ {
+			v := math.Min(x.Lo+(x.Hi-x.Lo)*float64(j)/100, x.Hi)
+			env := object.NewEnvironment()
+			env.Set("x", &object.Number{Value: v})
+			obj, err := eval.New().Eval(function, env)
+			if err != nil {
+				t.Fatalf("eval %q at %v: %v", input, v, err)
+			}
+			if fv := obj.(*object.Number).Value; !got.Contains(fv) {
+				t.Errorf("%s at x = %v is %v, not in %s", input, v, fv, got)
+			}
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"z + 1", "1:1: identifier not found: z"},
+		{"x < 1", "1:3: expected a number, got a condition"},
+		{"x ? 1 : 2", "1:1: expected a condition, got a number"},
+		{"foo(x)", "1:1: unknown function: foo"},
+		{"2 * 3i", "1:5: imaginary number 3i has no interval"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			function, err := parser.Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.input, err)
+			}
+			_, err = interval.Eval(function, map[string]interval.Interval{"x": iv(0, 1)})
+			var evalErr *eval.Error
+			if !errors.As(err, &evalErr) {
+				t.Fatalf("Eval(%q) error = %v, want *eval.Error", tt.input, err)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("Eval(%q) error = %q, want %q", tt.input, err, tt.want)
+			}
+		})
+	}
+}
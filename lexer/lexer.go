@@ -1,25 +1,91 @@
 package lexer
 
-import "github.com/ArtroxGabriel/sigma-parser/token"
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ArtroxGabriel/sigma-parser/token"
+)
 
 // Lexer represents a lexical analyzer for tokenizing input strings.
 type Lexer struct {
-	input        string // The input string to be tokenized
-	position     int    // Current position in input (points to current char)
-	readPosition int    // Current reading position in input (after current char)
-	ch           byte   // Current character under examination
+	input        string   // The input string to be tokenized
+	position     int      // Current position in input (points to current char)
+	readPosition int      // Current reading position in input (after current char)
+	ch           byte     // Current character under examination
+	line         int      // Line of the current character, starting at 1
+	lineStart    int      // Offset of the first character of the current line
+	symbols      []string // Extra operator symbols, longest first
+}
+
+// Error reports a character that does not start any token.
+type Error struct {
+	Pos     token.Position
+	End     token.Position
+	Literal string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: illegal character %q", e.Pos, e.Literal)
+}
+
+// Check scans input and returns an *Error for its first illegal character.
+func Check(input string) error {
+	l := New(input)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		if tok.Type == token.ILLEGAL {
+			return &Error{Pos: tok.Pos, End: tok.End(), Literal: tok.Literal}
+		}
+	}
+	return nil
 }
 
 // New creates a new Lexer instance with the given input string.
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
 	l.readChar() // Initialize the lexer by reading the first character
 	return l
 }
 
+// Len returns the length of the input in bytes.
+func (l *Lexer) Len() int { return len(l.input) }
+
+// AddSymbol makes the lexer recognise symbol as a single token whose type is
+// the symbol itself, taking precedence over shorter symbols and the built-in
+// single character tokens. It is used by parsers configured with extra
+// operators such as ** or %.
+func (l *Lexer) AddSymbol(symbol string) {
+	for _, s := range l.symbols {
+		if s == symbol {
+			return
+		}
+	}
+	l.symbols = append(l.symbols, symbol)
+	sort.SliceStable(l.symbols, func(i, j int) bool { return len(l.symbols[i]) > len(l.symbols[j]) })
+}
+
+// readSymbol returns the longest added symbol starting at the current
+// character, or "" if none does.
+func (l *Lexer) readSymbol() string {
+	for _, s := range l.symbols {
+		if strings.HasPrefix(l.input[l.position:], s) {
+			for range len(s) {
+				l.readChar()
+			}
+			return s
+		}
+	}
+	return ""
+}
+
 // readChar advances the lexer to the next character in the input.
 // If the end of the input is reached, it sets the current character to 0.
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.lineStart = l.readPosition
+	}
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
@@ -41,6 +107,13 @@ func (l *Lexer) NextToken() token.Token {
 	var tok token.Token
 
 	l.skipWhitespace()
+	pos := l.pos()
+
+	if l.ch != 0 {
+		if sym := l.readSymbol(); sym != "" {
+			return token.Token{Type: token.TokenType(sym), Literal: sym, Pos: pos}
+		}
+	}
 
 	switch l.ch {
 	case '+':
@@ -57,6 +130,28 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.RPAREN, l.ch)
 	case '^':
 		tok = newToken(token.POWER, l.ch)
+	case ',':
+		tok = newToken(token.COMMA, l.ch)
+	case '!':
+		tok = l.twoCharToken('=', token.NOT_EQ, token.BANG)
+	case '%':
+		tok = newToken(token.PERCENT, l.ch)
+	case '<':
+		tok = l.twoCharToken('=', token.LE, token.LT)
+	case '>':
+		tok = l.twoCharToken('=', token.GE, token.GT)
+	case '=':
+		tok = l.twoCharToken('=', token.EQ, token.ASSIGN)
+	case '&':
+		tok = l.twoCharToken('&', token.AND, token.ILLEGAL)
+	case '|':
+		tok = l.twoCharToken('|', token.OR, token.BAR)
+	case '?':
+		tok = newToken(token.QUESTION, l.ch)
+	case ':':
+		tok = newToken(token.COLON, l.ch)
+	case ';':
+		tok = newToken(token.SEMICOLON, l.ch)
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
@@ -64,20 +159,56 @@ func (l *Lexer) NextToken() token.Token {
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.IDENT
+			tok.Pos = pos
 			return tok
 		} else if isDigit(l.ch) {
 			tok.Type = token.NUMBER
 			tok.Literal = l.readNumber()
+			if (l.ch == 'i' || l.ch == 'j') && !isLetter(l.peekChar()) && !isDigit(l.peekChar()) {
+				tok.Type = token.IMAG
+				tok.Literal += string(l.ch)
+				l.readChar()
+			}
+			tok.Pos = pos
 			return tok
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch)
 		}
 	}
+	tok.Pos = pos
 	l.readChar()
 
 	return tok
 }
 
+// twoCharToken returns a token of type two if the current character is
+// followed by next, consuming both, and a token of type one otherwise.
+func (l *Lexer) twoCharToken(next byte, two, one token.TokenType) token.Token {
+	if l.peekChar() == next {
+		ch := l.ch
+		l.readChar()
+		return token.Token{Type: two, Literal: string(ch) + string(l.ch)}
+	}
+	return newToken(one, l.ch)
+}
+
+// peekChar returns the character after the current one without consuming it.
+func (l *Lexer) peekChar() byte {
+	if l.readPosition >= len(l.input) {
+		return 0
+	}
+	return l.input[l.readPosition]
+}
+
+// pos returns the position of the current character.
+func (l *Lexer) pos() token.Position {
+	return token.Position{
+		Offset: l.position,
+		Line:   l.line,
+		Column: l.position - l.lineStart + 1,
+	}
+}
+
 // isDigit checks if the given character is a numeric digit (0-9).
 func isDigit(ch byte) bool {
 	return '0' <= ch && ch <= '9'
@@ -33,6 +33,9 @@ func TestNextToken_ReadOneToken(t *testing.T) {
 		{name: "E token", input: "e", want: token.Token{Type: token.IDENT, Literal: "e"}},
 		{name: "LPAREN token", input: "(", want: token.Token{Type: token.LPAREN, Literal: "("}},
 		{name: "RPAREN token", input: ")", want: token.Token{Type: token.RPAREN, Literal: ")"}},
+		{name: "COMMA token", input: ",", want: token.Token{Type: token.COMMA, Literal: ","}},
+		{name: "BANG token", input: "!", want: token.Token{Type: token.BANG, Literal: "!"}},
+		{name: "PERCENT token", input: "%", want: token.Token{Type: token.PERCENT, Literal: "%"}},
 		{name: "ACOS token", input: "acos", want: token.Token{Type: token.IDENT, Literal: "acos"}},
 		{name: "ATAN token", input: "atan", want: token.Token{Type: token.IDENT, Literal: "atan"}},
 		{name: "NUMBER token", input: "123", want: token.Token{Type: token.NUMBER, Literal: "123"}},
@@ -153,6 +156,70 @@ func TestNextToken_ReadExpression(t *testing.T) {
 				{Type: token.ILLEGAL, Literal: "@"},
 			},
 		},
+		{
+			input: "x <= 1 && y != 2 || !(z >= 3) ? a<b : c>d == e",
+			want: []token.Token{
+				{Type: token.IDENT, Literal: "x"},
+				{Type: token.LE, Literal: "<="},
+				{Type: token.NUMBER, Literal: "1"},
+				{Type: token.AND, Literal: "&&"},
+				{Type: token.IDENT, Literal: "y"},
+				{Type: token.NOT_EQ, Literal: "!="},
+				{Type: token.NUMBER, Literal: "2"},
+				{Type: token.OR, Literal: "||"},
+				{Type: token.BANG, Literal: "!"},
+				{Type: token.LPAREN, Literal: "("},
+				{Type: token.IDENT, Literal: "z"},
+				{Type: token.GE, Literal: ">="},
+				{Type: token.NUMBER, Literal: "3"},
+				{Type: token.RPAREN, Literal: ")"},
+				{Type: token.QUESTION, Literal: "?"},
+				{Type: token.IDENT, Literal: "a"},
+				{Type: token.LT, Literal: "<"},
+				{Type: token.IDENT, Literal: "b"},
+				{Type: token.COLON, Literal: ":"},
+				{Type: token.IDENT, Literal: "c"},
+				{Type: token.GT, Literal: ">"},
+				{Type: token.IDENT, Literal: "d"},
+				{Type: token.EQ, Literal: "=="},
+				{Type: token.IDENT, Literal: "e"},
+			},
+		},
+		{
+			input: "3+4i - 2.5j*in + 2 i",
+			want: []token.Token{
+				{Type: token.NUMBER, Literal: "3"},
+				{Type: token.PLUS, Literal: "+"},
+				{Type: token.IMAG, Literal: "4i"},
+				{Type: token.MINUS, Literal: "-"},
+				{Type: token.IMAG, Literal: "2.5j"},
+				{Type: token.TIMES, Literal: "*"},
+				{Type: token.IDENT, Literal: "in"},
+				{Type: token.PLUS, Literal: "+"},
+				{Type: token.NUMBER, Literal: "2"},
+				{Type: token.IDENT, Literal: "i"},
+			},
+		},
+		{
+			input: "2in",
+			want: []token.Token{
+				{Type: token.NUMBER, Literal: "2"},
+				{Type: token.IDENT, Literal: "in"},
+			},
+		},
+		{
+			input: "a = b & c | d;",
+			want: []token.Token{
+				{Type: token.IDENT, Literal: "a"},
+				{Type: token.ASSIGN, Literal: "="},
+				{Type: token.IDENT, Literal: "b"},
+				{Type: token.ILLEGAL, Literal: "&"},
+				{Type: token.IDENT, Literal: "c"},
+				{Type: token.BAR, Literal: "|"},
+				{Type: token.IDENT, Literal: "d"},
+				{Type: token.SEMICOLON, Literal: ";"},
+			},
+		},
 	}
 	for _, tt := range tests {
 		l := lexer.New(tt.input)
@@ -167,3 +234,49 @@ func TestNextToken_ReadExpression(t *testing.T) {
 		}
 	}
 }
+
+func TestNextToken_Position(t *testing.T) {
+	input := "x + 10\n  * sin(y)"
+	want := []token.Position{
+		{Offset: 0, Line: 1, Column: 1},
+		{Offset: 2, Line: 1, Column: 3},
+		{Offset: 4, Line: 1, Column: 5},
+		{Offset: 9, Line: 2, Column: 3},
+		{Offset: 11, Line: 2, Column: 5},
+		{Offset: 14, Line: 2, Column: 8},
+		{Offset: 15, Line: 2, Column: 9},
+		{Offset: 16, Line: 2, Column: 10},
+		{Offset: 17, Line: 2, Column: 11},
+	}
+
+	l := lexer.New(input)
+	for i, pos := range want {
+		got := l.NextToken()
+		if got.Pos != pos {
+			t.Errorf("token %d (%q): Pos = %+v, want %+v", i, got.Literal, got.Pos, pos)
+		}
+	}
+}
+
+func TestNextToken_AddSymbol(t *testing.T) {
+	l := lexer.New("a ** b * c % d")
+	l.AddSymbol("%")
+	l.AddSymbol("**")
+
+	want := []token.Token{
+		{Type: token.IDENT, Literal: "a"},
+		{Type: "**", Literal: "**"},
+		{Type: token.IDENT, Literal: "b"},
+		{Type: token.TIMES, Literal: "*"},
+		{Type: token.IDENT, Literal: "c"},
+		{Type: "%", Literal: "%"},
+		{Type: token.IDENT, Literal: "d"},
+		{Type: token.EOF, Literal: ""},
+	}
+	for _, w := range want {
+		got := l.NextToken()
+		if got.Type != w.Type || got.Literal != w.Literal {
+			t.Errorf("NextToken() = %v %q, want %v %q", got.Type, got.Literal, w.Type, w.Literal)
+		}
+	}
+}
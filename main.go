@@ -1,7 +1,11 @@
 package main
 
-import "fmt"
+import (
+	"os"
+
+	"github.com/ArtroxGabriel/sigma-parser/cli"
+)
 
 func main() {
-	fmt.Println("oi")
+	os.Exit(cli.Run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
 }
@@ -0,0 +1,46 @@
+package object
+
+import "sort"
+
+// Environment maps variable names to their values
+type Environment struct {
+	store map[string]Object
+	outer *Environment
+}
+
+// NewEnvironment creates an empty environment.
+func NewEnvironment() *Environment {
+	return &Environment{store: make(map[string]Object)}
+}
+
+// NewEnclosedEnvironment creates an environment whose lookups fall back to outer.
+func NewEnclosedEnvironment(outer *Environment) *Environment {
+	env := NewEnvironment()
+	env.outer = outer
+	return env
+}
+
+// Get returns the value bound to name, searching enclosing environments.
+func (e *Environment) Get(name string) (Object, bool) {
+	obj, ok := e.store[name]
+	if !ok && e.outer != nil {
+		return e.outer.Get(name)
+	}
+	return obj, ok
+}
+
+// Set binds name to val in this environment and returns val.
+func (e *Environment) Set(name string, val Object) Object {
+	e.store[name] = val
+	return val
+}
+
+// Names returns the names bound directly in this environment, sorted.
+func (e *Environment) Names() []string {
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
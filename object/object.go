@@ -0,0 +1,98 @@
+// Package object defines the values produced by evaluating an AST.
+package object
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ArtroxGabriel/sigma-parser/ast"
+)
+
+type ObjectType string
+
+const (
+	NUMBER_OBJ   ObjectType = "NUMBER"
+	COMPLEX_OBJ  ObjectType = "COMPLEX"
+	RATIONAL_OBJ ObjectType = "RATIONAL"
+	BIGFLOAT_OBJ ObjectType = "BIGFLOAT"
+	BOOLEAN_OBJ  ObjectType = "BOOLEAN"
+	FUNCTION_OBJ ObjectType = "FUNCTION"
+)
+
+// Object is the base interface for every evaluated value
+type Object interface {
+	Type() ObjectType
+	Inspect() string
+}
+
+// Number is a real number value
+type Number struct {
+	Value float64
+}
+
+func (*Number) Type() ObjectType  { return NUMBER_OBJ }
+func (n *Number) Inspect() string { return strconv.FormatFloat(n.Value, 'g', -1, 64) }
+
+// Complex is a complex number value, produced in complex evaluation mode
+type Complex struct {
+	Value complex128
+}
+
+func (*Complex) Type() ObjectType { return COMPLEX_OBJ }
+
+// Inspect prints 3+4i, 4i or 3, leaving out a zero part.
+func (c *Complex) Inspect() string {
+	re, im := real(c.Value), imag(c.Value)
+	if im == 0 {
+		return strconv.FormatFloat(re, 'g', -1, 64)
+	}
+
+	imag := strconv.FormatFloat(im, 'g', -1, 64) + "i"
+	if re == 0 {
+		return imag
+	}
+	if !strings.HasPrefix(imag, "-") && !strings.HasPrefix(imag, "+") {
+		imag = "+" + imag
+	}
+	return strconv.FormatFloat(re, 'g', -1, 64) + imag
+}
+
+// Rational is an exact rational number, produced in rational evaluation mode
+type Rational struct {
+	Value *big.Rat
+}
+
+func (*Rational) Type() ObjectType { return RATIONAL_OBJ }
+
+// Inspect prints integers as such and other values as a fraction (3/10).
+func (r *Rational) Inspect() string { return r.Value.RatString() }
+
+// BigFloat is an arbitrary-precision floating-point number
+type BigFloat struct {
+	Value *big.Float
+}
+
+func (*BigFloat) Type() ObjectType { return BIGFLOAT_OBJ }
+
+// Inspect prints the shortest decimal that identifies the value at its
+// precision.
+func (f *BigFloat) Inspect() string { return f.Value.Text('g', -1) }
+
+// Boolean is the value of a comparison or logical expression
+type Boolean struct {
+	Value bool
+}
+
+func (*Boolean) Type() ObjectType  { return BOOLEAN_OBJ }
+func (b *Boolean) Inspect() string { return strconv.FormatBool(b.Value) }
+
+// Function is a user-defined function along with the environment it was
+// defined in, which its body sees besides its parameters
+type Function struct {
+	Definition *ast.FunctionDefinition
+	Env        *Environment
+}
+
+func (*Function) Type() ObjectType  { return FUNCTION_OBJ }
+func (f *Function) Inspect() string { return f.Definition.String() }
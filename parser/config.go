@@ -0,0 +1,169 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/ArtroxGabriel/sigma-parser/ast"
+	"github.com/ArtroxGabriel/sigma-parser/token"
+)
+
+// Fixity says where an operator sits relative to its operands.
+type Fixity int
+
+const (
+	Prefix  Fixity = iota // -x
+	Infix                 // x + y
+	Postfix               // x!
+)
+
+// Associativity says how a chain of infix operators of equal precedence
+// groups.
+type Associativity int
+
+const (
+	LeftAssoc  Associativity = iota // a - b - c is (a - b) - c
+	RightAssoc                      // a ** b ** c is a ** (b ** c)
+)
+
+// BuildFn constructs the node for an operator applied to its operands: one
+// for prefix and postfix operators, left and right for infix ones.
+type BuildFn func(tok token.Token, operands ...ast.Expression) ast.Expression
+
+// Operator declares an operator of the grammar.
+type Operator struct {
+	Symbol        string // the operator text, made only of punctuation
+	Fixity        Fixity
+	Precedence    int // binding strength, see LOWEST through CALL
+	Associativity Associativity
+
+	// Build constructs the node for the operator. When nil, operators
+	// produce PrefixExpression, InfixExpression or PostfixExpression nodes
+	// with Symbol as their operator.
+	Build BuildFn
+}
+
+// Config is the operator table of a grammar. The zero value has no
+// operators; start from DefaultConfig to extend the standard grammar.
+type Config struct {
+	operators []Operator
+}
+
+// DefaultConfig returns the operator table of the standard grammar.
+func DefaultConfig() *Config {
+	return new(Config).
+		Add(Operator{Symbol: "+", Fixity: Infix, Precedence: SUM}).
+		Add(Operator{Symbol: "-", Fixity: Infix, Precedence: SUM}).
+		Add(Operator{Symbol: "*", Fixity: Infix, Precedence: PRODUCT}).
+		Add(Operator{Symbol: "/", Fixity: Infix, Precedence: PRODUCT}).
+		Add(Operator{Symbol: "^", Fixity: Infix, Precedence: POWER}).
+		Add(Operator{Symbol: "<", Fixity: Infix, Precedence: LESSGREATER}).
+		Add(Operator{Symbol: "<=", Fixity: Infix, Precedence: LESSGREATER}).
+		Add(Operator{Symbol: ">", Fixity: Infix, Precedence: LESSGREATER}).
+		Add(Operator{Symbol: ">=", Fixity: Infix, Precedence: LESSGREATER}).
+		Add(Operator{Symbol: "==", Fixity: Infix, Precedence: EQUALS}).
+		Add(Operator{Symbol: "!=", Fixity: Infix, Precedence: EQUALS}).
+		Add(Operator{Symbol: "&&", Fixity: Infix, Precedence: AND}).
+		Add(Operator{Symbol: "||", Fixity: Infix, Precedence: OR}).
+		Add(Operator{Symbol: "-", Fixity: Prefix, Precedence: PREFIX}).
+		Add(Operator{Symbol: "!", Fixity: Prefix, Precedence: PREFIX}).
+		Add(Operator{Symbol: "!", Fixity: Postfix, Precedence: POSTFIX}).
+		Add(Operator{Symbol: "%", Fixity: Postfix, Precedence: POSTFIX})
+}
+
+// Add declares op, replacing any operator with the same symbol and fixity,
+// and returns c so that calls can be chained. It panics if op is malformed,
+// as that is a programming error in the grammar.
+func (c *Config) Add(op Operator) *Config {
+	if err := op.validate(); err != nil {
+		panic("parser: " + err.Error())
+	}
+
+	c.Remove(op.Symbol, op.Fixity)
+	c.operators = append(c.operators, op)
+	return c
+}
+
+// Remove deletes the operator with the given symbol and fixity, if any, and
+// returns c.
+func (c *Config) Remove(symbol string, fixity Fixity) *Config {
+	for i, op := range c.operators {
+		if op.Symbol == symbol && op.Fixity == fixity {
+			c.operators = append(c.operators[:i], c.operators[i+1:]...)
+			break
+		}
+	}
+	return c
+}
+
+// Operators returns a copy of the declared operators.
+func (c *Config) Operators() []Operator {
+	return append([]Operator(nil), c.operators...)
+}
+
+func (op Operator) validate() error {
+	if op.Symbol == "" {
+		return fmt.Errorf("operator has no symbol")
+	}
+	if strings.IndexFunc(op.Symbol, func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) || r == '_'
+	}) >= 0 {
+		return fmt.Errorf("operator symbol %q must be punctuation", op.Symbol)
+	}
+	switch op.Symbol {
+	case "(", ")", ",", "?", ":", "|", "=", ";":
+		return fmt.Errorf("operator symbol %q is reserved", op.Symbol)
+	}
+	if op.Fixity != Prefix && op.Precedence <= LOWEST {
+		return fmt.Errorf("operator %q must bind tighter than LOWEST", op.Symbol)
+	}
+	return nil
+}
+
+// InfixAs returns a BuildFn producing an InfixExpression with the given
+// operator, for aliases such as ** for ^.
+func InfixAs(operator string) BuildFn {
+	return func(tok token.Token, operands ...ast.Expression) ast.Expression {
+		return &ast.InfixExpression{
+			Token:    tok,
+			Left:     operands[0],
+			Operator: operator,
+			Right:    operands[1],
+		}
+	}
+}
+
+// PostfixAs returns a BuildFn producing a PostfixExpression with the given
+// operator.
+func PostfixAs(operator string) BuildFn {
+	return func(tok token.Token, operands ...ast.Expression) ast.Expression {
+		return &ast.PostfixExpression{Token: tok, Left: operands[0], Operator: operator}
+	}
+}
+
+// PrefixAs returns a BuildFn producing a PrefixExpression with the given
+// operator.
+func PrefixAs(operator string) BuildFn {
+	return func(tok token.Token, operands ...ast.Expression) ast.Expression {
+		return &ast.PrefixExpression{Token: tok, Operator: operator, Right: operands[0]}
+	}
+}
+
+// CallAs returns a BuildFn producing a call of the named function on the
+// operands, for operators such as % as mod(a, b).
+func CallAs(name string) BuildFn {
+	return func(tok token.Token, operands ...ast.Expression) ast.Expression {
+		return &ast.FunctionCall{
+			Token:     tok,
+			Function:  &ast.Identifier{Token: tok, Value: name},
+			Arguments: operands,
+		}
+	}
+}
+
+// WithConfig makes the parser use the operators declared in c instead of
+// the standard grammar.
+func WithConfig(c *Config) Option {
+	return func(p *Parser) { p.config = c }
+}
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 
@@ -10,61 +11,147 @@ import (
 )
 
 const (
-	// predefined precedence levels for operators
-	_ int = iota
-	LOWEST
-	SUM     // sum or subtraction (+, -)
-	PRODUCT // product or division (*, /)
-	POWER   // power or squar root (^, sqrt)
-	PREFIX  // negative numbers (- or + unary)
-	CALL    // call functions(sin, cos, ln, etc.)
+	// predefined precedence levels for operators, spaced so that custom
+	// operators can bind between them
+	LOWEST      = 10
+	CONDITIONAL = 12 // conditional (c ? a : b)
+	OR          = 14 // logical or (||)
+	AND         = 15 // logical and (&&)
+	EQUALS      = 16 // equality (==, !=)
+	LESSGREATER = 18 // comparison (<, <=, >, >=)
+	SUM         = 20 // sum or subtraction (+, -)
+	PRODUCT     = 30 // product or division (*, /)
+	POWER       = 40 // power or squar root (^, sqrt)
+	PREFIX      = 50 // negative numbers and logical not (-x, !x)
+	POSTFIX     = 55 // factorial and percent (!, %)
+	CALL        = 60 // call functions(sin, cos, ln, etc.)
 )
 
-var precedences = map[token.TokenType]int{
-	token.PLUS:   SUM,
-	token.MINUS:  SUM,
-	token.SLASH:  PRODUCT,
-	token.TIMES:  PRODUCT,
-	token.POWER:  POWER,
-	token.LPAREN: CALL,
-}
-
 type (
 	prefixParseFn func() ast.Expression
 	infixParseFn  func(ast.Expression) ast.Expression
 )
 
+// Errors returned when input exceeds one of the parser's limits.
+var (
+	ErrInputTooLong = errors.New("input too long")
+	ErrTooDeep      = errors.New("expression nested too deeply")
+	ErrTooManyNodes = errors.New("expression has too many nodes")
+)
+
+// Error is a parse error anchored at the token where it was detected.
+type Error struct {
+	Pos token.Position // start of the offending token
+	End token.Position // end of the offending token
+	Msg string
+	Err error // one of the limit errors above, if a limit was exceeded
+}
+
+func (e *Error) Error() string { return e.Pos.String() + ": " + e.Msg }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Option configures a Parser.
+type Option func(*Parser)
+
+// WithMaxLength rejects inputs longer than n bytes.
+func WithMaxLength(n int) Option {
+	return func(p *Parser) { p.maxLength = n }
+}
+
+// WithMaxDepth rejects expressions nested more than n levels deep.
+func WithMaxDepth(n int) Option {
+	return func(p *Parser) { p.maxDepth = n }
+}
+
+// WithMaxNodes rejects expressions made of more than n nodes.
+func WithMaxNodes(n int) Option {
+	return func(p *Parser) { p.maxNodes = n }
+}
+
 type Parser struct {
 	l *lexer.Lexer
 
 	currToken token.Token
 	peekToken token.Token
 
-	errors []string
+	errors []*Error
 
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
+
+	config      *Config
+	precedences map[token.TokenType]int
+	prefixOps   map[token.TokenType]Operator
+	infixOps    map[token.TokenType]Operator
+
+	illegal *token.Token // first illegal token read, if any
+	pending *token.Token // token to read before asking the lexer again
+
+	bars int // absolute value bars currently open
+
+	statements bool // parsing a program, where line breaks end statements
+	groups     int  // parentheses currently open
+
+	// limits, zero meaning unlimited
+	maxLength int
+	maxDepth  int
+	maxNodes  int
+
+	depth   int  // current nesting of parseExpression calls
+	nodes   int  // nodes parsed so far
+	aborted bool // set once a limit is exceeded to stop parsing
 }
 
-func New(l *lexer.Lexer) *Parser {
+func New(l *lexer.Lexer, opts ...Option) *Parser {
 	p := &Parser{
 		l:      l,
-		errors: []string{},
+		errors: []*Error{},
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if p.config == nil {
+		p.config = DefaultConfig()
 	}
 
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.NUMBER, p.parserNumberLiteral)
-	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(token.IMAG, p.parseImaginaryLiteral)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
+	p.registerPrefix(token.BAR, p.parseAbsoluteValue)
+	p.registerPrefix(token.OR, p.parseDoubleBar)
 
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
-	p.registerInfix(token.PLUS, p.parseInfixExpression)
-	p.registerInfix(token.MINUS, p.parseInfixExpression)
-	p.registerInfix(token.SLASH, p.parseInfixExpression)
-	p.registerInfix(token.TIMES, p.parseInfixExpression)
-	p.registerInfix(token.POWER, p.parseInfixExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
+	p.registerInfix(token.QUESTION, p.parseConditionalExpression)
+
+	p.precedences = map[token.TokenType]int{token.LPAREN: CALL, token.QUESTION: CONDITIONAL}
+	p.prefixOps = make(map[token.TokenType]Operator)
+	p.infixOps = make(map[token.TokenType]Operator)
+
+	for _, op := range p.config.operators {
+		t := token.TokenType(op.Symbol)
+		l.AddSymbol(op.Symbol)
+
+		switch op.Fixity {
+		case Prefix:
+			p.prefixOps[t] = op
+			p.registerPrefix(t, p.parsePrefixExpression)
+		case Infix, Postfix:
+			p.infixOps[t] = op
+			p.precedences[t] = op.Precedence
+			p.registerInfix(t, p.parseInfixExpression)
+		}
+	}
+
+	if p.maxLength > 0 && l.Len() > p.maxLength {
+		start := token.Token{Pos: token.Position{Line: 1, Column: 1}}
+		p.limitError(start, ErrInputTooLong, "%s: %d bytes, limit is %d", l.Len(), p.maxLength)
+		return p
+	}
 
 	p.nextToken() // populate currToken and peekToken
 	p.nextToken()
@@ -72,29 +159,256 @@ func New(l *lexer.Lexer) *Parser {
 	return p
 }
 
+// Parse lexes and parses input as a single function which must span the
+// whole input. It returns the first *lexer.Error for an illegal character,
+// or else the first *Error from parsing.
+func Parse(input string, opts ...Option) (*ast.Function, error) {
+	p := New(lexer.New(input), opts...)
+	if p.aborted {
+		return nil, p.errors[0]
+	}
+
+	function := p.ParseFunction()
+	if len(p.errors) == 0 && !p.peekTokenIs(token.EOF) {
+		p.addError(p.peekToken, "unexpected %s after expression", p.peekToken.Type)
+	}
+
+	if err := p.err(); err != nil {
+		return nil, err
+	}
+	return function, nil
+}
+
+// ParseProgram lexes and parses input as a program, reporting errors like
+// Parse.
+func ParseProgram(input string, opts ...Option) (*ast.Program, error) {
+	p := New(lexer.New(input), opts...)
+	if p.aborted {
+		return nil, p.errors[0]
+	}
+
+	program := p.ParseProgram()
+
+	if err := p.err(); err != nil {
+		return nil, err
+	}
+	return program, nil
+}
+
+// ParseEquation lexes and parses input as an equation, two expressions
+// joined by =, reporting errors like Parse.
+func ParseEquation(input string, opts ...Option) (*ast.Equation, error) {
+	p := New(lexer.New(input), opts...)
+	if p.aborted {
+		return nil, p.errors[0]
+	}
+
+	equation := p.ParseEquation()
+	if len(p.errors) == 0 && !p.peekTokenIs(token.EOF) {
+		p.addError(p.peekToken, "unexpected %s after equation", p.peekToken.Type)
+	}
+
+	if err := p.err(); err != nil {
+		return nil, err
+	}
+	return equation, nil
+}
+
+// err returns the first *lexer.Error for an illegal character in the input,
+// or else the first parse error, if any.
+func (p *Parser) err() error {
+	// read what the parser left over so that illegal characters anywhere
+	// in the input are reported
+	for p.illegal == nil && !p.peekTokenIs(token.EOF) {
+		p.nextToken()
+	}
+	if p.illegal != nil {
+		return &lexer.Error{Pos: p.illegal.Pos, End: p.illegal.End(), Literal: p.illegal.Literal}
+	}
+
+	if len(p.errors) != 0 {
+		return p.errors[0]
+	}
+	return nil
+}
+
 func (p *Parser) nextToken() {
 	p.currToken = p.peekToken
+	if p.pending != nil {
+		p.peekToken = *p.pending
+		p.pending = nil
+		return
+	}
 	p.peekToken = p.l.NextToken()
+
+	if p.peekToken.Type == token.ILLEGAL && p.illegal == nil {
+		tok := p.peekToken
+		p.illegal = &tok
+	}
 }
 
 func (p *Parser) ParseFunction() *ast.Function {
 	mathExpression := new(ast.Function)
+	if p.aborted {
+		return mathExpression
+	}
 
 	mathExpression.Expression = p.parseExpression(LOWEST)
 
 	return mathExpression
 }
 
+// ParseEquation parses an equation such as v = u + a * t.
+func (p *Parser) ParseEquation() *ast.Equation {
+	equation := new(ast.Equation)
+	if p.aborted {
+		return equation
+	}
+
+	equation.Left = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.ASSIGN) {
+		return equation
+	}
+	equation.Token = p.currToken
+	p.nextToken()
+	equation.Right = p.parseExpression(LOWEST)
+
+	return equation
+}
+
+// ParseProgram parses a sequence of statements separated by semicolons or
+// line breaks. A line break inside parentheses does not end a statement.
+func (p *Parser) ParseProgram() *ast.Program {
+	program := &ast.Program{Statements: []ast.Statement{}}
+	if p.aborted {
+		return program
+	}
+	p.statements = true
+
+	for !p.aborted && p.currToken.Type != token.EOF {
+		if p.currToken.Type == token.SEMICOLON {
+			p.nextToken()
+			continue
+		}
+
+		if stmt := p.parseStatement(); stmt != nil {
+			program.Statements = append(program.Statements, stmt)
+		}
+
+		if !p.atStatementEnd() {
+			p.addError(p.peekToken, "unexpected %s after statement", p.peekToken.Type)
+			for !p.aborted && !p.atStatementEnd() {
+				p.nextToken()
+			}
+		}
+		p.nextToken()
+	}
+
+	return program
+}
+
+// atStatementEnd reports whether the current token ends a statement.
+func (p *Parser) atStatementEnd() bool {
+	return p.peekTokenIs(token.SEMICOLON) || p.peekTokenIs(token.EOF) || p.atLineBreak()
+}
+
+// atLineBreak reports whether a line break that ends a statement follows the
+// current token.
+func (p *Parser) atLineBreak() bool {
+	return p.statements && p.groups == 0 && p.peekToken.Pos.Line > p.currToken.Pos.Line
+}
+
+// parseStatement parses an expression, an assignment x = 1 or a function
+// definition f(x) = x^2.
+func (p *Parser) parseStatement() ast.Statement {
+	tok := p.currToken
+	exp := p.parseExpression(LOWEST)
+	if !p.peekTokenIs(token.ASSIGN) || p.atLineBreak() {
+		return &ast.ExpressionStatement{Token: tok, Expression: exp}
+	}
+
+	p.nextToken()
+	assign := p.currToken
+	p.nextToken()
+	value := p.parseExpression(LOWEST)
+
+	switch target := exp.(type) {
+	case *ast.Identifier:
+		return &ast.AssignStatement{Token: assign, Name: target, Value: value}
+	case *ast.FunctionCall:
+		if def := p.parseDefinition(assign, target); def != nil {
+			def.Body = value
+			return def
+		}
+		return nil
+	}
+
+	if exp != nil {
+		p.addError(assign, "cannot assign to %s", exp)
+	}
+	return nil
+}
+
+// parseDefinition turns the head f(x, y) of a function definition into a
+// FunctionDefinition without a body.
+func (p *Parser) parseDefinition(assign token.Token, head *ast.FunctionCall) *ast.FunctionDefinition {
+	name, ok := head.Function.(*ast.Identifier)
+	if !ok || head.IsBars() {
+		p.addError(assign, "cannot assign to %s", head)
+		return nil
+	}
+
+	def := &ast.FunctionDefinition{Token: assign, Name: name}
+	seen := make(map[string]bool)
+	for _, arg := range head.Arguments {
+		param, ok := arg.(*ast.Identifier)
+		if !ok {
+			p.addError(assign, "parameter of %s must be a name, got %s", name, arg)
+			return nil
+		}
+		if seen[param.Value] {
+			p.addError(param.Token, "duplicate parameter %s of %s", param, name)
+			return nil
+		}
+		seen[param.Value] = true
+		def.Parameters = append(def.Parameters, param)
+	}
+	return def
+}
+
 func (p *Parser) parseExpression(precedence int) ast.Expression {
+	if p.aborted {
+		return nil
+	}
+
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.maxDepth > 0 && p.depth > p.maxDepth {
+		p.limitError(p.currToken, ErrTooDeep, "%s: limit is %d", p.maxDepth)
+		return nil
+	}
+
 	prefix := p.prefixParseFns[p.currToken.Type]
 	if prefix == nil {
 		p.noPrefixParseFnError(p.currToken.Type)
 		return nil
 	}
 
+	if !p.countNode() {
+		return nil
+	}
 	leftExp := prefix()
 
-	for !p.peekTokenIs(token.EOF) && precedence < p.peekPrecedence() {
+	for !p.aborted && !p.peekTokenIs(token.EOF) && !p.atLineBreak() && precedence < p.peekPrecedence() {
+		if p.implicitProduct() {
+			p.nextToken()
+			if !p.countNode() {
+				return nil
+			}
+			leftExp = p.parseImplicitProduct(leftExp)
+			continue
+		}
+
 		infix := p.infixParseFns[p.peekToken.Type]
 		if infix == nil {
 			return leftExp
@@ -102,12 +416,63 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 
 		p.nextToken()
 
+		if !p.countNode() {
+			return nil
+		}
 		leftExp = infix(leftExp)
 	}
 
 	return leftExp
 }
 
+// implicitProduct reports whether the next token starts an operand that
+// multiplies the postfix expression just parsed, as in 2!3! or n!(n - k)!.
+// A percentage is never multiplied so: 7 % 3 would read as a remainder.
+func (p *Parser) implicitProduct() bool {
+	op, ok := p.infixOps[p.currToken.Type]
+	if !ok || op.Fixity != Postfix || p.currToken.Type == token.PERCENT {
+		return false
+	}
+	return p.peekStartsOperand()
+}
+
+// peekStartsOperand reports whether the next token starts an operand.
+func (p *Parser) peekStartsOperand() bool {
+	switch p.peekToken.Type {
+	case token.NUMBER, token.IDENT, token.LPAREN:
+		return true
+	}
+	return false
+}
+
+func (p *Parser) parseImplicitProduct(left ast.Expression) ast.Expression {
+	tok := token.Token{Type: token.TIMES, Literal: "*", Pos: p.currToken.Pos}
+	return &ast.InfixExpression{
+		Token:    tok,
+		Left:     left,
+		Operator: "*",
+		Right:    p.parseExpression(PRODUCT),
+	}
+}
+
+// countNode records a new node and reports whether the node limit still holds.
+func (p *Parser) countNode() bool {
+	p.nodes++
+	if p.maxNodes > 0 && p.nodes > p.maxNodes {
+		p.limitError(p.currToken, ErrTooManyNodes, "%s: limit is %d", p.maxNodes)
+		return false
+	}
+	return true
+}
+
+// limitError records that a limit was exceeded and stops the parser. The
+// format receives err followed by args.
+func (p *Parser) limitError(tok token.Token, err error, format string, args ...any) {
+	p.addError(tok, format, append([]any{err}, args...)...)
+	p.errors[len(p.errors)-1].Err = err
+	p.aborted = true
+}
+
 func (p *Parser) parseIdentifier() ast.Expression {
 	return &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
 }
@@ -115,30 +480,48 @@ func (p *Parser) parseIdentifier() ast.Expression {
 func (p *Parser) parserNumberLiteral() ast.Expression {
 	value, err := strconv.ParseFloat(p.currToken.Literal, 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as float", p.currToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(p.currToken, "could not parse %q as float", p.currToken.Literal)
 		return nil
 	}
 
 	return &ast.NumberLiteral{Token: p.currToken, Value: value}
 }
 
-func (p *Parser) parsePrefixExpression() ast.Expression {
-	expression := &ast.PrefixExpression{
-		Token:    p.currToken,
-		Operator: p.currToken.Literal,
+// parseImaginaryLiteral parses 4i as a number literal whose IMAG token marks
+// it as imaginary.
+func (p *Parser) parseImaginaryLiteral() ast.Expression {
+	literal := p.currToken.Literal
+	value, err := strconv.ParseFloat(literal[:len(literal)-1], 64)
+	if err != nil {
+		p.addError(p.currToken, "could not parse %q as an imaginary number", literal)
+		return nil
 	}
 
+	return &ast.NumberLiteral{Token: p.currToken, Value: value}
+}
+
+func (p *Parser) parsePrefixExpression() ast.Expression {
+	tok := p.currToken
+	op := p.prefixOps[tok.Type]
+
 	p.nextToken()
-	expression.Right = p.parseExpression(PREFIX)
+	right := p.parseExpression(op.Precedence)
 
-	return expression
+	if op.Build != nil {
+		return op.Build(tok, right)
+	}
+	return &ast.PrefixExpression{
+		Token:    tok,
+		Operator: op.Symbol,
+		Right:    right,
+	}
 }
 
 func (p *Parser) parseGroupedExpression() ast.Expression {
+	p.nodes-- // parentheses only group, they add no node to the tree
 	p.nextToken()
 
-	exp := p.parseExpression(LOWEST)
+	exp := p.parseEnclosed(LOWEST)
 	if !p.expectPeek(token.RPAREN) {
 		return nil
 	}
@@ -146,42 +529,208 @@ func (p *Parser) parseGroupedExpression() ast.Expression {
 	return exp
 }
 
+// parseEnclosed parses an expression between parentheses, where || is a
+// logical or even inside an absolute value, |(a || b) ? 1 : 0|, and line
+// breaks never end a statement.
+func (p *Parser) parseEnclosed(precedence int) ast.Expression {
+	bars := p.bars
+	p.bars = 0
+	p.groups++
+	defer func() {
+		p.bars = bars
+		p.groups--
+	}()
+
+	return p.parseExpression(precedence)
+}
+
+// parseAbsoluteValue parses |x| as a call to abs. Bars both open and close,
+// so a || met while a bar is open closes two of them, as in |a - |b||.
+func (p *Parser) parseAbsoluteValue() ast.Expression {
+	tok := p.currToken
+
+	p.bars++
+	p.nextToken()
+	exp := p.parseExpression(LOWEST)
+	p.bars--
+
+	if p.peekTokenIs(token.OR) {
+		p.peekToken, p.pending = splitBars(p.peekToken)
+	}
+	if !p.expectPeek(token.BAR) {
+		return nil
+	}
+
+	return &ast.FunctionCall{
+		Token:     tok,
+		Function:  &ast.Identifier{Token: token.Token{Type: token.IDENT, Literal: "abs", Pos: tok.Pos}, Value: "abs"},
+		Arguments: []ast.Expression{exp},
+	}
+}
+
+// parseDoubleBar parses a || that opens two absolute values, as in ||a| - b|.
+func (p *Parser) parseDoubleBar() ast.Expression {
+	first, second := splitBars(p.currToken)
+	peek := p.peekToken
+
+	p.currToken, p.peekToken, p.pending = first, *second, &peek
+	return p.parseAbsoluteValue()
+}
+
+// splitBars splits a || token into two | tokens.
+func splitBars(tok token.Token) (token.Token, *token.Token) {
+	first := token.Token{Type: token.BAR, Literal: "|", Pos: tok.Pos}
+	second := token.Token{Type: token.BAR, Literal: "|", Pos: first.End()}
+	return first, &second
+}
+
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
-	expression := &ast.InfixExpression{
-		Token:    p.currToken,
-		Operator: p.currToken.Literal,
+	tok := p.currToken
+	op := p.infixOps[tok.Type]
+
+	if op.Fixity == Postfix {
+		if tok.Type == token.PERCENT && p.peekStartsOperand() {
+			p.addError(p.peekToken, "unexpected %s after %%: %% is a percentage, not a remainder", p.peekToken.Type)
+		}
+		if op.Build != nil {
+			return op.Build(tok, left)
+		}
+		return &ast.PostfixExpression{Token: tok, Left: left, Operator: op.Symbol}
+	}
+
+	precedence := op.Precedence
+	if op.Associativity == RightAssoc {
+		// parsing the right operand one level lower lets it absorb
+		// further operators of the same precedence
+		precedence--
+	}
+
+	p.nextToken()
+	right := p.parseExpression(precedence)
+
+	if op.Build != nil {
+		return op.Build(tok, left, right)
+	}
+	return &ast.InfixExpression{
+		Token:    tok,
 		Left:     left,
+		Operator: op.Symbol,
+		Right:    right,
+	}
+}
+
+func (p *Parser) parseConditionalExpression(condition ast.Expression) ast.Expression {
+	exp := &ast.ConditionalExpression{Token: p.currToken, Condition: condition}
+
+	p.nextToken()
+	exp.Consequence = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.COLON) {
+		return nil
 	}
 
-	precedence := p.currPrecedence()
+	// a ? b : c ? d : e groups as a ? b : (c ? d : e)
 	p.nextToken()
-	expression.Right = p.parseExpression(precedence)
+	exp.Alternative = p.parseExpression(CONDITIONAL - 1)
 
-	return expression
+	return exp
 }
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.FunctionCall{Token: p.currToken, Function: function}
-	exp.Argument = p.parseExpressionParam(token.RPAREN)
+	exp.Arguments = p.parseExpressionList(token.RPAREN)
+
+	if ident, ok := function.(*ast.Identifier); ok && exp.Arguments != nil {
+		switch ident.Value {
+		case "if":
+			return p.parseIf(ident, exp.Arguments)
+		case "piecewise":
+			return p.parsePiecewise(ident, exp.Arguments)
+		case "integrate":
+			return p.parseIntegrate(ident, exp)
+		}
+	}
 	return exp
 }
 
-func (p *Parser) parseExpressionParam(end token.TokenType) ast.Expression {
+// parseIf turns if(condition, then, else) into a conditional expression.
+func (p *Parser) parseIf(ident *ast.Identifier, args []ast.Expression) ast.Expression {
+	if len(args) != 3 {
+		p.addError(ident.Token, "if takes a condition and two values, got %d arguments", len(args))
+		return nil
+	}
+	return &ast.ConditionalExpression{
+		Token:       ident.Token,
+		Condition:   args[0],
+		Consequence: args[1],
+		Alternative: args[2],
+	}
+}
+
+// parseIntegrate checks the form of integrate(integrand, x, a, b), which
+// stays a call, with its variable bound within the integrand.
+func (p *Parser) parseIntegrate(ident *ast.Identifier, call *ast.FunctionCall) ast.Expression {
+	if len(call.Arguments) != 4 {
+		p.addError(ident.Token, "integrate takes an integrand, a variable and two bounds, got %d arguments", len(call.Arguments))
+		return nil
+	}
+	if _, ok := call.Arguments[1].(*ast.Identifier); !ok {
+		p.addError(ast.TokenOf(call.Arguments[1]), "variable of integration must be a name, got %s", call.Arguments[1])
+		return nil
+	}
+	return call
+}
+
+// parsePiecewise turns piecewise(c1, v1, c2, v2, ..., default) into nested
+// conditional expressions. The default value is optional.
+func (p *Parser) parsePiecewise(ident *ast.Identifier, args []ast.Expression) ast.Expression {
+	if len(args) < 2 {
+		p.addError(ident.Token, "piecewise takes condition and value pairs, got %d arguments", len(args))
+		return nil
+	}
+
+	var exp ast.Expression
+	if len(args)%2 == 1 {
+		exp = args[len(args)-1]
+		args = args[:len(args)-1]
+	}
+	for i := len(args) - 2; i >= 0; i -= 2 {
+		exp = &ast.ConditionalExpression{
+			Token:       ident.Token,
+			Condition:   args[i],
+			Consequence: args[i+1],
+			Alternative: exp,
+		}
+	}
+	return exp
+}
+
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	list := []ast.Expression{}
+
 	if p.peekTokenIs(end) {
 		p.nextToken()
-		return nil
+		return list
 	}
 
 	p.nextToken()
-	exp := p.parseExpression(LOWEST)
+	list = append(list, p.parseEnclosed(LOWEST))
+
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.nextToken()
+		list = append(list, p.parseEnclosed(LOWEST))
+	}
 
 	if !p.expectPeek(end) {
 		return nil
 	}
-	return exp
+	return list
 }
 
 func (p *Parser) expectPeek(t token.TokenType) bool {
+	if p.aborted {
+		return false
+	}
 	if p.peekTokenIs(t) {
 		p.nextToken()
 		return true
@@ -192,33 +741,48 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 
 func (p *Parser) peekTokenIs(t token.TokenType) bool { return p.peekToken.Type == t }
 
-func (p *Parser) Errors() []string { return p.errors }
+// Errors returns the messages of every error found while parsing.
+func (p *Parser) Errors() []string {
+	msgs := make([]string, 0, len(p.errors))
+	for _, e := range p.errors {
+		msgs = append(msgs, e.Msg)
+	}
+	return msgs
+}
+
+// ParseErrors returns every error found while parsing along with its position.
+func (p *Parser) ParseErrors() []*Error { return p.errors }
+
+func (p *Parser) addError(tok token.Token, format string, args ...any) {
+	p.errors = append(p.errors, &Error{
+		Pos: tok.Pos,
+		End: tok.End(),
+		Msg: fmt.Sprintf(format, args...),
+	})
+}
 
 func (p *Parser) peekErrors(t token.TokenType) {
-	msg := fmt.Sprintf(
+	p.addError(
+		p.peekToken,
 		"Expected next token to be %s, got %s instead",
 		t,
 		p.peekToken.Type,
 	)
-
-	p.errors = append(p.errors, msg)
 }
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	p.errors = append(p.errors,
-		fmt.Sprintf("no prefix parse function for %s found", t),
-	)
+	p.addError(p.currToken, "no prefix parse function for %s found", t)
 }
 
 func (p *Parser) peekPrecedence() int {
-	if p, ok := precedences[p.peekToken.Type]; ok {
-		return p
+	if p.implicitProduct() {
+		return PRODUCT
 	}
-	return LOWEST
-}
-
-func (p *Parser) currPrecedence() int {
-	if p, ok := precedences[p.currToken.Type]; ok {
+	if p.bars > 0 && p.peekTokenIs(token.OR) {
+		// closes absolute values rather than joining two operands
+		return LOWEST
+	}
+	if p, ok := p.precedences[p.peekToken.Type]; ok {
 		return p
 	}
 	return LOWEST
@@ -1,6 +1,8 @@
 package parser_test
 
 import (
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/ArtroxGabriel/sigma-parser/ast"
@@ -42,11 +44,14 @@ func TestIdentifierExpression(t *testing.T) {
 
 func TestNumberLiteralExpression(t *testing.T) {
 	tests := []struct {
-		input string
-		want  float64
+		input     string
+		want      float64
+		imaginary bool
 	}{
 		{input: "5", want: 5},
 		{input: "3.14", want: 3.14},
+		{input: "4i", want: 4, imaginary: true},
+		{input: "2.5j", want: 2.5, imaginary: true},
 	}
 
 	for _, tt := range tests {
@@ -64,6 +69,9 @@ func TestNumberLiteralExpression(t *testing.T) {
 		if ident.Value != tt.want {
 			t.Errorf("ident.Value not %f. got=%f", tt.want, ident.Value)
 		}
+		if ident.IsImaginary() != tt.imaginary {
+			t.Errorf("ident.IsImaginary() not %t", tt.imaginary)
+		}
 		if ident.TokenLiteral() != tt.input {
 			t.Errorf("ident.TokenLiteral not %f. got=%s", tt.want,
 				ident.TokenLiteral())
@@ -199,6 +207,130 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"sqrt(a + b + c * d / f + g)",
 			"sqrt((((a + b) + ((c * d) / f)) + g))",
 		},
+		{
+			"n!",
+			"(n!)",
+		},
+		{
+			"-3!",
+			"(-(3!))",
+		},
+		{
+			"2 ^ 3!",
+			"(2 ^ (3!))",
+		},
+		{
+			"x! ^ 2",
+			"((x!) ^ 2)",
+		},
+		{
+			"5! / (2!3!)",
+			"((5!) / ((2!) * (3!)))",
+		},
+		{
+			"n! / (k!(n - k)!)",
+			"((n!) / ((k!) * ((n - k)!)))",
+		},
+		{
+			"2!3!^2",
+			"((2!) * ((3!) ^ 2))",
+		},
+		{
+			"15% * x",
+			"((15%) * x)",
+		},
+		{
+			"a + b%",
+			"(a + (b%))",
+		},
+		{
+			"atan2(y, x + 1) * 2",
+			"(atan2(y, (x + 1)) * 2)",
+		},
+		{
+			"rand()",
+			"rand()",
+		},
+		{
+			"a + 1 < b * 2",
+			"((a + 1) < (b * 2))",
+		},
+		{
+			"a < b == c >= d",
+			"((a < b) == (c >= d))",
+		},
+		{
+			"a < b || c < d && e != f",
+			"((a < b) || ((c < d) && (e != f)))",
+		},
+		{
+			"!a && !(b || c)",
+			"((!a) && (!(b || c)))",
+		},
+		{
+			"!n! == 1",
+			"((!(n!)) == 1)",
+		},
+		{
+			"x < 10 ? a : b",
+			"((x < 10) ? a : b)",
+		},
+		{
+			"a ? b : c ? d : e",
+			"(a ? b : (c ? d : e))",
+		},
+		{
+			"a ? b ? c : d : e",
+			"(a ? (b ? c : d) : e)",
+		},
+		{
+			"(a ? b : c) * 2 + 1",
+			"(((a ? b : c) * 2) + 1)",
+		},
+		{
+			"if(x > 0, x, 0)",
+			"((x > 0) ? x : 0)",
+		},
+		{
+			"piecewise(x < 10, a, x < 20, b, c)",
+			"((x < 10) ? a : ((x < 20) ? b : c))",
+		},
+		{
+			"|x - 3|",
+			"|(x - 3)|",
+		},
+		{
+			"2 * |x| ^ 2",
+			"(2 * (|x| ^ 2))",
+		},
+		{
+			"|a - |b||",
+			"|(a - |b|)|",
+		},
+		{
+			"||a| - b|",
+			"|(|a| - b)|",
+		},
+		{
+			"||a||",
+			"||a||",
+		},
+		{
+			"|a| || |b| < 1",
+			"(|a| || (|b| < 1))",
+		},
+		{
+			"|(a || b) ? x : -x|",
+			"|((a || b) ? x : (-x))|",
+		},
+		{
+			"|sin(|x|)|!",
+			"(|sin(|x|)|!)",
+		},
+		{
+			"piecewise(x < 10, a, x < 20, b)",
+			"((x < 10) ? a : piecewise((x < 20), b))",
+		},
 	}
 
 	for _, tt := range tests {
@@ -322,3 +454,333 @@ func checkParserErrors(t *testing.T, p *parser.Parser) {
 	}
 	t.FailNow()
 }
+
+func TestParseErrorPositions(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"(1 + 2", "1:7: Expected next token to be ), got EOF instead"},
+		{"2 *\n  )", "2:3: no prefix parse function for ) found"},
+		{"sin(x, @)", "1:8: no prefix parse function for ILLEGAL found"},
+		{"a ? b", "1:6: Expected next token to be :, got EOF instead"},
+		{"|x + 1", "1:7: Expected next token to be |, got EOF instead"},
+		{"|a - |b|", "1:9: Expected next token to be |, got EOF instead"},
+		{"1 + if(x, y)", "1:5: if takes a condition and two values, got 2 arguments"},
+		{"piecewise(x)", "1:1: piecewise takes condition and value pairs, got 1 arguments"},
+		{"integrate(x, x, 0)", "1:1: integrate takes an integrand, a variable and two bounds, got 3 arguments"},
+		{"integrate(x, 2 * x, 0, 1)", "1:16: variable of integration must be a name, got (2 * x)"},
+		{"7 % 3", "1:5: unexpected NUMBER after %: % is a percentage, not a remainder"},
+		{"(x%(y))", "1:4: unexpected ( after %: % is a percentage, not a remainder"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			p := parser.New(lexer.New(tt.input))
+			p.ParseFunction()
+
+			errs := p.ParseErrors()
+			if len(errs) == 0 {
+				t.Fatalf("expected parse errors, got none")
+			}
+			if errs[0].Error() != tt.want {
+				t.Errorf("first error = %q, want %q", errs[0].Error(), tt.want)
+			}
+		})
+	}
+}
+
+func TestParserLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		opts    []parser.Option
+		wantErr error
+		wantMsg string
+	}{
+		{
+			name:    "input length",
+			input:   "1 + 2 + 3",
+			opts:    []parser.Option{parser.WithMaxLength(5)},
+			wantErr: parser.ErrInputTooLong,
+			wantMsg: "1:1: input too long: 9 bytes, limit is 5",
+		},
+		{
+			name:    "nested parentheses",
+			input:   strings.Repeat("(", 10000) + "1" + strings.Repeat(")", 10000),
+			opts:    []parser.Option{parser.WithMaxDepth(100)},
+			wantErr: parser.ErrTooDeep,
+			wantMsg: "1:101: expression nested too deeply: limit is 100",
+		},
+		{
+			name:    "nested prefix operators",
+			input:   strings.Repeat("-", 50) + "x",
+			opts:    []parser.Option{parser.WithMaxDepth(10)},
+			wantErr: parser.ErrTooDeep,
+		},
+		{
+			name:    "node count",
+			input:   "a + b * c - d",
+			opts:    []parser.Option{parser.WithMaxNodes(5)},
+			wantErr: parser.ErrTooManyNodes,
+		},
+		{
+			name:  "within limits",
+			input: "((a + b) * sin(c))",
+			opts: []parser.Option{
+				parser.WithMaxLength(18),
+				parser.WithMaxDepth(5),
+				parser.WithMaxNodes(7),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := parser.New(lexer.New(tt.input), tt.opts...)
+			p.ParseFunction()
+			errs := p.ParseErrors()
+
+			if tt.wantErr == nil {
+				checkParserErrors(t, p)
+				return
+			}
+			if len(errs) != 1 {
+				t.Fatalf("got %d errors, want exactly 1: %v", len(errs), p.Errors())
+			}
+			if !errors.Is(errs[0], tt.wantErr) {
+				t.Errorf("error = %v, want %v", errs[0], tt.wantErr)
+			}
+			if tt.wantMsg != "" && errs[0].Error() != tt.wantMsg {
+				t.Errorf("error = %q, want %q", errs[0].Error(), tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	if _, err := parser.Parse("1 + $"); err == nil || err.Error() != `1:5: illegal character "$"` {
+		t.Errorf("Parse(1 + $) error = %v, want illegal character", err)
+	}
+	if _, err := parser.Parse("1 +"); err == nil || err.Error() != "1:4: no prefix parse function for EOF found" {
+		t.Errorf("Parse(1 +) error = %v, want parse error", err)
+	}
+	if _, err := parser.Parse("((1))", parser.WithMaxDepth(2)); !errors.Is(err, parser.ErrTooDeep) {
+		t.Errorf("Parse(((1))) error = %v, want ErrTooDeep", err)
+	}
+
+	function, err := parser.Parse("2 * x")
+	if err != nil {
+		t.Fatalf("Parse(2 * x) error = %v", err)
+	}
+	if function.String() != "(2 * x)" {
+		t.Errorf("Parse(2 * x) = %s", function)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	dialect := parser.DefaultConfig().
+		Add(parser.Operator{
+			Symbol:        "**",
+			Fixity:        parser.Infix,
+			Precedence:    parser.POWER,
+			Associativity: parser.RightAssoc,
+			Build:         parser.InfixAs("^"),
+		}).
+		Add(parser.Operator{Symbol: "%", Fixity: parser.Infix, Precedence: parser.PRODUCT, Build: parser.CallAs("mod")}).
+		Add(parser.Operator{Symbol: "'", Fixity: parser.Postfix, Precedence: parser.CALL, Build: parser.CallAs("deriv")}).
+		Add(parser.Operator{Symbol: "+", Fixity: parser.Prefix, Precedence: parser.PREFIX}).
+		Add(parser.Operator{Symbol: "<>", Fixity: parser.Infix, Precedence: parser.LOWEST + 5})
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"2 ** 3 ** 2", "(2 ^ (3 ^ 2))"},
+		{"2 ^ 3 ^ 2", "((2 ^ 3) ^ 2)"},
+		{"a * b ** 2", "(a * (b ^ 2))"},
+		{"a % b + c", "(mod(a, b) + c)"},
+		{"a + b % c", "(a + mod(b, c))"},
+		{"f' * 2", "(deriv(f) * 2)"},
+		{"+x - -y", "((+x) - (-y))"},
+		{"a + b <> c * d", "((a + b) <> (c * d))"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			p := parser.New(lexer.New(tt.input), parser.WithConfig(dialect))
+			function := p.ParseFunction()
+			checkParserErrors(t, p)
+
+			if actual := function.String(); actual != tt.expected {
+				t.Errorf("expected %q. got=%q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestConfigRemove(t *testing.T) {
+	cfg := parser.DefaultConfig().Remove("^", parser.Infix)
+
+	if _, err := parser.Parse("x ^ 2", parser.WithConfig(cfg)); err == nil {
+		t.Errorf("Parse(x ^ 2) without ^ succeeded, want an error")
+	}
+	if _, err := parser.Parse("x * 2", parser.WithConfig(cfg)); err != nil {
+		t.Errorf("Parse(x * 2) error = %v", err)
+	}
+}
+
+func TestConfigRejectsMalformedOperators(t *testing.T) {
+	tests := []parser.Operator{
+		{Symbol: "", Fixity: parser.Infix, Precedence: parser.SUM},
+		{Symbol: "mod", Fixity: parser.Infix, Precedence: parser.PRODUCT},
+		{Symbol: ",", Fixity: parser.Infix, Precedence: parser.SUM},
+		{Symbol: "?", Fixity: parser.Infix, Precedence: parser.SUM},
+		{Symbol: "(", Fixity: parser.Prefix, Precedence: parser.PREFIX},
+		{Symbol: "~", Fixity: parser.Infix, Precedence: parser.LOWEST},
+	}
+
+	for _, op := range tests {
+		t.Run(op.Symbol, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Add(%+v) did not panic", op)
+				}
+			}()
+			parser.DefaultConfig().Add(op)
+		})
+	}
+}
+
+func TestParseRejectsTrailingTokens(t *testing.T) {
+	_, err := parser.Parse("2 3")
+	if err == nil || err.Error() != "1:3: unexpected NUMBER after expression" {
+		t.Errorf("Parse(2 3) error = %v, want unexpected NUMBER", err)
+	}
+}
+
+func TestParseProgram(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"f(x) = x^2 + 1; g(x, y) = f(x) * y; g(2, 3)", []string{
+			"f(x) = ((x ^ 2) + 1)",
+			"g(x, y) = (f(x) * y)",
+			"g(2, 3)",
+		}},
+		{"a = 1\nb = a + 2\n\nb * 3", []string{"a = 1", "b = (a + 2)", "(b * 3)"}},
+		{"a = 1\n-b", []string{"a = 1", "(-b)"}},
+		{"f(x) = x\n(2)", []string{"f(x) = x", "2"}},
+		{"y = (1 +\n  2)\nf(1,\n 2)", []string{"y = (1 + 2)", "f(1, 2)"}},
+		{"y = 1 +\n 2", []string{"y = (1 + 2)"}},
+		{";; x = 1;\n", []string{"x = 1"}},
+		{"", []string{}},
+		{"x = y = 1", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			program, err := parser.ParseProgram(tt.input)
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("ParseProgram() = %s, want an error", program)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseProgram() error = %v", err)
+			}
+
+			if len(program.Statements) != len(tt.want) {
+				t.Fatalf("ParseProgram() = %d statements %q, want %d", len(program.Statements), program, len(tt.want))
+			}
+			for i, stmt := range program.Statements {
+				if stmt.String() != tt.want[i] {
+					t.Errorf("statement %d = %q, want %q", i, stmt, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseProgramStatements(t *testing.T) {
+	program, err := parser.ParseProgram("k = 2; f(x, y) = k * x")
+	if err != nil {
+		t.Fatalf("ParseProgram() error = %v", err)
+	}
+
+	assign, ok := program.Statements[0].(*ast.AssignStatement)
+	if !ok {
+		t.Fatalf("Statements[0] = %T, want *ast.AssignStatement", program.Statements[0])
+	}
+	if assign.Name.Value != "k" {
+		t.Errorf("assign.Name = %q, want k", assign.Name.Value)
+	}
+
+	def, ok := program.Statements[1].(*ast.FunctionDefinition)
+	if !ok {
+		t.Fatalf("Statements[1] = %T, want *ast.FunctionDefinition", program.Statements[1])
+	}
+	if def.Name.Value != "f" || len(def.Parameters) != 2 || def.Parameters[1].Value != "y" {
+		t.Errorf("definition = %s, want f(x, y)", def)
+	}
+}
+
+func TestParseProgramErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"1 + 2 = 3", "1:7: cannot assign to (1 + 2)"},
+		{"f(x + 1) = x", "1:10: parameter of f must be a name, got (x + 1)"},
+		{"f(x, x) = x", "1:6: duplicate parameter x of f"},
+		{"|x| = 1", "1:5: cannot assign to |x|"},
+		{"x = 1 2", "1:7: unexpected NUMBER after statement"},
+		{"x = 1; y = (2", "1:14: Expected next token to be ), got EOF instead"},
+		{"x = 1\ny = 2 @", "2:7: illegal character \"@\""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := parser.ParseProgram(tt.input)
+			if err == nil {
+				t.Fatalf("ParseProgram() error = nil, want %q", tt.want)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("ParseProgram() error = %q, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEquation(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		err   string
+	}{
+		{"v = u + a * t", "v = (u + (a * t))", ""},
+		{"x^2 + 1 = 2 * x", "((x ^ 2) + 1) = (2 * x)", ""},
+		{"x + 1", "", "1:6: Expected next token to be =, got EOF instead"},
+		{"x = 1 = 2", "", "1:7: unexpected = after equation"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			eq, err := parser.ParseEquation(tt.input)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("ParseEquation() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseEquation() error = %v", err)
+			}
+			if eq.String() != tt.want {
+				t.Errorf("ParseEquation() = %q, want %q", eq, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,334 @@
+package polynomial
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/ArtroxGabriel/sigma-parser/ast"
+	"github.com/ArtroxGabriel/sigma-parser/eval"
+)
+
+// MaxDegree bounds the total degree of the polynomials FromExpression
+// builds, so that a short input such as (x + 1)^100000 or
+// ((x + 1)^64)^64 cannot take forever.
+const MaxDegree = 1024
+
+// MaxTerms bounds the number of terms of the polynomials FromExpression
+// builds, and so each product to MaxTerms² term multiplications: (x + y +
+// z)^200 has 20301 terms.
+const MaxTerms = 1024
+
+var (
+	// ErrNotPolynomial is returned when an expression is not a polynomial
+	// in the given variables.
+	ErrNotPolynomial = errors.New("not a polynomial")
+
+	// ErrTooLarge is returned when a polynomial is too large to expand:
+	// its degree is above MaxDegree or it has more than MaxTerms terms.
+	ErrTooLarge = errors.New("polynomial too large")
+)
+
+// IsPolynomial reports whether e is a polynomial in vars. It looks at the
+// form of e without expanding it, so (x + 1)^100000 is a polynomial even
+// though FromExpression fails on it with ErrTooLarge.
+func IsPolynomial(e ast.Expression, vars ...string) bool {
+	c := &converter{vars: vars, ev: eval.New()}
+	return c.polynomial(e)
+}
+
+// FromExpression converts e into a polynomial in vars, expanding products
+// and non-negative integer powers. Subexpressions that do not mention vars
+// are coefficients: they are evaluated, and must not have free identifiers
+// of their own. Division is allowed only by a coefficient.
+func FromExpression(e ast.Expression, vars ...string) (*Polynomial, error) {
+	c := &converter{vars: vars, ev: eval.New()}
+	return c.convert(e)
+}
+
+type converter struct {
+	vars []string
+	ev   *eval.Evaluator
+}
+
+// polynomial reports whether convert would succeed on e, given no bound on
+// its size.
+func (c *converter) polynomial(e ast.Expression) bool {
+	if !c.mentions(e) {
+		_, err := c.ev.Float(e, nil)
+		return err == nil
+	}
+
+	switch e := e.(type) {
+	case *ast.Identifier:
+		return true
+	case *ast.PrefixExpression:
+		return e.Operator == "-" && c.polynomial(e.Right)
+	case *ast.PostfixExpression:
+		return e.Operator == "%" && c.polynomial(e.Left)
+	case *ast.InfixExpression:
+		switch e.Operator {
+		case "+", "-", "*":
+			return c.polynomial(e.Left) && c.polynomial(e.Right)
+		case "/":
+			if c.mentions(e.Right) {
+				return false
+			}
+			k, err := c.ev.Float(e.Right, nil)
+			return err == nil && k != 0 && c.polynomial(e.Left)
+		case "^":
+			_, err := c.exponent(e.Right)
+			return (err == nil || errors.Is(err, ErrTooLarge)) && c.polynomial(e.Left)
+		}
+	}
+	return false
+}
+
+func (c *converter) convert(e ast.Expression) (*Polynomial, error) {
+	if !c.mentions(e) {
+		v, err := c.ev.Float(e, nil)
+		if err != nil {
+			return nil, fmt.Errorf("%w: coefficient %s: %v", ErrNotPolynomial, e, message(err))
+		}
+		return Constant(v, c.vars...), nil
+	}
+
+	switch e := e.(type) {
+	case *ast.Identifier:
+		return Variable(e.Value, c.vars...), nil
+
+	case *ast.PrefixExpression:
+		if e.Operator == "-" {
+			p, err := c.convert(e.Right)
+			if err != nil {
+				return nil, err
+			}
+			return p.Scale(-1), nil
+		}
+
+	case *ast.PostfixExpression:
+		if e.Operator == "%" {
+			p, err := c.convert(e.Left)
+			if err != nil {
+				return nil, err
+			}
+			return p.Scale(0.01), nil
+		}
+
+	case *ast.InfixExpression:
+		return c.convertInfix(e)
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrNotPolynomial, e)
+}
+
+func (c *converter) convertInfix(e *ast.InfixExpression) (*Polynomial, error) {
+	switch e.Operator {
+	case "^":
+		n, err := c.exponent(e.Right)
+		if err != nil {
+			return nil, err
+		}
+		p, err := c.convert(e.Left)
+		if err != nil {
+			return nil, err
+		}
+		return c.pow(p, n, e)
+
+	case "/":
+		if c.mentions(e.Right) {
+			return nil, fmt.Errorf("%w: division by %s", ErrNotPolynomial, e.Right)
+		}
+		d, err := c.convert(e.Right)
+		if err != nil {
+			return nil, err
+		}
+		p, err := c.convert(e.Left)
+		if err != nil {
+			return nil, err
+		}
+		k := d.Coefficient(make([]int, len(c.vars))...)
+		if k == 0 {
+			return nil, fmt.Errorf("%w: division by zero in %s", ErrNotPolynomial, e)
+		}
+		return p.Scale(1 / k), nil
+
+	case "+", "-", "*":
+		p, err := c.convert(e.Left)
+		if err != nil {
+			return nil, err
+		}
+		q, err := c.convert(e.Right)
+		if err != nil {
+			return nil, err
+		}
+		switch e.Operator {
+		case "+":
+			return bounded(p.Add(q), e)
+		case "-":
+			return bounded(p.Sub(q), e)
+		}
+		return c.mul(p, q, e)
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrNotPolynomial, e)
+}
+
+// mul returns the product p q computed for e, unless its degree or number of
+// terms is too large.
+func (c *converter) mul(p, q *Polynomial, e ast.Expression) (*Polynomial, error) {
+	if p.TotalDegree()+q.TotalDegree() > MaxDegree {
+		return nil, fmt.Errorf("%w: %s has a degree above %d", ErrTooLarge, e, MaxDegree)
+	}
+	return bounded(p.Mul(q), e)
+}
+
+// pow returns p^n computed for e by repeated squaring, stopping at the
+// first product whose degree or number of terms is too large.
+func (c *converter) pow(p *Polynomial, n int, e ast.Expression) (*Polynomial, error) {
+	result, base := Constant(1, p.vars...), p
+	for ; n > 0; n >>= 1 {
+		var err error
+		if n&1 == 1 {
+			if result, err = c.mul(result, base, e); err != nil {
+				return nil, err
+			}
+		}
+		if n > 1 {
+			if base, err = c.mul(base, base, e); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// bounded returns p, the value of e, unless it has more than MaxTerms terms.
+func bounded(p *Polynomial, e ast.Expression) (*Polynomial, error) {
+	if len(p.terms) > MaxTerms {
+		return nil, fmt.Errorf("%w: %s has more than %d terms", ErrTooLarge, e, MaxTerms)
+	}
+	return p, nil
+}
+
+// exponent evaluates the exponent of a power of a polynomial, which must be
+// a non-negative integer.
+func (c *converter) exponent(e ast.Expression) (int, error) {
+	if c.mentions(e) {
+		return 0, fmt.Errorf("%w: exponent %s", ErrNotPolynomial, e)
+	}
+	v, err := c.ev.Float(e, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%w: exponent %s: %v", ErrNotPolynomial, e, message(err))
+	}
+	if v < 0 || v != math.Trunc(v) {
+		return 0, fmt.Errorf("%w: exponent %s is not a non-negative integer", ErrNotPolynomial, e)
+	}
+	if v > MaxDegree {
+		return 0, fmt.Errorf("%w: exponent %s is above %d", ErrTooLarge, e, MaxDegree)
+	}
+	return int(v), nil
+}
+
+// message strips the position from an evaluation error, which points into
+// the subexpression rather than the input.
+func message(err error) string {
+	var evalErr *eval.Error
+	if errors.As(err, &evalErr) {
+		return evalErr.Msg
+	}
+	return err.Error()
+}
+
+// mentions reports whether e references one of the variables.
+func (c *converter) mentions(e ast.Expression) bool {
+	switch e := e.(type) {
+	case *ast.Identifier:
+		return slices.Contains(c.vars, e.Value)
+	case *ast.PrefixExpression:
+		return c.mentions(e.Right)
+	case *ast.PostfixExpression:
+		return c.mentions(e.Left)
+	case *ast.InfixExpression:
+		return c.mentions(e.Left) || c.mentions(e.Right)
+	case *ast.ConditionalExpression:
+		return c.mentions(e.Condition) || c.mentions(e.Consequence) ||
+			(e.Alternative != nil && c.mentions(e.Alternative))
+	case *ast.FunctionCall:
+		if integrand, x, lo, hi, ok := e.Integral(); ok {
+			return (!slices.Contains(c.vars, x.Value) && c.mentions(integrand)) ||
+				c.mentions(lo) || c.mentions(hi)
+		}
+		for _, arg := range e.Arguments {
+			if c.mentions(arg) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Expression returns p as an expression with the fewest nodes: terms with
+// the highest total degree first, unit coefficients and exponents left out
+// and negative terms subtracted.
+func (p *Polynomial) Expression() ast.Expression {
+	var e ast.Expression
+	for _, t := range p.Terms() {
+		c := t.Coefficient
+		neg := c < 0 && e != nil
+		if neg {
+			c = -c
+		}
+
+		term := p.monomial(c, t.Exponents)
+		switch {
+		case e == nil:
+			e = term
+		case neg:
+			e = ast.NewInfix(e, "-", term)
+		default:
+			e = ast.NewInfix(e, "+", term)
+		}
+	}
+	if e == nil {
+		return ast.NewNumber(0)
+	}
+	return e
+}
+
+// monomial returns c times the product of the variables raised to exps.
+func (p *Polynomial) monomial(c float64, exps []int) ast.Expression {
+	var e ast.Expression
+	if c != 1 && c != -1 || total(exps) == 0 {
+		e = ast.NewNumber(c)
+	}
+	for i, n := range exps {
+		if n == 0 {
+			continue
+		}
+		var f ast.Expression = ast.NewIdentifier(p.vars[i])
+		if n > 1 {
+			f = ast.NewInfix(f, "^", ast.NewNumber(float64(n)))
+		}
+		if e == nil {
+			e = f
+		} else {
+			e = ast.NewInfix(e, "*", f)
+		}
+	}
+	if c == -1 && total(exps) > 0 {
+		return ast.NewPrefix("-", e)
+	}
+	return e
+}
+
+// Expand multiplies out the products and powers of e, a polynomial in
+// vars, and collects like terms.
+func Expand(e ast.Expression, vars ...string) (ast.Expression, error) {
+	p, err := FromExpression(e, vars...)
+	if err != nil {
+		return nil, err
+	}
+	return p.Expression(), nil
+}
@@ -0,0 +1,409 @@
+// Package polynomial represents polynomials in several variables with real
+// coefficients, converts expressions to and from them, and does arithmetic
+// on them: sums, products, powers, division with remainder and greatest
+// common divisors.
+package polynomial
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ArtroxGabriel/sigma-parser/printer"
+)
+
+var (
+	// ErrDivisionByZero is returned when dividing by the zero polynomial.
+	ErrDivisionByZero = errors.New("division by the zero polynomial")
+
+	// ErrMultivariate is returned by GCD for polynomials in more than one
+	// variable that share one.
+	ErrMultivariate = errors.New("polynomial has more than one variable")
+)
+
+// Term is a coefficient times a monomial.
+type Term struct {
+	Coefficient float64
+	Exponents   []int // the power of each variable, in the polynomial's order
+}
+
+// Polynomial is a sparse sum of terms in a list of variables. The zero
+// value is the zero polynomial in no variables. Polynomials are immutable:
+// every operation returns a new one.
+type Polynomial struct {
+	vars  []string
+	terms map[string]Term // keyed by exponents, without zero coefficients
+}
+
+// Constant returns the polynomial c in vars.
+func Constant(c float64, vars ...string) *Polynomial {
+	return fromTerms(vars, Term{Coefficient: c, Exponents: make([]int, len(vars))})
+}
+
+// Variable returns the polynomial x in vars, adding x to vars if it is
+// missing.
+func Variable(x string, vars ...string) *Polynomial {
+	i := slices.Index(vars, x)
+	if i < 0 {
+		vars = append(slices.Clone(vars), x)
+		i = len(vars) - 1
+	}
+	exps := make([]int, len(vars))
+	exps[i] = 1
+	return fromTerms(vars, Term{Coefficient: 1, Exponents: exps})
+}
+
+// fromTerms returns the sum of terms, whose exponents are in vars.
+func fromTerms(vars []string, terms ...Term) *Polynomial {
+	p := &Polynomial{vars: slices.Clone(vars), terms: make(map[string]Term)}
+	for _, t := range terms {
+		p.add(t)
+	}
+	return p
+}
+
+// add adds t to p in place.
+func (p *Polynomial) add(t Term) {
+	k := key(t.Exponents)
+	c := p.terms[k].Coefficient + t.Coefficient
+	if c == 0 {
+		delete(p.terms, k)
+		return
+	}
+	p.terms[k] = Term{Coefficient: c, Exponents: t.Exponents}
+}
+
+func key(exps []int) string {
+	var b strings.Builder
+	for i, e := range exps {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.Itoa(e))
+	}
+	return b.String()
+}
+
+// Vars returns the variables of p.
+func (p *Polynomial) Vars() []string { return slices.Clone(p.vars) }
+
+// Terms returns the terms of p, highest total degree first and, within a
+// degree, in lexicographic order of the exponents.
+func (p *Polynomial) Terms() []Term {
+	terms := make([]Term, 0, len(p.terms))
+	for _, t := range p.terms {
+		terms = append(terms, Term{Coefficient: t.Coefficient, Exponents: slices.Clone(t.Exponents)})
+	}
+	slices.SortFunc(terms, func(a, b Term) int {
+		if d := total(b.Exponents) - total(a.Exponents); d != 0 {
+			return d
+		}
+		return -lex(a.Exponents, b.Exponents)
+	})
+	return terms
+}
+
+func total(exps []int) int {
+	n := 0
+	for _, e := range exps {
+		n += e
+	}
+	return n
+}
+
+// lex compares monomials lexicographically, the first variable being the
+// most significant.
+func lex(a, b []int) int { return slices.Compare(a, b) }
+
+// IsZero reports whether p is the zero polynomial.
+func (p *Polynomial) IsZero() bool { return len(p.terms) == 0 }
+
+// Coefficient returns the coefficient of the monomial with the given
+// exponents, one per variable.
+func (p *Polynomial) Coefficient(exps ...int) float64 {
+	return p.terms[key(exps)].Coefficient
+}
+
+// Degree returns the highest power of x in p, or -1 for the zero
+// polynomial.
+func (p *Polynomial) Degree(x string) int {
+	if p.IsZero() {
+		return -1
+	}
+	i := slices.Index(p.vars, x)
+	if i < 0 {
+		return 0
+	}
+	d := 0
+	for _, t := range p.terms {
+		d = max(d, t.Exponents[i])
+	}
+	return d
+}
+
+// TotalDegree returns the highest total degree of the terms of p, or -1
+// for the zero polynomial.
+func (p *Polynomial) TotalDegree() int {
+	d := -1
+	for _, t := range p.terms {
+		d = max(d, total(t.Exponents))
+	}
+	return d
+}
+
+// Eval returns the value of p with its variables bound to values. Unbound
+// variables are zero.
+func (p *Polynomial) Eval(values map[string]float64) float64 {
+	sum := 0.0
+	for _, t := range p.terms {
+		v := t.Coefficient
+		for i, e := range t.Exponents {
+			v *= math.Pow(values[p.vars[i]], float64(e))
+		}
+		sum += v
+	}
+	return sum
+}
+
+// Equal reports whether p and q have the same terms.
+func (p *Polynomial) Equal(q *Polynomial) bool {
+	p, q = align(p, q)
+	if len(p.terms) != len(q.terms) {
+		return false
+	}
+	for k, t := range p.terms {
+		if q.terms[k].Coefficient != t.Coefficient {
+			return false
+		}
+	}
+	return true
+}
+
+// align returns p and q rewritten over the union of their variables, those
+// of p first.
+func align(p, q *Polynomial) (*Polynomial, *Polynomial) {
+	if slices.Equal(p.vars, q.vars) {
+		return p, q
+	}
+	vars := slices.Clone(p.vars)
+	for _, x := range q.vars {
+		if !slices.Contains(vars, x) {
+			vars = append(vars, x)
+		}
+	}
+	return p.over(vars), q.over(vars)
+}
+
+// over rewrites p over vars, which must contain its variables.
+func (p *Polynomial) over(vars []string) *Polynomial {
+	out := fromTerms(vars)
+	for _, t := range p.terms {
+		exps := make([]int, len(vars))
+		for i, e := range t.Exponents {
+			exps[slices.Index(vars, p.vars[i])] = e
+		}
+		out.add(Term{Coefficient: t.Coefficient, Exponents: exps})
+	}
+	return out
+}
+
+// Add returns p + q.
+func (p *Polynomial) Add(q *Polynomial) *Polynomial {
+	p, q = align(p, q)
+	out := fromTerms(p.vars)
+	for _, t := range p.terms {
+		out.add(t)
+	}
+	for _, t := range q.terms {
+		out.add(t)
+	}
+	return out
+}
+
+// Sub returns p - q.
+func (p *Polynomial) Sub(q *Polynomial) *Polynomial { return p.Add(q.Scale(-1)) }
+
+// Scale returns c p.
+func (p *Polynomial) Scale(c float64) *Polynomial {
+	out := fromTerms(p.vars)
+	for _, t := range p.terms {
+		out.add(Term{Coefficient: c * t.Coefficient, Exponents: t.Exponents})
+	}
+	return out
+}
+
+// Mul returns p q.
+func (p *Polynomial) Mul(q *Polynomial) *Polynomial {
+	p, q = align(p, q)
+	out := fromTerms(p.vars)
+	for _, s := range p.terms {
+		for _, t := range q.terms {
+			out.add(Term{Coefficient: s.Coefficient * t.Coefficient, Exponents: addExps(s.Exponents, t.Exponents)})
+		}
+	}
+	return out
+}
+
+func addExps(a, b []int) []int {
+	out := make([]int, len(a))
+	for i := range a {
+		out[i] = a[i] + b[i]
+	}
+	return out
+}
+
+// Pow returns p^n for n >= 0, by repeated squaring.
+func (p *Polynomial) Pow(n int) *Polynomial {
+	result, base := Constant(1, p.vars...), p
+	for ; n > 0; n >>= 1 {
+		if n&1 == 1 {
+			result = result.Mul(base)
+		}
+		base = base.Mul(base)
+	}
+	return result
+}
+
+// leading returns the term of p that is greatest in lexicographic order.
+func (p *Polynomial) leading() Term {
+	var lead Term
+	for _, t := range p.terms {
+		if lead.Exponents == nil || lex(t.Exponents, lead.Exponents) > 0 {
+			lead = t
+		}
+	}
+	return lead
+}
+
+// DivMod divides p by q and returns the quotient and remainder, with p =
+// quotient q + remainder. Terms are ordered lexicographically, the first
+// variable being the most significant; no term of the remainder is
+// divisible by the leading term of q. In one variable this is ordinary
+// polynomial long division.
+func (p *Polynomial) DivMod(q *Polynomial) (quo, rem *Polynomial, err error) {
+	if q.IsZero() {
+		return nil, nil, ErrDivisionByZero
+	}
+	p, q = align(p, q)
+	lead := q.leading()
+
+	quo, rem = fromTerms(p.vars), fromTerms(p.vars)
+	r := p
+	for !r.IsZero() {
+		t := r.leading()
+		exps, ok := divides(lead.Exponents, t.Exponents)
+		if !ok {
+			// move the term to the remainder
+			rem.add(t)
+			r = r.Sub(fromTerms(p.vars, t))
+			continue
+		}
+		step := fromTerms(p.vars, Term{Coefficient: t.Coefficient / lead.Coefficient, Exponents: exps})
+		quo = quo.Add(step)
+		r = r.Sub(step.Mul(q))
+		// rounding may leave a tiny leading term behind
+		r.drop(t.Exponents)
+	}
+	return quo, rem, nil
+}
+
+// divides returns b / a for monomials a and b, if a divides b.
+func divides(a, b []int) ([]int, bool) {
+	out := make([]int, len(a))
+	for i := range a {
+		if out[i] = b[i] - a[i]; out[i] < 0 {
+			return nil, false
+		}
+	}
+	return out, true
+}
+
+// drop removes the term with the given exponents from p in place.
+func (p *Polynomial) drop(exps []int) { delete(p.terms, key(exps)) }
+
+// GCD returns the monic greatest common divisor of p and q, polynomials in
+// at most one common variable, by Euclid's algorithm. Remainders whose
+// coefficients are all within 1e-9 of zero, relative to the dividend, count
+// as zero, so that rounding in the coefficients does not hide a common
+// factor. Nonzero polynomials with no variable in common have the GCD 1,
+// whatever their variables.
+func (p *Polynomial) GCD(q *Polynomial) (*Polynomial, error) {
+	p, q = align(p, q)
+	if !p.IsZero() && !q.IsZero() && p.disjoint(q) {
+		return Constant(1, p.vars...), nil
+	}
+	if p.variables() > 1 || q.variables() > 1 || p.Add(q).variables() > 1 {
+		return nil, ErrMultivariate
+	}
+
+	a, b := p, q
+	for !b.IsZero() {
+		_, r, err := a.DivMod(b)
+		if err != nil {
+			return nil, err
+		}
+		a, b = b, r.clean(1e-9*a.norm())
+	}
+	if a.IsZero() {
+		return a, nil
+	}
+	return a.Scale(1 / a.leading().Coefficient), nil
+}
+
+// disjoint reports whether no variable occurs in both p and q, which have
+// the same variables.
+func (p *Polynomial) disjoint(q *Polynomial) bool {
+	for i := range p.vars {
+		if p.occurs(i) && q.occurs(i) {
+			return false
+		}
+	}
+	return true
+}
+
+// occurs reports whether the variable at index i occurs in p.
+func (p *Polynomial) occurs(i int) bool {
+	for _, t := range p.terms {
+		if t.Exponents[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// variables returns the number of variables that occur in p.
+func (p *Polynomial) variables() int {
+	n := 0
+	for i := range p.vars {
+		if p.occurs(i) {
+			n++
+		}
+	}
+	return n
+}
+
+// norm returns the largest magnitude of the coefficients of p.
+func (p *Polynomial) norm() float64 {
+	n := 0.0
+	for _, t := range p.terms {
+		n = math.Max(n, math.Abs(t.Coefficient))
+	}
+	return n
+}
+
+// clean returns p without the terms whose coefficients are within tol of
+// zero.
+func (p *Polynomial) clean(tol float64) *Polynomial {
+	out := fromTerms(p.vars)
+	for _, t := range p.terms {
+		if math.Abs(t.Coefficient) > tol {
+			out.add(t)
+		}
+	}
+	return out
+}
+
+// String returns p in the syntax of the parser.
+func (p *Polynomial) String() string { return printer.Format(p.Expression()) }
@@ -0,0 +1,212 @@
+package polynomial_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ArtroxGabriel/sigma-parser/parser"
+	"github.com/ArtroxGabriel/sigma-parser/polynomial"
+)
+
+func poly(t *testing.T, input string, vars ...string) *polynomial.Polynomial {
+	t.Helper()
+	function, err := parser.Parse(input)
+	if err != nil {
+		t.Fatalf("Parse(%q) error = %v", input, err)
+	}
+	p, err := polynomial.FromExpression(function.Expression, vars...)
+	if err != nil {
+		t.Fatalf("FromExpression(%q) error = %v", input, err)
+	}
+	return p
+}
+
+func TestFromExpression(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"(x + 1)^3", "x^3 + 3 * x^2 + 3 * x + 1"},
+		{"(x - y) * (x + y)", "x^2 - y^2"},
+		{"x * (y - 1)^2 - 3", "x * y^2 - 2 * x * y + x - 3"},
+		{"1 - x", "-x + 1"},
+		{"-(x^2) + 3", "-(x^2) + 3"},
+		{"2 * pi * x / (4 * pi)", "0.5 * x"},
+		{"(x + y)^2 - 2 * x * y", "x^2 + y^2"},
+		{"x^(1 + 1) * 50%", "0.5 * x^2"},
+		{"0 * x", "0"},
+		{"sqrt(4) * y", "2 * y"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := poly(t, tt.input, "x", "y").String(); got != tt.want {
+				t.Errorf("FromExpression() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsPolynomial(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"x^2 + 2 * x * y", true},
+		{"a * x", false},
+		{"sin(x)", false},
+		{"sin(2) * x", true},
+		{"x^0.5", false},
+		{"x^-1", false},
+		{"x^y", false},
+		{"(x + 1) / (x - 1)", false},
+		{"x / 0", false},
+		{"(x + 1)^2000", true},
+		{"((x + 1)^64)^64", true},
+		{"(x + 1)^1024", true},
+		{"(x + 1)^64 * (y + 1)^1000", true},
+		{"(x + y + 1)^200", true},
+		{"(x + 1)^1000", true},
+		{"(x + y)^40", true},
+		{"(x + 1)^0.5", false},
+		{"(x + 1)^2000 / y", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			function, err := parser.Parse(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := polynomial.IsPolynomial(function.Expression, "x", "y"); got != tt.want {
+				t.Errorf("IsPolynomial() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestTooLarge checks that FromExpression refuses polynomials too large to
+// expand, with a different error than for expressions that are not
+// polynomials.
+func TestTooLarge(t *testing.T) {
+	for _, input := range []string{"(x + 1)^2000", "((x + 1)^64)^64", "(x + 1)^64 * (y + 1)^1000", "(x + y + 1)^200"} {
+		function, err := parser.Parse(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = polynomial.FromExpression(function.Expression, "x", "y")
+		if !errors.Is(err, polynomial.ErrTooLarge) || errors.Is(err, polynomial.ErrNotPolynomial) {
+			t.Errorf("FromExpression(%s) error = %v, want ErrTooLarge", input, err)
+		}
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	p := poly(t, "x^2 + 1", "x")
+	q := poly(t, "y - x", "y", "x")
+
+	if got, want := p.Add(q).String(), "x^2 - x + y + 1"; got != want {
+		t.Errorf("Add() = %q, want %q", got, want)
+	}
+	if got, want := p.Sub(p).String(), "0"; got != want {
+		t.Errorf("Sub() = %q, want %q", got, want)
+	}
+	if got, want := p.Mul(q).String(), "-(x^3) + x^2 * y - x + y"; got != want {
+		t.Errorf("Mul() = %q, want %q", got, want)
+	}
+	if got, want := q.Pow(3).Degree("x"), 3; got != want {
+		t.Errorf("Pow(3).Degree(x) = %d, want %d", got, want)
+	}
+	if !p.Mul(q).Equal(q.Mul(p)) {
+		t.Error("Mul is not commutative")
+	}
+	if got := p.Mul(q).Eval(map[string]float64{"x": 2, "y": 5}); got != 15 {
+		t.Errorf("Eval() = %v, want 15", got)
+	}
+}
+
+func TestDivMod(t *testing.T) {
+	tests := []struct {
+		p, q     string
+		quo, rem string
+	}{
+		{"x^3 - 2 * x^2 - 4", "x - 3", "x^2 + x + 3", "5"},
+		{"x^4 - 1", "x^2 + 1", "x^2 - 1", "0"},
+		{"2 * x^2 + 3", "4", "0.5 * x^2 + 0.75", "0"},
+		{"x + 1", "x^2", "0", "x + 1"},
+		{"x^2 * y + x * y^2 + y^2", "x * y - 1", "x + y", "y^2 + x + y"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.p+" / "+tt.q, func(t *testing.T) {
+			p, q := poly(t, tt.p, "x", "y"), poly(t, tt.q, "x", "y")
+			quo, rem, err := p.DivMod(q)
+			if err != nil {
+				t.Fatalf("DivMod() error = %v", err)
+			}
+			if quo.String() != tt.quo || rem.String() != tt.rem {
+				t.Errorf("DivMod() = %s, %s, want %s, %s", quo, rem, tt.quo, tt.rem)
+			}
+			if !quo.Mul(q).Add(rem).Equal(p) {
+				t.Errorf("quotient * divisor + remainder = %s, want %s", quo.Mul(q).Add(rem), p)
+			}
+		})
+	}
+
+	if _, _, err := poly(t, "x", "x").DivMod(poly(t, "0", "x")); !errors.Is(err, polynomial.ErrDivisionByZero) {
+		t.Errorf("DivMod(0) error = %v, want ErrDivisionByZero", err)
+	}
+}
+
+func TestGCD(t *testing.T) {
+	tests := []struct {
+		p, q string
+		want string
+	}{
+		{"x^2 - 1", "x^2 + 2 * x + 1", "x + 1"},
+		{"(x - 2) * (x + 3) * (x - 5)", "4 * (x + 3) * (x - 5)^2", "x^2 - 2 * x - 15"},
+		{"x^2 + 1", "x - 1", "1"},
+		{"3 * x - 6", "0", "x - 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.p+", "+tt.q, func(t *testing.T) {
+			g, err := poly(t, tt.p, "x").GCD(poly(t, tt.q, "x"))
+			if err != nil {
+				t.Fatalf("GCD() error = %v", err)
+			}
+			if g.String() != tt.want {
+				t.Errorf("GCD() = %s, want %s", g, tt.want)
+			}
+		})
+	}
+
+	if _, err := poly(t, "x * y", "x", "y").GCD(poly(t, "x", "x")); !errors.Is(err, polynomial.ErrMultivariate) {
+		t.Errorf("GCD(x y, x) error = %v, want ErrMultivariate", err)
+	}
+
+	// no variable in common
+	for _, pq := range [][2]string{{"x^2", "y"}, {"x * y + 1", "2 * z"}} {
+		g, err := poly(t, pq[0], "x", "y").GCD(poly(t, pq[1], "y", "z"))
+		if err != nil {
+			t.Fatalf("GCD(%s, %s) error = %v", pq[0], pq[1], err)
+		}
+		if g.String() != "1" {
+			t.Errorf("GCD(%s, %s) = %s, want 1", pq[0], pq[1], g)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	function, err := parser.Parse("(a + b)^2 * c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := polynomial.Expand(function.Expression, "a", "b", "c")
+	if err != nil {
+		t.Fatalf("Expand() error = %v", err)
+	}
+	if got, want := e.String(), "((((a ^ 2) * c) + (((2 * a) * b) * c)) + ((b ^ 2) * c))"; got != want {
+		t.Errorf("Expand() = %s, want %s", got, want)
+	}
+}
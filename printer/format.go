@@ -0,0 +1,147 @@
+package printer
+
+import (
+	"bytes"
+
+	"github.com/ArtroxGabriel/sigma-parser/ast"
+)
+
+// Format renders node as source text with only the parentheses the parser
+// needs to rebuild the same tree. Programs get one statement per line.
+func Format(node ast.Node) string {
+	var out bytes.Buffer
+
+	switch node := node.(type) {
+	case *ast.Function:
+		if node.Expression != nil {
+			writeFormat(&out, node.Expression)
+		}
+	case *ast.Equation:
+		writeFormat(&out, node.Left)
+		out.WriteString(" = ")
+		writeFormat(&out, node.Right)
+	case *ast.Program:
+		for i, stmt := range node.Statements {
+			if i > 0 {
+				out.WriteString("\n")
+			}
+			writeFormatStatement(&out, stmt)
+		}
+	case ast.Statement:
+		writeFormatStatement(&out, node)
+	case ast.Expression:
+		writeFormat(&out, node)
+	}
+
+	return out.String()
+}
+
+func writeFormatStatement(out *bytes.Buffer, stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.ExpressionStatement:
+		writeFormat(out, stmt.Expression)
+	case *ast.AssignStatement:
+		out.WriteString(stmt.Name.Value + " = ")
+		writeFormat(out, stmt.Value)
+	case *ast.FunctionDefinition:
+		out.WriteString(stmt.Name.Value + "(")
+		for i, param := range stmt.Parameters {
+			if i > 0 {
+				out.WriteString(", ")
+			}
+			out.WriteString(param.Value)
+		}
+		out.WriteString(") = ")
+		writeFormat(out, stmt.Body)
+	}
+}
+
+func writeFormat(out *bytes.Buffer, e ast.Expression) {
+	switch e := e.(type) {
+	case *ast.NumberLiteral:
+		out.WriteString(e.String())
+	case *ast.Identifier:
+		out.WriteString(e.Value)
+	case *ast.Constant:
+		out.WriteString(e.Name)
+	case *ast.PrefixExpression:
+		out.WriteString(e.Operator)
+		writeFormatOperand(out, e.Right, precedence(e.Right) < precPostfix)
+	case *ast.PostfixExpression:
+		writeFormatOperand(out, e.Left, precedence(e.Left) < precPostfix)
+		out.WriteString(e.Operator)
+	case *ast.InfixExpression:
+		writeFormatOperand(out, e.Left, needsParens(e, e.Left, false))
+		if e.Operator == "^" {
+			out.WriteString(e.Operator)
+		} else {
+			out.WriteString(" " + e.Operator + " ")
+		}
+		writeFormatOperand(out, e.Right, needsParens(e, e.Right, true))
+	case *ast.ConditionalExpression:
+		if args, ok := piecewise(e); ok {
+			writeFormatCall(out, "piecewise", args)
+			return
+		}
+		// the branches are delimited by ? and :, and a conditional in the
+		// alternative groups to the right
+		writeFormatOperand(out, e.Condition, precedence(e.Condition) <= precConditional)
+		out.WriteString(" ? ")
+		writeFormat(out, e.Consequence)
+		out.WriteString(" : ")
+		writeFormat(out, e.Alternative)
+	case *ast.FunctionCall:
+		if e.IsBars() {
+			out.WriteString("|")
+			writeFormatOperand(out, e.Arguments[0], closesBars(e.Arguments[0]))
+			out.WriteString("|")
+			return
+		}
+		writeFormatOperand(out, e.Function, precedence(e.Function) < precAtom)
+		writeFormatArguments(out, e.Arguments)
+	}
+}
+
+// closesBars reports whether e, written between bars, may not parse back:
+// the first | of an || outside parentheses closes the bars. Operands are
+// parenthesized by precedence alone, and a conditional leaves its parts
+// bare, so any || or conditional in e counts.
+func closesBars(e ast.Expression) bool {
+	switch e := e.(type) {
+	case *ast.ConditionalExpression:
+		return true
+	case *ast.InfixExpression:
+		return e.Operator == "||" || closesBars(e.Left) || closesBars(e.Right)
+	case *ast.PrefixExpression:
+		return closesBars(e.Right)
+	case *ast.PostfixExpression:
+		return closesBars(e.Left)
+	}
+	return false
+}
+
+func writeFormatCall(out *bytes.Buffer, name string, args []ast.Expression) {
+	out.WriteString(name)
+	writeFormatArguments(out, args)
+}
+
+func writeFormatArguments(out *bytes.Buffer, args []ast.Expression) {
+	out.WriteString("(")
+	for i, arg := range args {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		writeFormat(out, arg)
+	}
+	out.WriteString(")")
+}
+
+func writeFormatOperand(out *bytes.Buffer, e ast.Expression, parens bool) {
+	if parens {
+		out.WriteString("(")
+		writeFormat(out, e)
+		out.WriteString(")")
+		return
+	}
+	writeFormat(out, e)
+}
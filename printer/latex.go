@@ -0,0 +1,251 @@
+package printer
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/ArtroxGabriel/sigma-parser/ast"
+)
+
+// functions typeset with a dedicated LaTeX command
+var latexFunctions = map[string]string{
+	"sin":  `\sin`,
+	"cos":  `\cos`,
+	"tan":  `\tan`,
+	"asin": `\arcsin`,
+	"acos": `\arccos`,
+	"atan": `\arctan`,
+	"sinh": `\sinh`,
+	"cosh": `\cosh`,
+	"tanh": `\tanh`,
+	"exp":  `\exp`,
+	"ln":   `\ln`,
+	"log":  `\log`,
+	"arg":  `\arg`,
+	"re":   `\operatorname{Re}`,
+	"im":   `\operatorname{Im}`,
+}
+
+// infix operators typeset with a dedicated LaTeX command
+var latexOperators = map[string]string{
+	"*":  `\cdot`,
+	"<=": `\le`,
+	">=": `\ge`,
+	"==": `=`,
+	"!=": `\ne`,
+	"&&": `\land`,
+	"||": `\lor`,
+}
+
+// identifiers typeset as symbols
+var latexSymbols = map[string]string{
+	"pi": `\pi`,
+	"PI": `\pi`,
+}
+
+// LaTeX renders node as a LaTeX math expression.
+func LaTeX(node ast.Node) string {
+	var out bytes.Buffer
+	writeLaTeXNode(&out, node)
+	return out.String()
+}
+
+func writeLaTeXNode(out *bytes.Buffer, node ast.Node) {
+	switch node := node.(type) {
+	case *ast.Function:
+		if node.Expression != nil {
+			writeLaTeX(out, node.Expression)
+		}
+	case *ast.Equation:
+		writeLaTeX(out, node.Left)
+		out.WriteString(" = ")
+		writeLaTeX(out, node.Right)
+	case *ast.Program:
+		for i, stmt := range node.Statements {
+			if i > 0 {
+				out.WriteString(` \\ `)
+			}
+			writeLaTeXNode(out, stmt)
+		}
+	case *ast.ExpressionStatement:
+		writeLaTeX(out, node.Expression)
+	case *ast.AssignStatement:
+		writeLaTeXSymbol(out, node.Name.Value)
+		out.WriteString(" = ")
+		writeLaTeX(out, node.Value)
+	case *ast.FunctionDefinition:
+		writeLaTeXCall(out, &ast.FunctionCall{
+			Function:  node.Name,
+			Arguments: identifiers(node.Parameters),
+		})
+		out.WriteString(" = ")
+		writeLaTeX(out, node.Body)
+	case ast.Expression:
+		writeLaTeX(out, node)
+	}
+}
+
+func identifiers(params []*ast.Identifier) []ast.Expression {
+	list := make([]ast.Expression, len(params))
+	for i, p := range params {
+		list[i] = p
+	}
+	return list
+}
+
+func writeLaTeX(out *bytes.Buffer, e ast.Expression) {
+	switch e := e.(type) {
+	case *ast.NumberLiteral:
+		out.WriteString(e.String())
+	case *ast.Identifier:
+		writeLaTeXSymbol(out, e.Value)
+	case *ast.Constant:
+		writeLaTeXSymbol(out, e.Name)
+	case *ast.PrefixExpression:
+		if e.Operator == "!" {
+			out.WriteString(`\lnot `)
+		} else {
+			out.WriteString(e.Operator)
+		}
+		writeLaTeXOperand(out, e.Right, precedence(e.Right) < precPower)
+	case *ast.PostfixExpression:
+		writeLaTeXOperand(out, e.Left, precedence(e.Left) < precPostfix)
+		if e.Operator == "%" {
+			out.WriteString(`\%`)
+		} else {
+			out.WriteString(e.Operator)
+		}
+	case *ast.InfixExpression:
+		writeLaTeXInfix(out, e)
+	case *ast.ConditionalExpression:
+		writeLaTeXCases(out, e)
+	case *ast.FunctionCall:
+		writeLaTeXCall(out, e)
+	}
+}
+
+func writeLaTeXSymbol(out *bytes.Buffer, name string) {
+	if sym, ok := latexSymbols[name]; ok {
+		out.WriteString(sym)
+		return
+	}
+	if len(name) > 1 {
+		out.WriteString(`\mathrm{` + strings.ReplaceAll(name, "_", `\_`) + `}`)
+		return
+	}
+	out.WriteString(name)
+}
+
+func writeLaTeXInfix(out *bytes.Buffer, e *ast.InfixExpression) {
+	switch e.Operator {
+	case "/":
+		out.WriteString(`\frac{`)
+		writeLaTeX(out, e.Left)
+		out.WriteString(`}{`)
+		writeLaTeX(out, e.Right)
+		out.WriteString(`}`)
+	case "^":
+		// a superscripted base always needs parentheses in LaTeX
+		writeLaTeXOperand(out, e.Left, precedence(e.Left) <= precPower || needsParens(e, e.Left, false))
+		out.WriteString(`^{`)
+		writeLaTeX(out, e.Right)
+		out.WriteString(`}`)
+	default:
+		op := " " + e.Operator + " "
+		if cmd, ok := latexOperators[e.Operator]; ok {
+			op = " " + cmd + " "
+		}
+		writeLaTeXOperand(out, e.Left, needsLaTeXParens(e, e.Left, false))
+		out.WriteString(op)
+		writeLaTeXOperand(out, e.Right, needsLaTeXParens(e, e.Right, true))
+	}
+}
+
+// needsLaTeXParens is needsParens for the operators that LaTeX does not
+// typeset structurally: a fraction on either side never needs parentheses.
+func needsLaTeXParens(parent *ast.InfixExpression, child ast.Expression, right bool) bool {
+	if childOperator(child) == "/" {
+		return false
+	}
+	return needsParens(parent, child, right)
+}
+
+// writeLaTeXCases typesets a chain of conditional expressions as a single
+// cases environment, one row per branch.
+func writeLaTeXCases(out *bytes.Buffer, e *ast.ConditionalExpression) {
+	out.WriteString(`\begin{cases} `)
+	for {
+		writeLaTeX(out, e.Consequence)
+		out.WriteString(` & \text{if } `)
+		writeLaTeX(out, e.Condition)
+
+		next, ok := e.Alternative.(*ast.ConditionalExpression)
+		if !ok {
+			break
+		}
+		out.WriteString(` \\ `)
+		e = next
+	}
+	if e.Alternative != nil {
+		out.WriteString(` \\ `)
+		writeLaTeX(out, e.Alternative)
+		out.WriteString(` & \text{otherwise}`)
+	}
+	out.WriteString(` \end{cases}`)
+}
+
+func writeLaTeXCall(out *bytes.Buffer, e *ast.FunctionCall) {
+	name := e.Function.String()
+	if ident, ok := e.Function.(*ast.Identifier); ok {
+		name = ident.Value
+	}
+
+	if name == "sqrt" && len(e.Arguments) == 1 {
+		out.WriteString(`\sqrt{`)
+		writeLaTeX(out, e.Arguments[0])
+		out.WriteString(`}`)
+		return
+	}
+	if integrand, x, lo, hi, ok := e.Integral(); ok {
+		out.WriteString(`\int_{`)
+		writeLaTeX(out, lo)
+		out.WriteString(`}^{`)
+		writeLaTeX(out, hi)
+		out.WriteString(`} `)
+		writeLaTeXOperand(out, integrand, precedence(integrand) < precProduct)
+		out.WriteString(`\,\mathrm{d}`)
+		writeLaTeXSymbol(out, x.Value)
+		return
+	}
+	if name == "abs" && len(e.Arguments) == 1 {
+		out.WriteString(`\left|`)
+		writeLaTeX(out, e.Arguments[0])
+		out.WriteString(`\right|`)
+		return
+	}
+
+	if cmd, ok := latexFunctions[name]; ok {
+		out.WriteString(cmd)
+	} else {
+		out.WriteString(`\operatorname{` + name + `}`)
+	}
+
+	out.WriteString(`\left(`)
+	for i, arg := range e.Arguments {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		writeLaTeX(out, arg)
+	}
+	out.WriteString(`\right)`)
+}
+
+func writeLaTeXOperand(out *bytes.Buffer, e ast.Expression, parens bool) {
+	if parens {
+		out.WriteString(`\left(`)
+		writeLaTeX(out, e)
+		out.WriteString(`\right)`)
+		return
+	}
+	writeLaTeX(out, e)
+}
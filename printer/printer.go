@@ -0,0 +1,93 @@
+// Package printer renders AST nodes for humans: as LaTeX and as an indented
+// tree.
+package printer
+
+import (
+	"github.com/ArtroxGabriel/sigma-parser/ast"
+)
+
+// binding strength of each node when printed without redundant parentheses
+const (
+	_ int = iota
+	precConditional
+	precOr
+	precAnd
+	precEquals
+	precCompare
+	precSum
+	precProduct
+	precPower
+	precPrefix  // the parser binds unary minus tighter than ^: -x^2 is (-x)^2
+	precPostfix // and postfix operators tighter still: -3! is -(3!)
+	precAtom
+)
+
+// precedence returns how tightly e binds when printed in infix form.
+func precedence(e ast.Expression) int {
+	switch e := e.(type) {
+	case *ast.InfixExpression:
+		switch e.Operator {
+		case "||":
+			return precOr
+		case "&&":
+			return precAnd
+		case "==", "!=":
+			return precEquals
+		case "<", "<=", ">", ">=":
+			return precCompare
+		case "+", "-":
+			return precSum
+		case "*", "/":
+			return precProduct
+		case "^":
+			return precPower
+		}
+	case *ast.PrefixExpression:
+		return precPrefix
+	case *ast.PostfixExpression:
+		return precPostfix
+	case *ast.ConditionalExpression:
+		return precConditional
+	case *ast.NumberLiteral:
+		// built rather than parsed literals may be negative
+		if e.Value < 0 {
+			return precPrefix
+		}
+	}
+	return precAtom
+}
+
+// needsParens reports whether child must be wrapped in parentheses when it
+// appears on the given side of the infix expression parent.
+func needsParens(parent *ast.InfixExpression, child ast.Expression, right bool) bool {
+	pp, cp := precedence(parent), precedence(child)
+	if cp != pp {
+		// a negated base still needs parentheses: (-x)^2 is not -x^2
+		return cp < pp || (parent.Operator == "^" && !right && cp == precPrefix)
+	}
+	// every binary operator in the grammar is left associative, so only the
+	// right operand keeps its parentheses: a - (b - c), (a ^ b) ^ c = a ^ b ^ c
+	return right
+}
+
+func childOperator(e ast.Expression) string {
+	if ie, ok := e.(*ast.InfixExpression); ok {
+		return ie.Operator
+	}
+	return ""
+}
+
+// piecewise returns the conditions and values of a chain of conditional
+// expressions whose last alternative is missing, which only the piecewise
+// form can express.
+func piecewise(e *ast.ConditionalExpression) ([]ast.Expression, bool) {
+	var args []ast.Expression
+	for {
+		args = append(args, e.Condition, e.Consequence)
+		next, ok := e.Alternative.(*ast.ConditionalExpression)
+		if !ok {
+			return args, e.Alternative == nil
+		}
+		e = next
+	}
+}
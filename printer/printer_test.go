@@ -0,0 +1,201 @@
+package printer_test
+
+import (
+	"testing"
+
+	"github.com/ArtroxGabriel/sigma-parser/lexer"
+	"github.com/ArtroxGabriel/sigma-parser/parser"
+	"github.com/ArtroxGabriel/sigma-parser/printer"
+)
+
+func TestLaTeX(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"x + 1", "x + 1"},
+		{"(a + b) * c", `\left(a + b\right) \cdot c`},
+		{"a - (b - c)", `a - \left(b - c\right)`},
+		{"(x + 1) / 2", `\frac{x + 1}{2}`},
+		{"x ^ (n + 1)", "x^{n + 1}"},
+		{"(x ^ 2) ^ 3", `\left(x^{2}\right)^{3}`},
+		{"-x ^ 2", `\left(-x\right)^{2}`},
+		{"-(a + b)", `-\left(a + b\right)`},
+		{"2 * pi * r", `2 \cdot \pi \cdot r`},
+		{"sqrt(x ^ 2 + y ^ 2)", `\sqrt{x^{2} + y^{2}}`},
+		{"sin(theta)", `\sin\left(\mathrm{theta}\right)`},
+		{"abs(x - 3)", `\left|x - 3\right|`},
+		{"|a - |b||", `\left|a - \left|b\right|\right|`},
+		{"f(x, y)", `\operatorname{f}\left(x, y\right)`},
+		{"n!", "n!"},
+		{"(n + 1)!", `\left(n + 1\right)!`},
+		{"15%", `15\%`},
+		{"a <= b && c != d", `a \le b \land c \ne d`},
+		{"!(a || b)", `\lnot \left(a \lor b\right)`},
+		{"x < 10 ? a : b", `\begin{cases} a & \text{if } x < 10 \\ b & \text{otherwise} \end{cases}`},
+		{"piecewise(x < 0, -x, x < 1, x ^ 2)", `\begin{cases} -x & \text{if } x < 0 \\ x^{2} & \text{if } x < 1 \end{cases}`},
+		{"integrate(x ^ 2, x, 0, 1)", `\int_{0}^{1} x^{2}\,\mathrm{d}x`},
+		{"integrate(t + 1, t, a, b)", `\int_{a}^{b} \left(t + 1\right)\,\mathrm{d}t`},
+		{"2 * if(x > 0, x, 0)", `2 \cdot \left(\begin{cases} x & \text{if } x > 0 \\ 0 & \text{otherwise} \end{cases}\right)`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			p := parser.New(lexer.New(tt.input))
+			function := p.ParseFunction()
+			if len(p.Errors()) != 0 {
+				t.Fatalf("parser errors: %v", p.Errors())
+			}
+
+			if got := printer.LaTeX(function); got != tt.want {
+				t.Errorf("LaTeX() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTree(t *testing.T) {
+	p := parser.New(lexer.New("-x * sin(2)"))
+	function := p.ParseFunction()
+
+	want := `Function
+  InfixExpression *
+    PrefixExpression -
+      Identifier x
+    FunctionCall
+      Identifier sin
+      NumberLiteral 2
+`
+	if got := printer.Tree(function); got != want {
+		t.Errorf("Tree() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestTreeConditional(t *testing.T) {
+	p := parser.New(lexer.New("piecewise(!c, 1)"))
+	function := p.ParseFunction()
+
+	want := `Function
+  ConditionalExpression
+    PrefixExpression !
+      Identifier c
+    NumberLiteral 1
+    <nil>
+`
+	if got := printer.Tree(function); got != want {
+		t.Errorf("Tree() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"x+1", "x + 1"},
+		{"((a + b)) * c", "(a + b) * c"},
+		{"a + (b + c)", "a + (b + c)"},
+		{"(a + b) + c", "a + b + c"},
+		{"a - (b - c)", "a - (b - c)"},
+		{"a / (b * c)", "a / (b * c)"},
+		{"x ^ 2 + 1", "x^2 + 1"},
+		{"x ^ (y ^ 2)", "x^(y^2)"},
+		{"(x ^ y) ^ 2", "x^y^2"},
+		{"-x ^ 2", "(-x)^2"},
+		{"-(x ^ 2)", "-(x^2)"},
+		{"-(a * b)", "-(a * b)"},
+		{"-sin(x)", "-sin(x)"},
+		{"n!", "n!"},
+		{"(n + 1)!", "(n + 1)!"},
+		{"-3!", "-3!"},
+		{"(-3)!", "(-3)!"},
+		{"x ^ 2!", "x^2!"},
+		{"(x ^ 2)!", "(x^2)!"},
+		{"15 %", "15%"},
+		{"atan2(y, x + 1)", "atan2(y, x + 1)"},
+		{"abs(x)", "abs(x)"},
+		{"|x-3|", "|x - 3|"},
+		{"|a - |b||", "|a - |b||"},
+		{"||a| - b|", "||a| - b|"},
+		{"||a||", "||a||"},
+		{"-|x|^2", "(-|x|)^2"},
+		{"|a| || b", "|a| || b"},
+		{"|(a || b)|", "|(a || b)|"},
+		{"|(a || b) && c|", "|((a || b) && c)|"},
+		{"|(a ? b : c)|", "|(a ? b : c)|"},
+		{"|-(a || b)|", "|(-(a || b))|"},
+		{"a+1<b", "a + 1 < b"},
+		{"(a < b) == (c < d)", "a < b == c < d"},
+		{"a < (b == c)", "a < (b == c)"},
+		{"a || b && c", "a || b && c"},
+		{"(a || b) && c", "(a || b) && c"},
+		{"!(a && b)", "!(a && b)"},
+		{"!n!", "!n!"},
+		{"x < 10 ? a : b", "x < 10 ? a : b"},
+		{"a ? b : (c ? d : e)", "a ? b : c ? d : e"},
+		{"(a ? b : c) ? d : e", "(a ? b : c) ? d : e"},
+		{"a ? (b ? c : d) : e", "a ? b ? c : d : e"},
+		{"(a ? b : c) + 1", "(a ? b : c) + 1"},
+		{"if(x > 0, x, 0)", "x > 0 ? x : 0"},
+		{"piecewise(x < 0, -x, x < 1, x)", "piecewise(x < 0, -x, x < 1, x)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			p := parser.New(lexer.New(tt.input))
+			function := p.ParseFunction()
+			if len(p.Errors()) != 0 {
+				t.Fatalf("parser errors: %v", p.Errors())
+			}
+
+			got := printer.Format(function)
+			if got != tt.want {
+				t.Fatalf("Format() = %q, want %q", got, tt.want)
+			}
+
+			// the formatted text must parse back to the same tree
+			p = parser.New(lexer.New(got))
+			if again := p.ParseFunction(); again.String() != function.String() {
+				t.Errorf("Format() round trip = %s, want %s", again, function)
+			}
+		})
+	}
+}
+
+func TestProgram(t *testing.T) {
+	program, err := parser.ParseProgram("f(x,y)=x^2*y; k=f(1,2)\nk+1")
+	if err != nil {
+		t.Fatalf("ParseProgram() error = %v", err)
+	}
+
+	wantFormat := "f(x, y) = x^2 * y\nk = f(1, 2)\nk + 1"
+	if got := printer.Format(program); got != wantFormat {
+		t.Errorf("Format() = %q, want %q", got, wantFormat)
+	}
+
+	wantLaTeX := `\operatorname{f}\left(x, y\right) = x^{2} \cdot y \\ k = \operatorname{f}\left(1, 2\right) \\ k + 1`
+	if got := printer.LaTeX(program); got != wantLaTeX {
+		t.Errorf("LaTeX() = %q, want %q", got, wantLaTeX)
+	}
+
+	wantTree := `Program
+  FunctionDefinition f(x, y)
+    InfixExpression *
+      InfixExpression ^
+        Identifier x
+        NumberLiteral 2
+      Identifier y
+  AssignStatement k
+    FunctionCall
+      Identifier f
+      NumberLiteral 1
+      NumberLiteral 2
+  ExpressionStatement
+    InfixExpression +
+      Identifier k
+      NumberLiteral 1
+`
+	if got := printer.Tree(program); got != wantTree {
+		t.Errorf("Tree() =\n%s\nwant\n%s", got, wantTree)
+	}
+}
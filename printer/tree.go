@@ -0,0 +1,77 @@
+package printer
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/ArtroxGabriel/sigma-parser/ast"
+)
+
+// Tree renders node as an indented tree, one node per line.
+func Tree(node ast.Node) string {
+	var out bytes.Buffer
+	writeTree(&out, node, 0)
+	return out.String()
+}
+
+func writeTree(out *bytes.Buffer, node ast.Node, depth int) {
+	indent := strings.Repeat("  ", depth)
+
+	switch node := node.(type) {
+	case *ast.Function:
+		fmt.Fprintf(out, "%sFunction\n", indent)
+		writeTree(out, node.Expression, depth+1)
+	case *ast.Equation:
+		fmt.Fprintf(out, "%sEquation\n", indent)
+		writeTree(out, node.Left, depth+1)
+		writeTree(out, node.Right, depth+1)
+	case *ast.Program:
+		fmt.Fprintf(out, "%sProgram\n", indent)
+		for _, stmt := range node.Statements {
+			writeTree(out, stmt, depth+1)
+		}
+	case *ast.ExpressionStatement:
+		fmt.Fprintf(out, "%sExpressionStatement\n", indent)
+		writeTree(out, node.Expression, depth+1)
+	case *ast.AssignStatement:
+		fmt.Fprintf(out, "%sAssignStatement %s\n", indent, node.Name.Value)
+		writeTree(out, node.Value, depth+1)
+	case *ast.FunctionDefinition:
+		params := make([]string, len(node.Parameters))
+		for i, p := range node.Parameters {
+			params[i] = p.Value
+		}
+		fmt.Fprintf(out, "%sFunctionDefinition %s(%s)\n", indent, node.Name.Value, strings.Join(params, ", "))
+		writeTree(out, node.Body, depth+1)
+	case *ast.NumberLiteral:
+		fmt.Fprintf(out, "%sNumberLiteral %s\n", indent, node.Token.Literal)
+	case *ast.Identifier:
+		fmt.Fprintf(out, "%sIdentifier %s\n", indent, node.Value)
+	case *ast.Constant:
+		fmt.Fprintf(out, "%sConstant %s\n", indent, node.Name)
+	case *ast.PrefixExpression:
+		fmt.Fprintf(out, "%sPrefixExpression %s\n", indent, node.Operator)
+		writeTree(out, node.Right, depth+1)
+	case *ast.PostfixExpression:
+		fmt.Fprintf(out, "%sPostfixExpression %s\n", indent, node.Operator)
+		writeTree(out, node.Left, depth+1)
+	case *ast.InfixExpression:
+		fmt.Fprintf(out, "%sInfixExpression %s\n", indent, node.Operator)
+		writeTree(out, node.Left, depth+1)
+		writeTree(out, node.Right, depth+1)
+	case *ast.ConditionalExpression:
+		fmt.Fprintf(out, "%sConditionalExpression\n", indent)
+		writeTree(out, node.Condition, depth+1)
+		writeTree(out, node.Consequence, depth+1)
+		writeTree(out, node.Alternative, depth+1)
+	case *ast.FunctionCall:
+		fmt.Fprintf(out, "%sFunctionCall\n", indent)
+		writeTree(out, node.Function, depth+1)
+		for _, arg := range node.Arguments {
+			writeTree(out, arg, depth+1)
+		}
+	default:
+		fmt.Fprintf(out, "%s<nil>\n", indent)
+	}
+}
@@ -0,0 +1,193 @@
+// Package quadrature computes definite integrals of functions of one
+// variable numerically, with an estimate of the error.
+package quadrature
+
+import (
+	"math"
+)
+
+// Defaults for the options.
+const (
+	DefaultTolerance      = 1e-10
+	DefaultMaxEvaluations = 100000
+)
+
+// Result is an integral and an estimate of its absolute error. Converged
+// reports whether the estimate met the tolerance within the evaluation
+// budget; when it did not, Value is the best approximation found.
+type Result struct {
+	Value       float64
+	Error       float64
+	Evaluations int
+	Converged   bool
+}
+
+type config struct {
+	tol     float64
+	maxEval int
+}
+
+// Option configures an integration.
+type Option func(*config)
+
+// WithTolerance asks for an error below tol, relative to the integral when
+// that is above one.
+func WithTolerance(tol float64) Option {
+	return func(c *config) { c.tol = tol }
+}
+
+// WithMaxEvaluations limits an integration to about n evaluations of the
+// integrand.
+func WithMaxEvaluations(n int) Option {
+	return func(c *config) { c.maxEval = n }
+}
+
+func newConfig(opts []Option) config {
+	c := config{tol: DefaultTolerance, maxEval: DefaultMaxEvaluations}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// accurate reports whether an error estimate is within tolerance for a
+// finite value.
+func (c config) accurate(value, err float64) bool {
+	return err <= c.tol*math.Max(1, math.Abs(value)) && !math.IsInf(value, 0) && !math.IsNaN(value)
+}
+
+// Integrate integrates f over [a, b], either bound of which may be
+// infinite. It uses Gauss–Kronrod quadrature, or tanh-sinh quadrature when f
+// is not finite at a bound, and falls back to the other method if the first
+// does not converge.
+func Integrate(f func(float64) float64, a, b float64, opts ...Option) Result {
+	first, second := GaussKronrod, TanhSinh
+	if singular(f, a) || singular(f, b) {
+		first, second = TanhSinh, GaussKronrod
+	}
+
+	r := first(f, a, b, opts...)
+	r.Evaluations += 2
+	if r.Converged {
+		return r
+	}
+	alt := second(f, a, b, opts...)
+	alt.Evaluations += r.Evaluations
+	if alt.Converged || alt.Error < r.Error || math.IsNaN(r.Value) {
+		return alt
+	}
+	r.Evaluations = alt.Evaluations
+	return r
+}
+
+// singular reports whether f has no finite value at the finite bound x.
+func singular(f func(float64) float64, x float64) bool {
+	if math.IsInf(x, 0) {
+		return false
+	}
+	v := f(x)
+	return math.IsInf(v, 0) || math.IsNaN(v)
+}
+
+// oriented integrates over [a, b] with a <= b, negating the result of
+// integrate for reversed bounds.
+func oriented(a, b float64, integrate func(a, b float64) Result) Result {
+	switch {
+	case a == b:
+		return Result{Converged: true}
+	case a > b:
+		r := integrate(b, a)
+		r.Value = -r.Value
+		return r
+	}
+	return integrate(a, b)
+}
+
+// finite maps an integral over an infinite range onto one over a finite
+// range of t, returning the new integrand and bounds. The Jacobians are
+// divided out one factor at a time, since their squares may underflow.
+func finite(f func(float64) float64, a, b float64) (func(float64) float64, float64, float64) {
+	switch {
+	case math.IsInf(a, -1) && math.IsInf(b, 1):
+		// x = t / (1 - t^2) over (-1, 1)
+		return func(t float64) float64 {
+			s := 1 - t*t
+			return f(t/s) * (1 + t*t) / s / s
+		}, -1, 1
+	case math.IsInf(b, 1):
+		// x = a + t / (1 - t) over [0, 1)
+		return func(t float64) float64 {
+			s := 1 - t
+			return f(a+t/s) / s / s
+		}, 0, 1
+	case math.IsInf(a, -1):
+		// x = b - (1 - t) / t over (0, 1]
+		return func(t float64) float64 {
+			return f(b-(1-t)/t) / t / t
+		}, 0, 1
+	}
+	return f, a, b
+}
+
+// Simpson integrates f over the finite range [a, b] by adaptive Simpson's
+// rule, halving each panel until Richardson's estimate of its error is
+// small enough. It is simple and quick for smooth integrands.
+func Simpson(f func(float64) float64, a, b float64, opts ...Option) Result {
+	c := newConfig(opts)
+	if math.IsInf(a, 0) || math.IsInf(b, 0) {
+		return Result{Value: math.NaN(), Error: math.Inf(1)}
+	}
+
+	return oriented(a, b, func(a, b float64) Result {
+		s := &simpson{f: f, c: c}
+		fa, fm, fb := f(a), f((a+b)/2), f(b)
+		s.evals = 3
+		whole := (b - a) / 6 * (fa + 4*fm + fb)
+
+		// the first pass finds the size of the integral, the tolerance is
+		// relative to
+		value, err := s.panel(a, b, fa, fm, fb, whole, c.tol*math.Max(1, math.Abs(whole)), 0)
+		return Result{
+			Value:       value,
+			Error:       err,
+			Evaluations: s.evals,
+			Converged:   !s.truncated && c.accurate(value, err),
+		}
+	})
+}
+
+type simpson struct {
+	f         func(float64) float64
+	c         config
+	evals     int
+	truncated bool // a panel was accepted without meeting its tolerance
+}
+
+const maxDepth = 50
+
+// panel integrates over [a, b] given f at its ends and middle and the
+// Simpson estimate over the whole, and returns the integral and its error.
+func (s *simpson) panel(a, b, fa, fm, fb, whole, tol float64, depth int) (float64, float64) {
+	m := (a + b) / 2
+	lm, rm := (a+m)/2, (m+b)/2
+	flm, frm := s.f(lm), s.f(rm)
+	s.evals += 2
+
+	left := (m - a) / 6 * (fa + 4*flm + fm)
+	right := (b - m) / 6 * (fm + 4*frm + fb)
+	diff := left + right - whole
+
+	// |diff| / 15 estimates the error of the refined sum, which the
+	// correction diff / 15 then improves on
+	if math.Abs(diff) <= 15*tol || m <= a || b <= m {
+		return left + right + diff/15, math.Abs(diff) / 15
+	}
+	if depth >= maxDepth || s.evals >= s.c.maxEval || math.IsNaN(diff) {
+		s.truncated = true
+		return left + right + diff/15, math.Abs(diff) / 15
+	}
+
+	lv, le := s.panel(a, m, fa, flm, fm, left, tol/2, depth+1)
+	rv, re := s.panel(m, b, fm, frm, fb, right, tol/2, depth+1)
+	return lv + rv, le + re
+}
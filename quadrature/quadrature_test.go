@@ -0,0 +1,131 @@
+package quadrature_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ArtroxGabriel/sigma-parser/quadrature"
+)
+
+type method func(func(float64) float64, float64, float64, ...quadrature.Option) quadrature.Result
+
+var methods = map[string]method{
+	"simpson":      quadrature.Simpson,
+	"gausskronrod": quadrature.GaussKronrod,
+	"tanhsinh":     quadrature.TanhSinh,
+	"integrate":    quadrature.Integrate,
+}
+
+var smooth = []struct {
+	name string
+	f    func(float64) float64
+	a, b float64
+	want float64
+}{
+	{"polynomial", func(x float64) float64 { return x*x*x - 2*x + 1 }, 0, 2, 2},
+	{"sine", math.Sin, 0, math.Pi, 2},
+	{"gaussian", func(x float64) float64 { return math.Exp(-x * x) }, -3, 3, math.Sqrt(math.Pi) * math.Erf(3)},
+	{"reversed", math.Exp, 1, 0, 1 - math.E},
+	{"empty", math.Exp, 2, 2, 0},
+	{"oscillating", func(x float64) float64 { return math.Cos(20 * x) }, 0, 1, math.Sin(20) / 20},
+	{"runge", func(x float64) float64 { return 1 / (1 + 25*x*x) }, -1, 1, 2 * math.Atan(5) / 5},
+}
+
+func TestSmooth(t *testing.T) {
+	for name, integrate := range methods {
+		for _, tt := range smooth {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				r := integrate(tt.f, tt.a, tt.b)
+				if !r.Converged {
+					t.Errorf("did not converge: %+v", r)
+				}
+				if math.Abs(r.Value-tt.want) > 1e-8 {
+					t.Errorf("integral = %v, want %v (error estimate %g)", r.Value, tt.want, r.Error)
+				}
+				if math.Abs(r.Value-tt.want) > r.Error+1e-14 && tt.a != tt.b {
+					t.Errorf("error %g exceeds the estimate %g", math.Abs(r.Value-tt.want), r.Error)
+				}
+			})
+		}
+	}
+}
+
+// TestEndpointSingularity checks that tanh-sinh handles integrable
+// singularities at the bounds that defeat the other rules.
+func TestEndpointSingularity(t *testing.T) {
+	tests := []struct {
+		name string
+		f    func(float64) float64
+		a, b float64
+		want float64
+	}{
+		{"inverse sqrt", func(x float64) float64 { return 1 / math.Sqrt(x) }, 0, 1, 2},
+		{"log", math.Log, 0, 1, -1},
+		{"x log x", func(x float64) float64 { return x * math.Log(x) }, 0, 1, -0.25},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, integrate := range []method{quadrature.TanhSinh, quadrature.Integrate} {
+				r := integrate(tt.f, tt.a, tt.b)
+				if !r.Converged || math.Abs(r.Value-tt.want) > 1e-8 {
+					t.Errorf("integral = %+v, want %v", r, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// TestRoundingNearBounds integrates a function that loses precision near
+// both bounds, where 1 - x^2 cancels, so that no float64 rule can be as
+// accurate as the tolerance asks.
+func TestRoundingNearBounds(t *testing.T) {
+	f := func(x float64) float64 { return 1 / math.Sqrt(1-x*x) }
+	r := quadrature.Integrate(f, -1, 1)
+	if math.Abs(r.Value-math.Pi) > 1e-7 {
+		t.Errorf("integral = %+v, want %v", r, math.Pi)
+	}
+}
+
+func TestInfiniteRange(t *testing.T) {
+	tests := []struct {
+		name string
+		f    func(float64) float64
+		a, b float64
+		want float64
+	}{
+		{"gaussian", func(x float64) float64 { return math.Exp(-x * x) }, math.Inf(-1), math.Inf(1), math.Sqrt(math.Pi)},
+		{"decay", func(x float64) float64 { return math.Exp(-x) }, 0, math.Inf(1), 1},
+		{"left tail", func(x float64) float64 { return 1 / (1 + x*x) }, math.Inf(-1), 0, math.Pi / 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, integrate := range map[string]method{"gausskronrod": quadrature.GaussKronrod, "tanhsinh": quadrature.TanhSinh} {
+				r := integrate(tt.f, tt.a, tt.b)
+				if !r.Converged || math.Abs(r.Value-tt.want) > 1e-8 {
+					t.Errorf("%s = %+v, want %v", name, r, tt.want)
+				}
+			}
+		})
+	}
+
+	if r := quadrature.Simpson(math.Exp, math.Inf(-1), 0); r.Converged || !math.IsNaN(r.Value) {
+		t.Errorf("Simpson over an infinite range = %+v, want NaN", r)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	f := func(x float64) float64 { return math.Sqrt(x) }
+
+	coarse := quadrature.Simpson(f, 0, 1, quadrature.WithTolerance(1e-3))
+	fine := quadrature.Simpson(f, 0, 1, quadrature.WithTolerance(1e-12))
+	if coarse.Evaluations >= fine.Evaluations {
+		t.Errorf("coarse took %d evaluations, fine %d", coarse.Evaluations, fine.Evaluations)
+	}
+
+	r := quadrature.GaussKronrod(func(x float64) float64 { return 1 / x }, 0, 1, quadrature.WithMaxEvaluations(500))
+	if r.Converged || r.Evaluations > 500 {
+		t.Errorf("divergent integral = %+v, want no convergence within 500 evaluations", r)
+	}
+}
@@ -0,0 +1,233 @@
+// Package repl implements the interactive read-eval-print loop.
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/ArtroxGabriel/sigma-parser/ast"
+	"github.com/ArtroxGabriel/sigma-parser/eval"
+	"github.com/ArtroxGabriel/sigma-parser/lexer"
+	"github.com/ArtroxGabriel/sigma-parser/object"
+	"github.com/ArtroxGabriel/sigma-parser/parser"
+	"github.com/ArtroxGabriel/sigma-parser/printer"
+	"github.com/ArtroxGabriel/sigma-parser/token"
+)
+
+const (
+	PROMPT   = ">> "
+	CONTINUE = ".. "
+)
+
+const help = `Enter an expression to evaluate it, or one of:
+  x = <expr>       bind x for the rest of the session (also let x = <expr>)
+  f(x) = <expr>    define a function, calls to it may not recurse
+  a = 1; a + 1     run several statements, separated by ; or new lines
+  :ast <expr>      print the syntax tree
+  :tokens <expr>   print the tokens
+  :latex <expr>    print the LaTeX rendering
+  :vars            list bound variables and functions
+  :mode [mode]     show or set the number mode: real, complex, rational
+                   or bigfloat
+  :history         list previous inputs
+  :help            show this message
+  :quit            leave the REPL
+`
+
+var letStatement = regexp.MustCompile(`^let\s+([A-Za-z_][A-Za-z0-9_]*)\s*=(.*)$`)
+
+// session holds the state that persists between inputs.
+type session struct {
+	out     io.Writer
+	env     *object.Environment
+	mode    eval.Mode
+	history []string
+}
+
+// Start reads inputs from in until EOF or :quit and writes results to out.
+func Start(in io.Reader, out io.Writer) {
+	scanner := bufio.NewScanner(in)
+	s := &session{out: out, env: object.NewEnvironment()}
+
+	for {
+		fmt.Fprint(out, PROMPT)
+
+		input, ok := readInput(scanner, out)
+		if !ok {
+			return
+		}
+
+		input = strings.TrimSpace(input)
+		if input == "" {
+			continue
+		}
+		s.history = append(s.history, input)
+
+		if !s.execute(input) {
+			return
+		}
+	}
+}
+
+// readInput reads one line, continuing onto further lines while parentheses
+// are left open.
+func readInput(scanner *bufio.Scanner, out io.Writer) (string, bool) {
+	if !scanner.Scan() {
+		return "", false
+	}
+	input := scanner.Text()
+
+	for openParens(input) > 0 {
+		fmt.Fprint(out, CONTINUE)
+		if !scanner.Scan() {
+			break
+		}
+		input += "\n" + scanner.Text()
+	}
+
+	return input, true
+}
+
+// openParens returns how many parentheses in input are still unclosed.
+func openParens(input string) int {
+	depth := 0
+	l := lexer.New(input)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case token.LPAREN:
+			depth++
+		case token.RPAREN:
+			depth--
+		}
+	}
+	return depth
+}
+
+// execute runs a single input and reports whether the REPL should continue.
+func (s *session) execute(input string) bool {
+	if strings.HasPrefix(input, ":") {
+		return s.command(input)
+	}
+
+	if m := letStatement.FindStringSubmatch(input); m != nil {
+		if val, _, ok := s.eval(strings.TrimSpace(m[2])); ok {
+			s.env.Set(m[1], val)
+			fmt.Fprintf(s.out, "%s = %s\n", m[1], val.Inspect())
+		}
+		return true
+	}
+
+	val, program, ok := s.eval(input)
+	if !ok {
+		return true
+	}
+	switch last := program.Statements[len(program.Statements)-1].(type) {
+	case *ast.AssignStatement:
+		fmt.Fprintf(s.out, "%s = %s\n", last.Name.Value, val.Inspect())
+	case *ast.FunctionDefinition:
+		fmt.Fprintln(s.out, printer.Format(last))
+	default:
+		fmt.Fprintln(s.out, val.Inspect())
+	}
+	return true
+}
+
+func (s *session) command(input string) bool {
+	name, arg, _ := strings.Cut(input, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch name {
+	case ":quit", ":q":
+		return false
+	case ":help":
+		fmt.Fprint(s.out, help)
+	case ":history":
+		for i, h := range s.history[:len(s.history)-1] {
+			fmt.Fprintf(s.out, "%3d  %s\n", i+1, strings.ReplaceAll(h, "\n", " "))
+		}
+	case ":vars":
+		for _, name := range s.env.Names() {
+			val, _ := s.env.Get(name)
+			if fn, ok := val.(*object.Function); ok {
+				fmt.Fprintln(s.out, printer.Format(fn.Definition))
+				continue
+			}
+			fmt.Fprintf(s.out, "%s = %s\n", name, val.Inspect())
+		}
+	case ":mode":
+		if arg != "" {
+			if err := s.mode.UnmarshalText([]byte(arg)); err != nil {
+				fmt.Fprintf(s.out, "error: %s\n", err)
+				break
+			}
+		}
+		fmt.Fprintf(s.out, "mode %s\n", s.mode)
+	case ":tokens":
+		l := lexer.New(arg)
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+			fmt.Fprintf(s.out, "%s\t%-8s %q\n", tok.Pos, tok.Type, tok.Literal)
+		}
+	case ":ast":
+		if function, ok := s.parse(arg); ok {
+			fmt.Fprint(s.out, printer.Tree(function))
+		}
+	case ":latex":
+		if function, ok := s.parse(arg); ok {
+			fmt.Fprintln(s.out, printer.LaTeX(function))
+		}
+	default:
+		fmt.Fprintf(s.out, "unknown command %s, try :help\n", name)
+	}
+	return true
+}
+
+func (s *session) parse(input string) (*ast.Function, bool) {
+	p := parser.New(lexer.New(input))
+	function := p.ParseFunction()
+
+	// later errors are usually a consequence of the first one
+	if errs := p.ParseErrors(); len(errs) != 0 {
+		s.printError(input, errs[0].Pos, errs[0].Msg)
+		return nil, false
+	}
+	return function, true
+}
+
+// eval runs input as a program and returns the value of its last statement.
+// It reports failure for programs without statements.
+func (s *session) eval(input string) (object.Object, *ast.Program, bool) {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+
+	if errs := p.ParseErrors(); len(errs) != 0 {
+		s.printError(input, errs[0].Pos, errs[0].Msg)
+		return nil, nil, false
+	}
+	if len(program.Statements) == 0 {
+		return nil, nil, false
+	}
+
+	val, err := eval.New(eval.WithMode(s.mode)).Eval(program, s.env)
+	if err != nil {
+		if e, ok := err.(*eval.Error); ok {
+			s.printError(input, e.Pos, e.Msg)
+		} else {
+			fmt.Fprintf(s.out, "error: %s\n", err)
+		}
+		return nil, nil, false
+	}
+	return val, program, true
+}
+
+// printError prints the offending line of input with a caret under pos.
+func (s *session) printError(input string, pos token.Position, msg string) {
+	lines := strings.Split(input, "\n")
+	if pos.Line >= 1 && pos.Line <= len(lines) {
+		fmt.Fprintf(s.out, "  %s\n", lines[pos.Line-1])
+		fmt.Fprintf(s.out, "  %s^\n", strings.Repeat(" ", max(pos.Column-1, 0)))
+	}
+	fmt.Fprintf(s.out, "%s: %s\n", pos, msg)
+}
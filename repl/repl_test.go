@@ -0,0 +1,84 @@
+package repl_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ArtroxGabriel/sigma-parser/repl"
+)
+
+func TestStart(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "evaluates expressions",
+			input: "1 + 2 * 3\n",
+			want:  ">> 7\n>> ",
+		},
+		{
+			name:  "let bindings persist",
+			input: "let r = 2\nr ^ 2 + 1\n",
+			want:  ">> r = 2\n>> 5\n>> ",
+		},
+		{
+			name:  "assignments and definitions persist",
+			input: "k = 3\nf(x)=k*x^2\nf(2); f(1)\n",
+			want:  ">> k = 3\n>> f(x) = k * x^2\n>> 3\n>> ",
+		},
+		{
+			name:  "recursive definitions are rejected",
+			input: "f(x) = f(x)\n",
+			want:  ">>   f(x) = f(x)\n  ^\n1:1: recursive definition of f: f -> f\n>> ",
+		},
+		{
+			name:  "unbalanced parentheses continue",
+			input: "(1 +\n2) * 3\n",
+			want:  ">> .. 9\n>> ",
+		},
+		{
+			name:  "errors point at the position",
+			input: "2 * y\n",
+			want:  ">>   2 * y\n      ^\n1:5: identifier not found: y\n>> ",
+		},
+		{
+			name:  "latex command",
+			input: ":latex x / 2\n",
+			want:  ">> \\frac{x}{2}\n>> ",
+		},
+		{
+			name:  "tokens command",
+			input: ":tokens x+1\n",
+			want:  ">> 1:1\tIDENT    \"x\"\n1:2\t+        \"+\"\n1:3\tNUMBER   \"1\"\n>> ",
+		},
+		{
+			name:  "mode command",
+			input: ":mode complex\nsqrt(-4)\n:mode bogus\n",
+			want:  ">> mode complex\n>> 2i\n>> error: unknown evaluation mode \"bogus\"\n>> ",
+		},
+		{
+			name:  "history command",
+			input: "1\n2\n:history\n",
+			want:  ">> 1\n>> 2\n>>   1  1\n  2  2\n>> ",
+		},
+		{
+			name:  "quit stops reading",
+			input: ":quit\n1 + 1\n",
+			want:  ">> ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			repl.Start(strings.NewReader(tt.input), &out)
+
+			if out.String() != tt.want {
+				t.Errorf("output = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/ArtroxGabriel/sigma-parser/ast"
+)
+
+// parseResult is a cached outcome of parsing an expression. Errors are
+// cached too so that repeated bad input costs no more than good input.
+type parseResult struct {
+	function *ast.Function
+	err      error
+}
+
+// cache is a fixed-size least-recently-used cache of parse results. The
+// cached trees are shared between requests and must not be modified.
+type cache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // front is most recently used
+	entries map[string]*list.Element
+}
+
+type cacheEntry struct {
+	input  string
+	result parseResult
+}
+
+func newCache(size int) *cache {
+	return &cache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (c *cache) get(input string) (parseResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[input]
+	if !ok {
+		return parseResult{}, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*cacheEntry).result, true
+}
+
+func (c *cache) put(input string, result parseResult) {
+	if c.size <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[input]; ok {
+		el.Value.(*cacheEntry).result = result
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.entries[input] = c.order.PushFront(&cacheEntry{input: input, result: result})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).input)
+	}
+}
+
+func (c *cache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
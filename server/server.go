@@ -0,0 +1,318 @@
+// Package server exposes parsing, evaluation and symbolic manipulation of
+// expressions as a JSON HTTP API.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/ArtroxGabriel/sigma-parser/ast"
+	"github.com/ArtroxGabriel/sigma-parser/calculus"
+	"github.com/ArtroxGabriel/sigma-parser/eval"
+	"github.com/ArtroxGabriel/sigma-parser/lexer"
+	"github.com/ArtroxGabriel/sigma-parser/object"
+	"github.com/ArtroxGabriel/sigma-parser/parser"
+	"github.com/ArtroxGabriel/sigma-parser/printer"
+	"github.com/ArtroxGabriel/sigma-parser/simplify"
+	"github.com/ArtroxGabriel/sigma-parser/token"
+)
+
+// Options configures a Server. Zero values select the defaults.
+type Options struct {
+	MaxBodyBytes int64         // largest accepted request body, default 64 KiB
+	Timeout      time.Duration // time allowed per request, default 5s
+	CacheSize    int           // parsed expressions kept in memory, default 1024
+	MaxDepth     int           // deepest accepted expression nesting, default 256
+	MaxNodes     int           // largest accepted expression, default 10000 nodes
+	MaxSteps     int           // evaluation step budget, default 1000000
+	MaxPrecision uint          // most bits of precision a request may ask for, default 4096
+}
+
+const (
+	defaultMaxBodyBytes = 64 << 10
+	defaultTimeout      = 5 * time.Second
+	defaultCacheSize    = 1024
+	defaultMaxDepth     = 256
+	defaultMaxNodes     = 10000
+	defaultMaxSteps     = 1000000
+	defaultMaxPrecision = 4096
+)
+
+// Server serves the endpoints POST /parse, /eval, /simplify, /derive and
+// /render.
+type Server struct {
+	handler http.Handler
+	opts    Options
+	cache   *cache
+}
+
+// New creates a Server with the given options.
+func New(opts Options) *Server {
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = defaultMaxBodyBytes
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.CacheSize == 0 {
+		opts.CacheSize = defaultCacheSize
+	}
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = defaultMaxDepth
+	}
+	if opts.MaxNodes <= 0 {
+		opts.MaxNodes = defaultMaxNodes
+	}
+	if opts.MaxSteps <= 0 {
+		opts.MaxSteps = defaultMaxSteps
+	}
+	if opts.MaxPrecision == 0 {
+		opts.MaxPrecision = defaultMaxPrecision
+	}
+
+	s := &Server{opts: opts, cache: newCache(opts.CacheSize)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /parse", s.endpoint(s.parse))
+	mux.HandleFunc("POST /eval", s.endpoint(s.eval))
+	mux.HandleFunc("POST /simplify", s.endpoint(s.simplify))
+	mux.HandleFunc("POST /derive", s.endpoint(s.derive))
+	mux.HandleFunc("POST /render", s.endpoint(s.render))
+
+	s.handler = http.TimeoutHandler(mux, opts.Timeout, `{"error":{"kind":"timeout","message":"request timed out"}}`)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+// Request is the body accepted by every endpoint.
+type Request struct {
+	Expression string             `json:"expression"`
+	Variables  map[string]float64 `json:"variables,omitempty"` // used by /eval
+	Mode       eval.Mode          `json:"mode,omitempty"`      // used by /eval: real, complex, rational or bigfloat
+	Precision  uint               `json:"precision,omitempty"` // used by /eval: bits for rational and bigfloat
+	Wrt        string             `json:"wrt,omitempty"`       // used by /derive
+}
+
+// Span locates an error in the expression.
+type Span struct {
+	Start token.Position `json:"start"`
+	End   token.Position `json:"end"`
+}
+
+// Error is the body of every failed response.
+type Error struct {
+	Kind    string `json:"kind"` // request, lex, parse, eval, limit or timeout
+	Message string `json:"message"`
+	Span    *Span  `json:"span,omitempty"`
+
+	status int
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func requestError(status int, msg string) *Error {
+	return &Error{Kind: "request", Message: msg, status: status}
+}
+
+// endpoint decodes the request, runs handle and encodes its result.
+func (s *Server) endpoint(handle func(context.Context, *Request) (any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, s.opts.MaxBodyBytes)
+
+		var req Request
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeError(w, requestError(http.StatusRequestEntityTooLarge, "request body too large"))
+				return
+			}
+			writeError(w, requestError(http.StatusBadRequest, "invalid JSON: "+err.Error()))
+			return
+		}
+		if req.Expression == "" {
+			writeError(w, requestError(http.StatusBadRequest, "missing expression"))
+			return
+		}
+
+		result, err := handle(r.Context(), &req)
+		if err != nil {
+			writeError(w, toError(err))
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+// function returns the parsed form of expression, from the cache if possible.
+func (s *Server) function(expression string) (*ast.Function, error) {
+	if res, ok := s.cache.get(expression); ok {
+		return res.function, res.err
+	}
+
+	function, err := parser.Parse(expression,
+		parser.WithMaxDepth(s.opts.MaxDepth),
+		parser.WithMaxNodes(s.opts.MaxNodes),
+	)
+	s.cache.put(expression, parseResult{function: function, err: err})
+	return function, err
+}
+
+func (s *Server) parse(_ context.Context, req *Request) (any, error) {
+	function, err := s.function(req.Expression)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"ast": function, "text": printer.Format(function)}, nil
+}
+
+func (s *Server) eval(ctx context.Context, req *Request) (any, error) {
+	if req.Precision > s.opts.MaxPrecision {
+		return nil, requestError(http.StatusBadRequest,
+			fmt.Sprintf("precision %d exceeds the limit of %d bits", req.Precision, s.opts.MaxPrecision))
+	}
+
+	function, err := s.function(req.Expression)
+	if err != nil {
+		return nil, err
+	}
+
+	env := object.NewEnvironment()
+	for name, value := range req.Variables {
+		env.Set(name, &object.Number{Value: value})
+	}
+
+	opts := []eval.Option{eval.WithContext(ctx), eval.WithMaxSteps(s.opts.MaxSteps), eval.WithMode(req.Mode)}
+	if req.Precision > 0 {
+		opts = append(opts, eval.WithPrecision(req.Precision))
+	}
+	obj, err := eval.New(opts...).Eval(function, env)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := map[string]any{"text": obj.Inspect()}
+	switch obj := obj.(type) {
+	case *object.Number:
+		// JSON has no encoding for NaN and the infinities, so only text is sent
+		if !math.IsNaN(obj.Value) && !math.IsInf(obj.Value, 0) {
+			resp["result"] = obj.Value
+		}
+	case *object.Complex:
+		re, im := real(obj.Value), imag(obj.Value)
+		if !math.IsNaN(re) && !math.IsInf(re, 0) && !math.IsNaN(im) && !math.IsInf(im, 0) {
+			resp["result"] = map[string]float64{"re": re, "im": im}
+		}
+	case *object.Rational, *object.BigFloat:
+		// as text, since a JSON number would round them to a float64
+		resp["result"] = obj.Inspect()
+	case *object.Boolean:
+		resp["result"] = obj.Value
+	}
+	return resp, nil
+}
+
+func (s *Server) simplify(ctx context.Context, req *Request) (any, error) {
+	function, err := s.function(req.Expression)
+	if err != nil {
+		return nil, err
+	}
+
+	simplified, err := simplify.FunctionContext(ctx, function)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"ast": simplified, "result": printer.Format(simplified)}, nil
+}
+
+func (s *Server) derive(ctx context.Context, req *Request) (any, error) {
+	if req.Wrt == "" {
+		return nil, requestError(http.StatusBadRequest, "missing wrt")
+	}
+
+	function, err := s.function(req.Expression)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := calculus.DeriveContext(ctx, function.Expression, req.Wrt)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"ast": d, "result": printer.Format(d)}, nil
+}
+
+func (s *Server) render(_ context.Context, req *Request) (any, error) {
+	function, err := s.function(req.Expression)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"latex": printer.LaTeX(function), "text": printer.Format(function)}, nil
+}
+
+// toError converts any error returned by a handler into a response error.
+func toError(err error) *Error {
+	e := classify(err)
+	if errors.Is(err, parser.ErrInputTooLong) || errors.Is(err, parser.ErrTooDeep) ||
+		errors.Is(err, parser.ErrTooManyNodes) || errors.Is(err, eval.ErrStepLimit) ||
+		errors.Is(err, eval.ErrTooLarge) {
+		e.Kind = "limit"
+	}
+	return e
+}
+
+func classify(err error) *Error {
+	var (
+		reqErr   *Error
+		lexErr   *lexer.Error
+		parseErr *parser.Error
+		evalErr  *eval.Error
+	)
+
+	switch {
+	case errors.As(err, &reqErr):
+		return reqErr
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return &Error{Kind: "timeout", Message: "request timed out", status: http.StatusServiceUnavailable}
+	case errors.As(err, &lexErr):
+		return &Error{
+			Kind:    "lex",
+			Message: fmt.Sprintf("illegal character %q", lexErr.Literal),
+			Span:    &Span{lexErr.Pos, lexErr.End},
+			status:  http.StatusUnprocessableEntity,
+		}
+	case errors.As(err, &parseErr):
+		return &Error{
+			Kind:    "parse",
+			Message: parseErr.Msg,
+			Span:    &Span{parseErr.Pos, parseErr.End},
+			status:  http.StatusUnprocessableEntity,
+		}
+	case errors.As(err, &evalErr):
+		e := &Error{Kind: "eval", Message: evalErr.Msg, status: http.StatusUnprocessableEntity}
+		if evalErr.Pos.Line > 0 {
+			e.Span = &Span{evalErr.Pos, evalErr.End}
+		}
+		return e
+	}
+	return &Error{Kind: "eval", Message: err.Error(), status: http.StatusUnprocessableEntity}
+}
+
+func writeError(w http.ResponseWriter, err *Error) {
+	writeJSON(w, err.status, map[string]any{"error": err})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
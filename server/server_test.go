@@ -0,0 +1,195 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func post(t *testing.T, s *Server, path, body string) (int, map[string]any) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+
+	var resp map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("response is not JSON: %q", rec.Body.String())
+	}
+	return rec.Code, resp
+}
+
+func TestEndpoints(t *testing.T) {
+	tests := []struct {
+		path  string
+		body  string
+		field string
+		want  any
+	}{
+		{"/eval", `{"expression": "x ^ 2 + y", "variables": {"x": 3, "y": 1}}`, "result", 10.0},
+		{"/eval", `{"expression": "1 / 0"}`, "text", "+Inf"},
+		{"/eval", `{"expression": "sqrt(-4) + 1", "mode": "complex"}`, "text", "1+2i"},
+		{"/eval", `{"expression": "0.1 + 0.2", "mode": "rational"}`, "result", "3/10"},
+		{"/eval", `{"expression": "pi", "mode": "bigfloat", "precision": 80}`, "result", "3.141592653589793238462642"},
+		{"/simplify", `{"expression": "x * 1 + 0"}`, "result", "x"},
+		{"/derive", `{"expression": "x ^ 3", "wrt": "x"}`, "result", "3 * x^2"},
+		{"/render", `{"expression": "sqrt(x) / 2"}`, "latex", `\frac{\sqrt{x}}{2}`},
+		{"/parse", `{"expression": "((x+1))"}`, "text", "x + 1"},
+	}
+
+	s := New(Options{})
+	for _, tt := range tests {
+		t.Run(tt.path+" "+tt.body, func(t *testing.T) {
+			code, resp := post(t, s, tt.path, tt.body)
+			if code != http.StatusOK {
+				t.Fatalf("status = %d, want 200 (%v)", code, resp)
+			}
+			if resp[tt.field] != tt.want {
+				t.Errorf("%s = %#v, want %#v", tt.field, resp[tt.field], tt.want)
+			}
+		})
+	}
+}
+
+func TestParseReturnsAST(t *testing.T) {
+	_, resp := post(t, New(Options{}), "/parse", `{"expression": "-x"}`)
+
+	tree, ok := resp["ast"].(map[string]any)
+	if !ok || tree["type"] != "Function" {
+		t.Fatalf("ast = %v, want a Function node", resp["ast"])
+	}
+	if exp := tree["expression"].(map[string]any); exp["type"] != "PrefixExpression" {
+		t.Errorf("expression type = %v, want PrefixExpression", exp["type"])
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+		kind   string
+		span   string // start-end offsets, empty for no span
+	}{
+		{"bad json", "/eval", `{"expression":`, http.StatusBadRequest, "request", ""},
+		{"unknown field", "/eval", `{"expr": "1"}`, http.StatusBadRequest, "request", ""},
+		{"missing expression", "/eval", `{}`, http.StatusBadRequest, "request", ""},
+		{"unknown mode", "/eval", `{"expression": "1", "mode": "quaternion"}`, http.StatusBadRequest, "request", ""},
+		{"precision too large", "/eval", `{"expression": "1", "precision": 100000}`, http.StatusBadRequest, "request", ""},
+		{"missing wrt", "/derive", `{"expression": "x"}`, http.StatusBadRequest, "request", ""},
+		{"lex error", "/parse", `{"expression": "1 + #"}`, http.StatusUnprocessableEntity, "lex", "4-5"},
+		{"parse error", "/parse", `{"expression": "sin(x"}`, http.StatusUnprocessableEntity, "parse", "5-5"},
+		{"eval error", "/eval", `{"expression": "1 + foo"}`, http.StatusUnprocessableEntity, "eval", "4-7"},
+		{"body too large", "/eval", `{"expression": "` + strings.Repeat("1+", 100) + `1"}`, http.StatusRequestEntityTooLarge, "request", ""},
+	}
+
+	s := New(Options{MaxBodyBytes: 100})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := post(t, s, tt.path, tt.body)
+			if code != tt.status {
+				t.Errorf("status = %d, want %d", code, tt.status)
+			}
+
+			e, ok := resp["error"].(map[string]any)
+			if !ok {
+				t.Fatalf("response has no error: %v", resp)
+			}
+			if e["kind"] != tt.kind {
+				t.Errorf("kind = %v, want %s (%v)", e["kind"], tt.kind, e["message"])
+			}
+
+			span, _ := e["span"].(map[string]any)
+			got := ""
+			if span != nil {
+				start := span["start"].(map[string]any)["offset"].(float64)
+				end := span["end"].(map[string]any)["offset"].(float64)
+				got = strings.Join([]string{ftoa(start), ftoa(end)}, "-")
+			}
+			if got != tt.span {
+				t.Errorf("span = %q, want %q", got, tt.span)
+			}
+		})
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	rec := httptest.NewRecorder()
+	New(Options{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/eval", nil))
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want 405", rec.Code)
+	}
+}
+
+func TestParseCache(t *testing.T) {
+	s := New(Options{CacheSize: 2})
+
+	for _, expr := range []string{"a", "b", "a", "c"} {
+		post(t, s, "/parse", `{"expression": "`+expr+`"}`)
+	}
+
+	if n := s.cache.len(); n != 2 {
+		t.Fatalf("cache holds %d entries, want 2", n)
+	}
+	if _, ok := s.cache.get("b"); ok {
+		t.Errorf("least recently used entry b was not evicted")
+	}
+	if _, ok := s.cache.get("a"); !ok {
+		t.Errorf("recently used entry a was evicted")
+	}
+}
+
+func ftoa(f float64) string {
+	b, _ := json.Marshal(f)
+	return string(b)
+}
+
+func TestLimits(t *testing.T) {
+	s := New(Options{MaxDepth: 10, MaxNodes: 20, MaxSteps: 5})
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"depth", `{"expression": "` + strings.Repeat("(", 20) + "1" + strings.Repeat(")", 20) + `"}`},
+		{"nodes", `{"expression": "` + strings.Repeat("1+", 20) + `1"}`},
+		{"steps", `{"expression": "1 + 2 + 3 + 4"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := post(t, s, "/eval", tt.body)
+			if code != http.StatusUnprocessableEntity {
+				t.Errorf("status = %d, want 422", code)
+			}
+			if e, _ := resp["error"].(map[string]any); e["kind"] != "limit" {
+				t.Errorf("error = %v, want kind limit", resp["error"])
+			}
+		})
+	}
+}
+
+// TestDeriveStopsAtDeadline checks that a derivative much larger than its
+// input stops being simplified once the request context is done, since
+// the timeout handler only abandons the response.
+func TestDeriveStopsAtDeadline(t *testing.T) {
+	s := New(Options{})
+	req := &Request{Expression: "x" + strings.Repeat("*x", 2000), Wrt: "x"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := s.derive(ctx, req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("derive() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("derive() took %v after a 50ms deadline", elapsed)
+	}
+}
@@ -0,0 +1,342 @@
+// Package simplify rewrites expressions into smaller equivalent ones by
+// folding constants and applying algebraic identities.
+package simplify
+
+import (
+	"context"
+	"math"
+
+	"github.com/ArtroxGabriel/sigma-parser/ast"
+	"github.com/ArtroxGabriel/sigma-parser/builtin"
+	"github.com/ArtroxGabriel/sigma-parser/eval"
+)
+
+// Simplify returns a simplified copy of e. The input is never modified.
+func Simplify(e ast.Expression) ast.Expression {
+	s := &simplifier{ctx: context.Background()}
+	return s.simplify(e)
+}
+
+// SimplifyContext is like Simplify, but gives up with the context's error
+// once ctx is done.
+func SimplifyContext(ctx context.Context, e ast.Expression) (ast.Expression, error) {
+	s := &simplifier{ctx: ctx}
+	out := s.simplify(e)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return out, nil
+}
+
+// Function simplifies the expression held by fn.
+func Function(fn *ast.Function) *ast.Function {
+	return &ast.Function{Expression: Simplify(fn.Expression)}
+}
+
+// FunctionContext is like Function, but gives up with the context's error
+// once ctx is done.
+func FunctionContext(ctx context.Context, fn *ast.Function) (*ast.Function, error) {
+	e, err := SimplifyContext(ctx, fn.Expression)
+	if err != nil {
+		return nil, err
+	}
+	return &ast.Function{Expression: e}, nil
+}
+
+// how many nodes pass between checks of the context
+const contextCheckInterval = 256
+
+// simplifier walks an expression, checking its context as it goes. Shared
+// subtrees are walked once per use, so the walk can be much longer than
+// the input suggests.
+type simplifier struct {
+	ctx   context.Context
+	nodes int
+	err   error
+}
+
+func (s *simplifier) simplify(e ast.Expression) ast.Expression {
+	if s.err != nil {
+		return e
+	}
+	if s.nodes++; s.nodes%contextCheckInterval == 0 {
+		if s.err = s.ctx.Err(); s.err != nil {
+			return e
+		}
+	}
+
+	switch e := e.(type) {
+	case *ast.PrefixExpression:
+		return simplifyPrefix(e.Operator, s.simplify(e.Right))
+	case *ast.PostfixExpression:
+		return simplifyPostfix(s.simplify(e.Left), e.Operator)
+	case *ast.InfixExpression:
+		return simplifyInfix(s.simplify(e.Left), e.Operator, s.simplify(e.Right))
+	case *ast.ConditionalExpression:
+		return simplifyConditional(s.simplify(e.Condition), s.simplify(e.Consequence), s.simplify(e.Alternative))
+	case *ast.FunctionCall:
+		args := make([]ast.Expression, len(e.Arguments))
+		for i, arg := range e.Arguments {
+			args[i] = s.simplify(arg)
+		}
+		return simplifyCall(&ast.FunctionCall{
+			Token:     e.Token,
+			Function:  ast.Clone(e.Function),
+			Arguments: args,
+		})
+	}
+	return ast.Clone(e)
+}
+
+func simplifyPrefix(operator string, right ast.Expression) ast.Expression {
+	switch operator {
+	case "+":
+		return right
+	case "-":
+		if v, ok := number(right); ok {
+			return ast.NewNumber(-v)
+		}
+		// -(-x) = x
+		if pe, ok := right.(*ast.PrefixExpression); ok && pe.Operator == "-" {
+			return pe.Right
+		}
+	}
+	return ast.NewPrefix(operator, right)
+}
+
+func simplifyPostfix(left ast.Expression, operator string) ast.Expression {
+	if v, ok := number(left); ok {
+		switch operator {
+		case "!":
+			// small integer factorials only, 3! but not 0.5! or 100!
+			if v >= 0 && v <= 20 && v == math.Trunc(v) {
+				return ast.NewNumber(eval.Factorial(v))
+			}
+		case "%":
+			if r := v / 100; r == math.Trunc(r) {
+				return ast.NewNumber(r)
+			}
+		}
+	}
+	return ast.NewPostfix(left, operator)
+}
+
+func simplifyConditional(condition, consequence, alternative ast.Expression) ast.Expression {
+	if holds, ok := decide(condition); ok {
+		if holds {
+			return consequence
+		}
+		if alternative != nil {
+			return alternative
+		}
+	}
+	// x < 0 ? 1 : 1 = 1
+	if ast.Equal(consequence, alternative) {
+		return consequence
+	}
+	return ast.NewConditional(condition, consequence, alternative)
+}
+
+// decide reports the value of a comparison between two numbers.
+func decide(condition ast.Expression) (holds, ok bool) {
+	ie, isInfix := condition.(*ast.InfixExpression)
+	if !isInfix {
+		return false, false
+	}
+	l, lok := number(ie.Left)
+	r, rok := number(ie.Right)
+	if !lok || !rok {
+		return false, false
+	}
+
+	switch ie.Operator {
+	case "<":
+		return l < r, true
+	case "<=":
+		return l <= r, true
+	case ">":
+		return l > r, true
+	case ">=":
+		return l >= r, true
+	case "==":
+		return l == r, true
+	case "!=":
+		return l != r, true
+	}
+	return false, false
+}
+
+func simplifyInfix(left ast.Expression, operator string, right ast.Expression) ast.Expression {
+	l, lok := number(left)
+	r, rok := number(right)
+
+	if lok && rok {
+		if v, ok := fold(l, operator, r); ok {
+			return ast.NewNumber(v)
+		}
+	}
+
+	switch operator {
+	case "+":
+		switch {
+		case lok && l == 0:
+			return right
+		case rok && r == 0:
+			return left
+		case isNegation(right):
+			return simplifyInfix(left, "-", negate(right))
+		case rok && r < 0:
+			return simplifyInfix(left, "-", ast.NewNumber(-r))
+		}
+	case "-":
+		switch {
+		case rok && r == 0:
+			return left
+		case lok && l == 0:
+			return simplifyPrefix("-", right)
+		case ast.Equal(left, right):
+			return ast.NewNumber(0)
+		case isNegation(right):
+			return simplifyInfix(left, "+", negate(right))
+		case rok && r < 0:
+			return simplifyInfix(left, "+", ast.NewNumber(-r))
+		}
+	case "*":
+		switch {
+		case lok && l == 0, rok && r == 0:
+			return ast.NewNumber(0)
+		case lok && l == 1:
+			return right
+		case rok && r == 1:
+			return left
+		case lok && l == -1:
+			return simplifyPrefix("-", right)
+		case rok && r == -1:
+			return simplifyPrefix("-", left)
+		case isNegation(left):
+			// -a * b = -(a * b)
+			return simplifyPrefix("-", simplifyInfix(negate(left), "*", right))
+		case isNegation(right):
+			return simplifyPrefix("-", simplifyInfix(left, "*", negate(right)))
+		case rok && !lok:
+			// keep numeric coefficients on the left: x * 2 = 2 * x
+			return simplifyInfix(right, "*", left)
+		case lok:
+			// 2 * (3 * x) = 6 * x
+			if ie, ok := right.(*ast.InfixExpression); ok && ie.Operator == "*" {
+				if c, ok := number(ie.Left); ok {
+					return simplifyInfix(ast.NewNumber(l*c), "*", ie.Right)
+				}
+			}
+		case ast.Equal(left, right):
+			return simplifyInfix(left, "^", ast.NewNumber(2))
+		}
+	case "/":
+		switch {
+		case rok && r == 1:
+			return left
+		case lok && l == 0:
+			return ast.NewNumber(0)
+		case ast.Equal(left, right):
+			return ast.NewNumber(1)
+		}
+	case "^":
+		switch {
+		case rok && r == 0:
+			return ast.NewNumber(1)
+		case rok && r == 1:
+			return left
+		case lok && l == 1:
+			return ast.NewNumber(1)
+		}
+		// (x ^ a) ^ n = x ^ (a * n) for numeric a and integer n
+		if ie, ok := left.(*ast.InfixExpression); ok && ie.Operator == "^" && rok && r == math.Trunc(r) {
+			if a, ok := number(ie.Right); ok {
+				return simplifyInfix(ie.Left, "^", ast.NewNumber(a*r))
+			}
+		}
+	}
+
+	return ast.NewInfix(left, operator, right)
+}
+
+// fold computes l operator r when the result is exact enough to replace the
+// expression: sums and products always, quotients and powers only when they
+// produce an integer.
+func fold(l float64, operator string, r float64) (float64, bool) {
+	var v float64
+
+	switch operator {
+	case "+":
+		v = l + r
+	case "-":
+		v = l - r
+	case "*":
+		v = l * r
+	case "/":
+		if r == 0 {
+			return 0, false
+		}
+		v = l / r
+		if v != math.Trunc(v) {
+			return 0, false
+		}
+	case "^":
+		v = math.Pow(l, r)
+		if v != math.Trunc(v) {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+
+	if math.IsInf(v, 0) || math.IsNaN(v) {
+		return 0, false
+	}
+	return v, true
+}
+
+// simplifyCall folds built-in calls with numeric arguments whose result is
+// an integer, such as sqrt(16) or cos(0).
+func simplifyCall(call *ast.FunctionCall) ast.Expression {
+	ident, ok := call.Function.(*ast.Identifier)
+	if !ok {
+		return call
+	}
+	fn, ok := builtin.Lookup(ident.Value)
+	if !ok || fn.Arity != len(call.Arguments) {
+		return call
+	}
+
+	args := make([]float64, len(call.Arguments))
+	for i, arg := range call.Arguments {
+		v, ok := number(arg)
+		if !ok {
+			return call
+		}
+		args[i] = v
+	}
+
+	v := fn.Fn(args...)
+	if math.IsInf(v, 0) || math.IsNaN(v) || v != math.Trunc(v) {
+		return call
+	}
+	return ast.NewNumber(v)
+}
+
+// number returns the value of e if it is a real numeric literal.
+func number(e ast.Expression) (float64, bool) {
+	if nl, ok := e.(*ast.NumberLiteral); ok && !nl.IsImaginary() {
+		return nl.Value, true
+	}
+	return 0, false
+}
+
+func isNegation(e ast.Expression) bool {
+	pe, ok := e.(*ast.PrefixExpression)
+	return ok && pe.Operator == "-"
+}
+
+func negate(e ast.Expression) ast.Expression {
+	return e.(*ast.PrefixExpression).Right
+}
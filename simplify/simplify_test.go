@@ -0,0 +1,75 @@
+package simplify_test
+
+import (
+	"testing"
+
+	"github.com/ArtroxGabriel/sigma-parser/lexer"
+	"github.com/ArtroxGabriel/sigma-parser/parser"
+	"github.com/ArtroxGabriel/sigma-parser/printer"
+	"github.com/ArtroxGabriel/sigma-parser/simplify"
+)
+
+func TestSimplify(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"1 + 2 * 3", "7"},
+		{"x + 0", "x"},
+		{"0 + x", "x"},
+		{"x - 0", "x"},
+		{"0 - x", "-x"},
+		{"x * 1", "x"},
+		{"1 * x * y", "x * y"},
+		{"x * 0 + y", "y"},
+		{"x / 1", "x"},
+		{"0 / x", "0"},
+		{"x ^ 1", "x"},
+		{"x ^ 0", "1"},
+		{"1 ^ x", "1"},
+		{"-(-x)", "x"},
+		{"x - x", "0"},
+		{"(x + 1) / (x + 1)", "1"},
+		{"x * x", "x^2"},
+		{"x * 3", "3 * x"},
+		{"2 * (3 * x)", "6 * x"},
+		{"x + -y", "x - y"},
+		{"x - -y", "x + y"},
+		{"-x * y", "-(x * y)"},
+		{"(x ^ 2) ^ 3", "x^6"},
+		{"1 / 3", "1 / 3"},
+		{"4!", "24"},
+		{"0.5!", "0.5!"},
+		{"250%", "250%"},
+		{"300%", "3"},
+		{"(x + 0)!", "x!"},
+		{"1 < 2 ? x : y", "x"},
+		{"2 + 1 <= 1 ? x : y * 1", "y"},
+		{"piecewise(2 < 1, x)", "piecewise(2 < 1, x)"},
+		{"x < 0 ? x * 0 : 0", "0"},
+		{"x < 0 + 1 ? -x : x", "x < 1 ? -x : x"},
+		{"6 / 3", "2"},
+		{"2 ^ 0.5", "2^0.5"},
+		{"sqrt(16) + cos(0)", "5"},
+		{"sin(1)", "sin(1)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			p := parser.New(lexer.New(tt.input))
+			function := p.ParseFunction()
+			if len(p.Errors()) != 0 {
+				t.Fatalf("parser errors: %v", p.Errors())
+			}
+
+			before := function.String()
+			got := printer.Format(simplify.Function(function))
+			if got != tt.want {
+				t.Errorf("Simplify() = %q, want %q", got, tt.want)
+			}
+			if function.String() != before {
+				t.Errorf("Simplify() modified its input: %s", function)
+			}
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package solve
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/ArtroxGabriel/sigma-parser/ast"
+	"github.com/ArtroxGabriel/sigma-parser/builtin"
+	"github.com/ArtroxGabriel/sigma-parser/calculus"
+	"github.com/ArtroxGabriel/sigma-parser/eval"
+	"github.com/ArtroxGabriel/sigma-parser/object"
+	"github.com/ArtroxGabriel/sigma-parser/simplify"
+)
+
+// ErrNotIsolated is returned by Isolate when it cannot rearrange an
+// equation to get the variable on its own.
+var ErrNotIsolated = errors.New("cannot isolate variable")
+
+// Isolate rearranges eq symbolically into an expression for variable, so
+// that v = u + a * t solved for t gives (v - u) / a. It undoes + - * / and
+// ^, exp, ln, log, log2 and sqrt one at a time, and collects the variable
+// from both sides when the equation is linear in it. Even powers are
+// undone by the principal root: x^2 = 4 gives 2, not -2. A solution free
+// of other identifiers is folded to a number, and must satisfy eq.
+func Isolate(eq *ast.Equation, variable string) (ast.Expression, error) {
+	if eq == nil || eq.Left == nil || eq.Right == nil {
+		return nil, errors.New("empty equation")
+	}
+
+	l, r := eq.Left, eq.Right
+	switch inL, inR := calculus.DependsOn(l, variable), calculus.DependsOn(r, variable); {
+	case !inL && !inR:
+		return nil, fmt.Errorf("%w: %s does not appear in %s", ErrNotIsolated, variable, eq)
+	case inL && inR:
+		// bring everything to the left and hope it is linear
+		l, r = infix(l, "-", r), num(0)
+	case inR:
+		l, r = r, l
+	}
+
+	x, ok := unwind(l, r, variable)
+	if !ok {
+		x, ok = linear(infix(l, "-", r), variable)
+	}
+	if ok {
+		x, ok = check(eq, variable, simplify.Simplify(x))
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %s in %s", ErrNotIsolated, variable, eq)
+	}
+	return x, nil
+}
+
+// check folds a solution x that has a value to that number, and reports
+// whether it satisfies eq. Unwinding assumes every step can be undone,
+// which a principal root or logarithm may not.
+func check(eq *ast.Equation, variable string, x ast.Expression) (ast.Expression, bool) {
+	if !bound(x, variable) {
+		// other identifiers, so there is nothing to check against
+		return x, true
+	}
+	// 256 bits, so that x^3 = -8 folds to -2 rather than a neighbour of it
+	ev := eval.New(eval.WithMode(eval.BigFloat), eval.WithPrecision(256))
+	v, err := ev.Float(&ast.Function{Expression: x}, nil)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil, false
+	}
+	f := infix(eq.Left, "-", eq.Right)
+	return num(v), !bound(f, variable) || isRoot(eq, variable, v, nil)
+}
+
+// bound reports whether every identifier in e, other than variable, is a
+// built-in constant, so that e has a value once variable has one.
+func bound(e ast.Expression, variable string) bool {
+	switch e := e.(type) {
+	case *ast.Identifier:
+		_, ok := builtin.LookupConstant(e.Value)
+		return ok || e.Value == variable
+	case *ast.PrefixExpression:
+		return bound(e.Right, variable)
+	case *ast.PostfixExpression:
+		return bound(e.Left, variable)
+	case *ast.InfixExpression:
+		return bound(e.Left, variable) && bound(e.Right, variable)
+	case *ast.ConditionalExpression:
+		return bound(e.Condition, variable) && bound(e.Consequence, variable) &&
+			(e.Alternative == nil || bound(e.Alternative, variable))
+	case *ast.FunctionCall:
+		for _, arg := range e.Arguments {
+			if !bound(arg, variable) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// unwind peels the outermost operation off l, the only side holding x,
+// and applies its inverse to r until x stands alone.
+func unwind(l, r ast.Expression, x string) (ast.Expression, bool) {
+	for {
+		var ok bool
+		switch e := l.(type) {
+		case *ast.Identifier:
+			return r, e.Value == x
+		case *ast.PrefixExpression:
+			if e.Operator != "-" {
+				return nil, false
+			}
+			l, r, ok = e.Right, ast.NewPrefix("-", r), true
+		case *ast.InfixExpression:
+			l, r, ok = unwindInfix(e, r, x)
+		case *ast.FunctionCall:
+			l, r, ok = unwindCall(e, r)
+		default:
+			return nil, false
+		}
+		if !ok {
+			return nil, false
+		}
+	}
+}
+
+func unwindInfix(e *ast.InfixExpression, r ast.Expression, x string) (ast.Expression, ast.Expression, bool) {
+	inL, inR := calculus.DependsOn(e.Left, x), calculus.DependsOn(e.Right, x)
+	if inL == inR {
+		return nil, nil, false
+	}
+
+	switch e.Operator {
+	case "+":
+		if inL {
+			return e.Left, infix(r, "-", e.Right), true
+		}
+		return e.Right, infix(r, "-", e.Left), true
+	case "-":
+		if inL {
+			return e.Left, infix(r, "+", e.Right), true
+		}
+		return e.Right, infix(e.Left, "-", r), true
+	case "*":
+		if inL {
+			return e.Left, infix(r, "/", e.Right), !isZero(e.Right)
+		}
+		return e.Right, infix(r, "/", e.Left), !isZero(e.Left)
+	case "/":
+		if inL {
+			return e.Left, infix(r, "*", e.Right), true
+		}
+		return e.Right, infix(e.Left, "/", r), true
+	case "^":
+		if inL {
+			return e.Left, root(r, e.Right), !isZero(e.Right) && !evenRoot(r, e.Right)
+		}
+		// b^u = r: u = ln(r) / ln(b)
+		return e.Right, infix(call("ln", r), "/", call("ln", e.Left)), true
+	}
+	return nil, nil, false
+}
+
+// root returns the solution r^(1/n) of u^n = r, keeping the sign of a
+// negative r for odd n: x^3 = -8 gives -2.
+func root(r, n ast.Expression) ast.Expression {
+	k, err := eval.Float(n, nil)
+	if negative(r) && err == nil && math.Mod(k, 2) != 0 && k == math.Trunc(k) {
+		return ast.NewPrefix("-", infix(ast.NewPrefix("-", r), "^", infix(num(1), "/", n)))
+	}
+	return infix(r, "^", infix(num(1), "/", n))
+}
+
+// evenRoot reports whether u^n = r has no real solution because n is even
+// and r negative.
+func evenRoot(r, n ast.Expression) bool {
+	k, err := eval.Float(n, nil)
+	return err == nil && math.Mod(k, 2) == 0 && negative(r)
+}
+
+// negative reports whether r has a value, and it is below zero.
+func negative(r ast.Expression) bool {
+	v, err := eval.Float(r, nil)
+	return err == nil && v < 0
+}
+
+// isZero reports whether e simplifies to the constant 0, which a side
+// cannot be divided by.
+func isZero(e ast.Expression) bool {
+	n, ok := simplify.Simplify(e).(*ast.NumberLiteral)
+	return ok && n.Value == 0
+}
+
+// inverses maps functions of one argument to the expression that undoes
+// them.
+var inverses = map[string]func(r ast.Expression) ast.Expression{
+	"exp":  func(r ast.Expression) ast.Expression { return call("ln", r) },
+	"ln":   func(r ast.Expression) ast.Expression { return call("exp", r) },
+	"log":  func(r ast.Expression) ast.Expression { return infix(num(10), "^", r) },
+	"log2": func(r ast.Expression) ast.Expression { return infix(num(2), "^", r) },
+	"sqrt": func(r ast.Expression) ast.Expression { return infix(r, "^", num(2)) },
+}
+
+func unwindCall(e *ast.FunctionCall, r ast.Expression) (ast.Expression, ast.Expression, bool) {
+	name, ok := e.Function.(*ast.Identifier)
+	if !ok || e.IsBars() || len(e.Arguments) != 1 {
+		return nil, nil, false
+	}
+	inverse, ok := inverses[name.Value]
+	if !ok || (name.Value == "sqrt" && negative(r)) {
+		return nil, nil, false
+	}
+	return e.Arguments[0], inverse(r), true
+}
+
+// linear solves f = 0 for x when f is a x + b with a and b free of x.
+func linear(f ast.Expression, x string) (ast.Expression, bool) {
+	a, err := calculus.Derive(f, x)
+	if err != nil || calculus.DependsOn(a, x) {
+		return nil, false
+	}
+	if n, ok := a.(*ast.NumberLiteral); ok && n.Value == 0 {
+		return nil, false
+	}
+	b := simplify.Simplify(calculus.Substitute(f, x, num(0)))
+	return infix(ast.NewPrefix("-", b), "/", a), true
+}
+
+// Equation solves eq for variable. It returns the expression Isolate finds
+// where it can; otherwise it searches numerically for a root of left -
+// right, with every other identifier bound by WithEnv, and returns the root
+// as a number. The numeric search looks for a sign change outwards from
+// [0, 1] and falls back to Newton's method from 1.
+func Equation(eq *ast.Equation, variable string, opts ...Option) (ast.Expression, error) {
+	x, err := Isolate(eq, variable)
+	if !errors.Is(err, ErrNotIsolated) || !calculus.DependsOn(infix(eq.Left, "-", eq.Right), variable) {
+		return x, err
+	}
+
+	fn := &ast.Function{Expression: infix(eq.Left, "-", eq.Right)}
+	opts = append(opts, WithVariable(variable))
+
+	var r Result
+	if a, b, berr := FindBracket(fn, 0, 1, opts...); berr == nil {
+		r, err = Brent(fn, a, b, opts...)
+	} else {
+		r, err = Newton(fn, 1, opts...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !r.Converged || math.IsNaN(r.Root) || !isRoot(eq, variable, r.Root, opts) {
+		return nil, fmt.Errorf("no solution found for %s in %s", variable, eq)
+	}
+	return num(r.Root), nil
+}
+
+// isRoot checks a root x reported by a search, whose float64 arithmetic can
+// make left - right vanish where it does not: x = x + 1 holds for large
+// enough x. Evaluated with 256 bits, left - right must be zero at x, change
+// sign within a few ulps of it, or be small next to both sides without
+// being flat around x.
+func isRoot(eq *ast.Equation, variable string, x float64, opts []Option) bool {
+	var c config
+	for _, opt := range opts {
+		opt(&c)
+	}
+	env := object.NewEnvironment()
+	if c.env != nil {
+		env = object.NewEnclosedEnvironment(c.env)
+	}
+	ev := eval.New(eval.WithMode(eval.BigFloat), eval.WithPrecision(256))
+	at := func(e ast.Expression, x float64) float64 {
+		env.Set(variable, &object.Number{Value: x})
+		v, err := ev.Float(e, env)
+		if err != nil {
+			return math.NaN()
+		}
+		return v
+	}
+
+	f := infix(eq.Left, "-", eq.Right)
+	step := 4 * (math.Nextafter(math.Abs(x), math.Inf(1)) - math.Abs(x))
+	fx, below, above := at(f, x), at(f, x-step), at(f, x+step)
+	switch {
+	case math.IsNaN(fx):
+		return false
+	case fx == 0:
+		return true
+	case !math.IsNaN(below) && !math.IsNaN(above) && math.Signbit(below) != math.Signbit(above):
+		return true
+	}
+
+	scale := math.Max(1, math.Max(math.Abs(at(eq.Left, x)), math.Abs(at(eq.Right, x))))
+	flat := below == fx && above == fx
+	return math.Abs(fx) <= 1e-9*scale && !flat
+}
+
+func num(v float64) ast.Expression { return ast.NewNumber(v) }
+
+func infix(l ast.Expression, op string, r ast.Expression) ast.Expression {
+	return ast.NewInfix(l, op, r)
+}
+
+func call(name string, args ...ast.Expression) ast.Expression {
+	return ast.NewCall(name, args...)
+}
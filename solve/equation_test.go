@@ -0,0 +1,150 @@
+package solve_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/ArtroxGabriel/sigma-parser/ast"
+	"github.com/ArtroxGabriel/sigma-parser/eval"
+	"github.com/ArtroxGabriel/sigma-parser/object"
+	"github.com/ArtroxGabriel/sigma-parser/parser"
+	"github.com/ArtroxGabriel/sigma-parser/printer"
+	"github.com/ArtroxGabriel/sigma-parser/solve"
+)
+
+func parseEquation(t *testing.T, input string) *ast.Equation {
+	t.Helper()
+	eq, err := parser.ParseEquation(input)
+	if err != nil {
+		t.Fatalf("ParseEquation(%q) error = %v", input, err)
+	}
+	return eq
+}
+
+func TestIsolate(t *testing.T) {
+	tests := []struct {
+		input, variable string
+		want            string
+	}{
+		{"v = u + a * t", "t", "(v - u) / a"},
+		{"v = u + a * t", "u", "v - a * t"},
+		{"y = 2 * x + 3", "x", "(y - 3) / 2"},
+		{"3 * x + 1 = x - 5", "x", "-3"},
+		{"y = exp(2 * x)", "x", "ln(y) / 2"},
+		{"y = ln(x) + 1", "x", "exp(y - 1)"},
+		{"y = 2^x", "x", "ln(y) / ln(2)"},
+		{"T = 2 * pi * sqrt(L / g)", "L", "(T / (2 * pi))^2 * g"},
+		{"x^2 = 4", "x", "2"},
+		{"x^3 = -8", "x", "-2"},
+		{"sqrt(x + 1) = 3", "x", "8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input+"/"+tt.variable, func(t *testing.T) {
+			x, err := solve.Isolate(parseEquation(t, tt.input), tt.variable)
+			if err != nil {
+				t.Fatalf("Isolate() error = %v", err)
+			}
+			if got := printer.Format(x); got != tt.want {
+				t.Errorf("Isolate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestIsolateSatisfies puts each solution back into its equation.
+func TestIsolateSatisfies(t *testing.T) {
+	tests := []struct {
+		input, variable string
+	}{
+		{"v = u + a * t", "a"},
+		{"A = pi * r^2", "r"},
+		{"1 / x = 1 / a + 1 / b", "x"},
+		{"a * x + b = c * x + d", "x"},
+		{"y = log(x / 2) - log2(b)", "x"},
+		{"T = 2 * pi * sqrt(L / g)", "g"},
+		{"y = a - b / (x + 1)", "x"},
+		{"y = -(x^2) + 4", "x"},
+	}
+	values := map[string]float64{"v": 7, "u": 1, "a": 2.5, "t": 3, "A": 5, "b": 1.5,
+		"c": 0.5, "d": 4, "y": 0.7, "T": 1.2, "L": 0.8, "g": 9.81}
+
+	ev := eval.New()
+	for _, tt := range tests {
+		t.Run(tt.input+"/"+tt.variable, func(t *testing.T) {
+			eq := parseEquation(t, tt.input)
+			x, err := solve.Isolate(eq, tt.variable)
+			if err != nil {
+				t.Fatalf("Isolate() error = %v", err)
+			}
+
+			env := object.NewEnvironment()
+			for name, v := range values {
+				if name != tt.variable {
+					env.Set(name, &object.Number{Value: v})
+				}
+			}
+			root, err := ev.Float(&ast.Function{Expression: x}, env)
+			if err != nil {
+				t.Fatalf("evaluating %s: %v", x, err)
+			}
+			env.Set(tt.variable, &object.Number{Value: root})
+
+			left, err := ev.Float(&ast.Function{Expression: eq.Left}, env)
+			if err != nil {
+				t.Fatal(err)
+			}
+			right, err := ev.Float(&ast.Function{Expression: eq.Right}, env)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(left-right) > 1e-9*math.Max(1, math.Abs(right)) {
+				t.Errorf("%s = %s gives %v = %v", tt.variable, x, left, right)
+			}
+		})
+	}
+}
+
+func TestIsolateFails(t *testing.T) {
+	for _, input := range []string{"x = cos(x)", "y = x^2 + x", "y = 2", "0 * x = 5", "x * (1 - 1) = 5", "x^0 = 5", "x^(a - a) = 5",
+		"sqrt(x) = -2", "x^2 = -4", "x^(1/2) = -2", "exp(x) = -1"} {
+		_, err := solve.Isolate(parseEquation(t, input), "x")
+		if !errors.Is(err, solve.ErrNotIsolated) {
+			t.Errorf("Isolate(%s) error = %v, want ErrNotIsolated", input, err)
+		}
+	}
+}
+
+func TestEquationFallsBackToNumeric(t *testing.T) {
+	env := object.NewEnvironment()
+	env.Set("k", &object.Number{Value: 2})
+
+	x, err := solve.Equation(parseEquation(t, "x * exp(x) = k"), "x", solve.WithEnv(env))
+	if err != nil {
+		t.Fatalf("Equation() error = %v", err)
+	}
+	n, ok := x.(*ast.NumberLiteral)
+	if !ok {
+		t.Fatalf("Equation() = %s, want a number", x)
+	}
+	// the Lambert W function at 2
+	if want := 0.8526055020137255; math.Abs(n.Value-want) > 1e-9 {
+		t.Errorf("Equation() = %v, want %v", n.Value, want)
+	}
+
+	x, err = solve.Equation(parseEquation(t, "v = u + a * t"), "t")
+	if err != nil || printer.Format(x) != "(v - u) / a" {
+		t.Errorf("Equation() = %v, %v, want (v - u) / a", x, err)
+	}
+
+	if _, err := solve.Equation(parseEquation(t, "y = 2"), "x"); !errors.Is(err, solve.ErrNotIsolated) {
+		t.Errorf("Equation(y = 2) error = %v, want ErrNotIsolated", err)
+	}
+	// x + 1 rounds to x far from zero, which is not a solution
+	for _, input := range []string{"x = x + 1", "exp(x) = x"} {
+		if x, err := solve.Equation(parseEquation(t, input), "x"); err == nil {
+			t.Errorf("Equation(%s) = %s, want an error", input, x)
+		}
+	}
+}
@@ -0,0 +1,374 @@
+// Package solve finds roots of parsed expressions: the values of their one
+// free variable at which they are zero.
+package solve
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/ArtroxGabriel/sigma-parser/analysis"
+	"github.com/ArtroxGabriel/sigma-parser/ast"
+	"github.com/ArtroxGabriel/sigma-parser/calculus"
+	"github.com/ArtroxGabriel/sigma-parser/eval"
+	"github.com/ArtroxGabriel/sigma-parser/object"
+)
+
+// Defaults for the options.
+const (
+	DefaultTolerance     = 1e-12
+	DefaultMaxIterations = 200
+)
+
+var (
+	// ErrNotBracketed is returned when the function has the same sign at
+	// both ends of the interval given to a bracketing method.
+	ErrNotBracketed = errors.New("root is not bracketed")
+
+	// ErrNoBracket is returned when FindBracket gives up.
+	ErrNoBracket = errors.New("no sign change found")
+)
+
+// Result reports the outcome of a search. When Converged is false, Root is
+// the best estimate found before the iteration limit or a breakdown.
+type Result struct {
+	Root       float64
+	Value      float64 // f(Root)
+	Iterations int
+	Converged  bool
+}
+
+type config struct {
+	tol      float64
+	maxIter  int
+	variable string
+	env      *object.Environment
+}
+
+// Option configures a search.
+type Option func(*config)
+
+// WithTolerance stops a search once the root is known to within tol,
+// relative to its magnitude when that is above one.
+func WithTolerance(tol float64) Option {
+	return func(c *config) { c.tol = tol }
+}
+
+// WithMaxIterations limits a search to n iterations.
+func WithMaxIterations(n int) Option {
+	return func(c *config) { c.maxIter = n }
+}
+
+// WithVariable names the variable to solve for, for expressions with more
+// than one free identifier.
+func WithVariable(name string) Option {
+	return func(c *config) { c.variable = name }
+}
+
+// WithEnv evaluates the expression in env, which binds every free
+// identifier other than the variable.
+func WithEnv(env *object.Environment) Option {
+	return func(c *config) { c.env = env }
+}
+
+// problem is a function of one variable to find a root of.
+type problem struct {
+	config
+	fn *ast.Function
+	ev *eval.Evaluator
+}
+
+func newProblem(fn *ast.Function, opts []Option) (*problem, error) {
+	p := &problem{
+		config: config{tol: DefaultTolerance, maxIter: DefaultMaxIterations},
+		fn:     fn,
+		ev:     eval.New(),
+	}
+	for _, opt := range opts {
+		opt(&p.config)
+	}
+	if fn == nil || fn.Expression == nil {
+		return nil, &eval.Error{Msg: "empty expression"}
+	}
+
+	if p.variable == "" {
+		var free []string
+		for _, name := range analysis.Analyze(fn).Variables {
+			if p.env != nil {
+				if _, ok := p.env.Get(name); ok {
+					continue
+				}
+			}
+			free = append(free, name)
+		}
+		if len(free) != 1 {
+			return nil, fmt.Errorf("expected one free variable, got %d: %v", len(free), free)
+		}
+		p.variable = free[0]
+	}
+	return p, nil
+}
+
+// at evaluates the function with the variable bound to x.
+func (p *problem) at(x float64) (float64, error) {
+	env := object.NewEnvironment()
+	if p.env != nil {
+		env = object.NewEnclosedEnvironment(p.env)
+	}
+	env.Set(p.variable, &object.Number{Value: x})
+	return p.ev.Float(p.fn, env)
+}
+
+// done reports whether a root known to lie within width of x is accurate
+// enough.
+func (p *problem) done(x, width float64) bool {
+	return math.Abs(width) <= p.tol*math.Max(1, math.Abs(x))
+}
+
+// FindBracket widens [a, b] geometrically until fn changes sign over it,
+// and returns the bracket found.
+func FindBracket(fn *ast.Function, a, b float64, opts ...Option) (float64, float64, error) {
+	p, err := newProblem(fn, opts)
+	if err != nil {
+		return a, b, err
+	}
+	if a == b {
+		return a, b, fmt.Errorf("empty interval [%v, %v]", a, b)
+	}
+	if a > b {
+		a, b = b, a
+	}
+
+	const grow = 1.6
+	fa, err := p.at(a)
+	if err != nil {
+		return a, b, err
+	}
+	fb, err := p.at(b)
+	if err != nil {
+		return a, b, err
+	}
+	for i := 0; i < p.maxIter; i++ {
+		if math.Signbit(fa) != math.Signbit(fb) || fa == 0 || fb == 0 {
+			return a, b, nil
+		}
+		// move the end where the function is closer to zero
+		if math.Abs(fa) < math.Abs(fb) {
+			a += grow * (a - b)
+			fa, err = p.at(a)
+		} else {
+			b += grow * (b - a)
+			fb, err = p.at(b)
+		}
+		if err != nil {
+			return a, b, err
+		}
+	}
+	return a, b, ErrNoBracket
+}
+
+// bracket evaluates fn at both ends of [a, b] and checks for a sign change.
+func (p *problem) bracket(a, b float64) (fa, fb float64, err error) {
+	if fa, err = p.at(a); err != nil {
+		return 0, 0, err
+	}
+	if fb, err = p.at(b); err != nil {
+		return 0, 0, err
+	}
+	if fa != 0 && fb != 0 && math.Signbit(fa) == math.Signbit(fb) {
+		return fa, fb, fmt.Errorf("%w: f(%v) = %v and f(%v) = %v", ErrNotBracketed, a, fa, b, fb)
+	}
+	return fa, fb, nil
+}
+
+// Bisection halves [a, b] until it holds the root to within the tolerance.
+// It needs a sign change over [a, b] and always converges.
+func Bisection(fn *ast.Function, a, b float64, opts ...Option) (Result, error) {
+	p, err := newProblem(fn, opts)
+	if err != nil {
+		return Result{}, err
+	}
+	fa, fb, err := p.bracket(a, b)
+	if err != nil {
+		return Result{}, err
+	}
+	switch {
+	case fa == 0:
+		return Result{Root: a, Converged: true}, nil
+	case fb == 0:
+		return Result{Root: b, Converged: true}, nil
+	}
+
+	var r Result
+	for r.Iterations = 1; r.Iterations <= p.maxIter; r.Iterations++ {
+		m := a + (b-a)/2
+		fm, err := p.at(m)
+		if err != nil {
+			return r, err
+		}
+		r.Root, r.Value = m, fm
+		if fm == 0 || p.done(m, (b-a)/2) || m == a || m == b {
+			r.Converged = true
+			return r, nil
+		}
+		if math.Signbit(fm) == math.Signbit(fa) {
+			a, fa = m, fm
+		} else {
+			b = m
+		}
+	}
+	r.Iterations = p.maxIter
+	return r, nil
+}
+
+// Brent combines bisection with secant steps and inverse quadratic
+// interpolation: as safe as bisection, and much faster near a simple root.
+// It needs a sign change over [a, b].
+func Brent(fn *ast.Function, a, b float64, opts ...Option) (Result, error) {
+	p, err := newProblem(fn, opts)
+	if err != nil {
+		return Result{}, err
+	}
+	fa, fb, err := p.bracket(a, b)
+	if err != nil {
+		return Result{}, err
+	}
+
+	// b is the best estimate, a the previous one and c the other end of
+	// the bracket around the root
+	c, fc := a, fa
+	d := b - a
+	e := d
+
+	var r Result
+	for r.Iterations = 1; r.Iterations <= p.maxIter; r.Iterations++ {
+		if math.Signbit(fb) == math.Signbit(fc) && fb != 0 {
+			c, fc = a, fa
+			d = b - a
+			e = d
+		}
+		if math.Abs(fc) < math.Abs(fb) {
+			a, b, c = b, c, b
+			fa, fb, fc = fb, fc, fb
+		}
+
+		tol := 2*math.SmallestNonzeroFloat64 + 0.5*p.tol*math.Max(1, math.Abs(b))
+		m := (c - b) / 2
+		r.Root, r.Value = b, fb
+		if fb == 0 || math.Abs(m) <= tol {
+			r.Converged = true
+			return r, nil
+		}
+
+		if math.Abs(e) >= tol && math.Abs(fa) > math.Abs(fb) {
+			// interpolate: secant with two points, inverse quadratic
+			// with three
+			var num, den float64
+			s := fb / fa
+			if a == c {
+				num = 2 * m * s
+				den = 1 - s
+			} else {
+				q, t := fa/fc, fb/fc
+				num = s * (2*m*q*(q-t) - (b-a)*(t-1))
+				den = (q - 1) * (t - 1) * (s - 1)
+			}
+			if num > 0 {
+				den = -den
+			} else {
+				num = -num
+			}
+			// accept the step only if it stays well inside the bracket
+			// and shrinks faster than bisection would
+			if 2*num < math.Min(3*m*den-math.Abs(tol*den), math.Abs(e*den)) {
+				e, d = d, num/den
+			} else {
+				d, e = m, m
+			}
+		} else {
+			d, e = m, m
+		}
+
+		a, fa = b, fb
+		if math.Abs(d) > tol {
+			b += d
+		} else {
+			b += math.Copysign(tol, m)
+		}
+		if fb, err = p.at(b); err != nil {
+			return r, err
+		}
+	}
+	r.Iterations = p.maxIter
+	return r, nil
+}
+
+// Newton iterates x - f(x)/f'(x) from x0. The derivative is the symbolic
+// derivative of the expression where one exists, and a central difference
+// where it does not. Newton's method converges quadratically near a simple
+// root but may wander off from a poor start; the result then reports that
+// it did not converge.
+func Newton(fn *ast.Function, x0 float64, opts ...Option) (Result, error) {
+	p, err := newProblem(fn, opts)
+	if err != nil {
+		return Result{}, err
+	}
+	deriv := p.derivative()
+
+	r := Result{Root: x0}
+	if r.Value, err = p.at(x0); err != nil {
+		return r, err
+	}
+	for r.Iterations = 1; r.Iterations <= p.maxIter; r.Iterations++ {
+		if r.Value == 0 {
+			r.Converged = true
+			return r, nil
+		}
+		dfx, err := deriv(r.Root)
+		if err != nil {
+			return r, err
+		}
+		step := r.Value / dfx
+		if dfx == 0 || math.IsNaN(step) || math.IsInf(step, 0) {
+			// a flat spot or a breakdown: no way to continue
+			return r, nil
+		}
+
+		x := r.Root - step
+		fx, err := p.at(x)
+		if err != nil {
+			return r, err
+		}
+		r.Root, r.Value = x, fx
+		if p.done(x, step) {
+			r.Converged = true
+			return r, nil
+		}
+	}
+	r.Iterations = p.maxIter
+	return r, nil
+}
+
+const epsilon = 0x1p-52 // spacing of float64 values at 1
+
+// derivative returns f', symbolic when the expression has one.
+func (p *problem) derivative() func(float64) (float64, error) {
+	if d, err := calculus.Derive(p.fn.Expression, p.variable); err == nil {
+		df := &problem{config: p.config, fn: &ast.Function{Expression: d}, ev: p.ev}
+		return df.at
+	}
+
+	return func(x float64) (float64, error) {
+		// the step that balances truncation and rounding error
+		h := math.Cbrt(epsilon) * math.Max(1, math.Abs(x))
+		fp, err := p.at(x + h)
+		if err != nil {
+			return 0, err
+		}
+		fm, err := p.at(x - h)
+		if err != nil {
+			return 0, err
+		}
+		return (fp - fm) / (2 * h), nil
+	}
+}
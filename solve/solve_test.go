@@ -0,0 +1,177 @@
+package solve_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/ArtroxGabriel/sigma-parser/ast"
+	"github.com/ArtroxGabriel/sigma-parser/object"
+	"github.com/ArtroxGabriel/sigma-parser/parser"
+	"github.com/ArtroxGabriel/sigma-parser/solve"
+)
+
+func parse(t *testing.T, input string) *ast.Function {
+	t.Helper()
+	function, err := parser.Parse(input)
+	if err != nil {
+		t.Fatalf("Parse(%q) error = %v", input, err)
+	}
+	return function
+}
+
+var rootTests = []struct {
+	input string
+	a, b  float64
+	want  float64
+}{
+	{"x^2 - 2", 0, 2, math.Sqrt2},
+	{"cos(x) - x", 0, 1, 0.7390851332151607},
+	{"exp(x) - 3", 0, 2, math.Log(3)},
+	{"x^3 - 2*x - 5", 2, 3, 2.0945514815423265},
+	{"ln(t) - 1", 1, 4, math.E},
+	{"x", -1, 1, 0},
+	{"(x - 1)^3", 0, 3, 1},
+}
+
+func TestBracketingMethods(t *testing.T) {
+	methods := map[string]func(*ast.Function, float64, float64, ...solve.Option) (solve.Result, error){
+		"bisection": solve.Bisection,
+		"brent":     solve.Brent,
+	}
+
+	for name, method := range methods {
+		for _, tt := range rootTests {
+			t.Run(name+"/"+tt.input, func(t *testing.T) {
+				r, err := method(parse(t, tt.input), tt.a, tt.b)
+				if err != nil {
+					t.Fatalf("error = %v", err)
+				}
+				if !r.Converged {
+					t.Errorf("did not converge: %+v", r)
+				}
+				if math.Abs(r.Root-tt.want) > 1e-9 {
+					t.Errorf("root = %v, want %v", r.Root, tt.want)
+				}
+			})
+		}
+	}
+}
+
+// TestBrentIsFaster checks that Brent's method needs far fewer steps than
+// bisection on a smooth function.
+func TestBrentIsFaster(t *testing.T) {
+	fn := parse(t, "x^3 - 2*x - 5")
+	bisection, err := solve.Bisection(fn, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	brent, err := solve.Brent(fn, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if brent.Iterations*3 > bisection.Iterations {
+		t.Errorf("brent took %d iterations, bisection %d", brent.Iterations, bisection.Iterations)
+	}
+}
+
+func TestNewton(t *testing.T) {
+	for _, tt := range rootTests {
+		t.Run(tt.input, func(t *testing.T) {
+			r, err := solve.Newton(parse(t, tt.input), tt.b)
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if !r.Converged || math.Abs(r.Root-tt.want) > 1e-6 {
+				t.Errorf("Newton = %+v, want root %v", r, tt.want)
+			}
+		})
+	}
+
+	// x! has no symbolic derivative, so a difference stands in for it
+	r, err := solve.Newton(parse(t, "x! - 6"), 2.5)
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if !r.Converged || math.Abs(r.Root-3) > 1e-9 {
+		t.Errorf("Newton(x! - 6) = %+v, want root 3", r)
+	}
+}
+
+func TestNewtonDoesNotConverge(t *testing.T) {
+	// the derivative vanishes at the start
+	r, err := solve.Newton(parse(t, "x^2 + 1"), 0)
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if r.Converged {
+		t.Errorf("Newton(x^2 + 1) converged to %v", r.Root)
+	}
+
+	// no real root: iterations run out
+	r, err = solve.Newton(parse(t, "x^2 + 1"), 0.5, solve.WithMaxIterations(20))
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if r.Converged || r.Iterations != 20 {
+		t.Errorf("Newton(x^2 + 1) = %+v, want 20 iterations without convergence", r)
+	}
+}
+
+func TestFindBracket(t *testing.T) {
+	fn := parse(t, "x^2 - 50")
+	a, b, err := solve.FindBracket(fn, 0, 1)
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	r, err := solve.Brent(fn, a, b)
+	if err != nil {
+		t.Fatalf("Brent(%v, %v) error = %v", a, b, err)
+	}
+	if math.Abs(r.Root-math.Sqrt(50)) > 1e-9 {
+		t.Errorf("root = %v, want %v", r.Root, math.Sqrt(50))
+	}
+
+	if _, _, err := solve.FindBracket(parse(t, "x^2 + 1"), 0, 1); !errors.Is(err, solve.ErrNoBracket) {
+		t.Errorf("FindBracket(x^2 + 1) error = %v, want ErrNoBracket", err)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	env := object.NewEnvironment()
+	env.Set("a", &object.Number{Value: 9})
+
+	r, err := solve.Brent(parse(t, "x^2 - a"), 0, 5, solve.WithEnv(env))
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if math.Abs(r.Root-3) > 1e-9 {
+		t.Errorf("root = %v, want 3", r.Root)
+	}
+
+	r, err = solve.Bisection(parse(t, "x - y"), 0, 1,
+		solve.WithVariable("x"), solve.WithEnv(env), solve.WithTolerance(1e-3))
+	if err == nil {
+		t.Fatalf("unbound y: got root %v", r.Root)
+	}
+
+	r, err = solve.Bisection(parse(t, "x^2 - 2"), 0, 2, solve.WithTolerance(1e-3))
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if math.Abs(r.Root-math.Sqrt2) > 1e-3 || r.Iterations > 12 {
+		t.Errorf("coarse bisection = %+v", r)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	if _, err := solve.Brent(parse(t, "x^2 + 1"), -1, 1); !errors.Is(err, solve.ErrNotBracketed) {
+		t.Errorf("Brent(x^2 + 1) error = %v, want ErrNotBracketed", err)
+	}
+	if _, err := solve.Newton(parse(t, "x + y"), 0); err == nil {
+		t.Error("Newton(x + y) error = nil, want two free variables")
+	}
+	if _, err := solve.Newton(parse(t, "2 + 2"), 0); err == nil {
+		t.Error("Newton(2 + 2) error = nil, want no free variable")
+	}
+}
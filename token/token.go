@@ -1,10 +1,32 @@
 package token
 
+import "fmt"
+
 type TokenType string
 
+// Position identifies where a token starts in the input
+type Position struct {
+	Offset int `json:"offset"` // byte offset, starting at 0
+	Line   int `json:"line"`   // line number, starting at 1
+	Column int `json:"column"` // column number in bytes, starting at 1
+}
+
+func (p Position) String() string { return fmt.Sprintf("%d:%d", p.Line, p.Column) }
+
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     Position
+}
+
+// End returns the position just after the token's literal. Tokens never span
+// lines, so only the offset and column move.
+func (t Token) End() Position {
+	return Position{
+		Offset: t.Pos.Offset + len(t.Literal),
+		Line:   t.Pos.Line,
+		Column: t.Pos.Column + len(t.Literal),
+	}
 }
 
 const (
@@ -17,9 +39,31 @@ const (
 	SLASH TokenType = "/"
 	POWER TokenType = "^"
 
+	BANG    TokenType = "!" // factorial, or logical not before an operand
+	PERCENT TokenType = "%"
+
+	LT     TokenType = "<"
+	LE     TokenType = "<="
+	GT     TokenType = ">"
+	GE     TokenType = ">="
+	EQ     TokenType = "=="
+	NOT_EQ TokenType = "!="
+	AND    TokenType = "&&"
+	OR     TokenType = "||"
+
+	BAR TokenType = "|" // absolute value, as in |x|
+
+	QUESTION TokenType = "?"
+	COLON    TokenType = ":"
+
+	ASSIGN    TokenType = "="
+	SEMICOLON TokenType = ";"
+
 	IDENT  TokenType = "IDENT" // functions and variables
 	NUMBER TokenType = "NUMBER"
+	IMAG   TokenType = "IMAG" // imaginary numbers (4i, 2.5j)
 
 	LPAREN TokenType = "("
 	RPAREN TokenType = ")"
+	COMMA  TokenType = ","
 )